@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/logger"
+	"bluebell/logic"
+	"bluebell/pkg/snowflake"
+	"bluebell/setting"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"go.uber.org/zap"
+)
+
+// 除了serve以外的子命令,只初始化各自需要的组件,执行完就退出
+
+// initStores 初始化日志和存储,withRedis为false时只连接MySQL
+func initStores(withRedis bool) (closeFn func(), err error) {
+	if err := setting.Conf.Validate(); err != nil {
+		return nil, err
+	}
+	if err := logger.Init(setting.Conf.LogConfig, setting.Conf.Mode); err != nil {
+		return nil, fmt.Errorf("init logger failed: %w", err)
+	}
+	if err := mysql.Init(setting.Conf.MySQLConfig); err != nil {
+		return nil, fmt.Errorf("init mysql failed: %w", err)
+	}
+	if !withRedis {
+		return mysql.Close, nil
+	}
+	if err := redis.Init(setting.Conf.RedisConfig); err != nil {
+		mysql.Close()
+		return nil, fmt.Errorf("init redis failed: %w", err)
+	}
+	return func() {
+		redis.Close()
+		mysql.Close()
+	}, nil
+}
+
+func printJSON(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// runMigrate 执行或回滚数据库迁移
+func runMigrate(args []string) error {
+	if len(args) == 0 || (args[0] != "up" && args[0] != "down") {
+		return errors.New("usage: migrate up | migrate down [-steps N]")
+	}
+	direction := args[0]
+	fs := flag.NewFlagSet("migrate "+direction, flag.ExitOnError)
+	steps := fs.Int("steps", 1, "down时回滚的迁移个数")
+	_ = fs.Parse(args[1:])
+
+	closeFn, err := initStores(false)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+	if direction == "up" {
+		return migrateUp(context.Background())
+	}
+	migrations, err := mysql.Migrations()
+	if err != nil {
+		return err
+	}
+	done, err := mysql.MigrateDown(context.Background(), migrations, *steps)
+	for _, m := range done {
+		fmt.Printf("migrate down: %d_%s\n", m.Version, m.Name)
+	}
+	if err == nil && len(done) == 0 {
+		fmt.Println("migrate down: nothing to do")
+	}
+	return err
+}
+
+// migrateUp 执行所有未执行过的迁移,serve在配置了 mysql.auto_migrate 时也会调用
+func migrateUp(ctx context.Context) error {
+	migrations, err := mysql.Migrations()
+	if err != nil {
+		return err
+	}
+	done, err := mysql.MigrateUp(ctx, migrations)
+	for _, m := range done {
+		zap.L().Info("migrate up", zap.Int64("version", m.Version), zap.String("name", m.Name))
+		fmt.Printf("migrate up: %d_%s\n", m.Version, m.Name)
+	}
+	if err == nil && len(done) == 0 {
+		fmt.Println("migrate up: nothing to do")
+	}
+	return err
+}
+
+// runReindex 从MySQL重建redis中的帖子索引并打印结果
+func runReindex(args []string) error {
+	fs := flag.NewFlagSet("reindex", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "只统计redis与MySQL不一致的数量,不修改redis")
+	_ = fs.Parse(args)
+
+	closeFn, err := initStores(true)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+	report, err := logic.Reindex(context.Background(), *dryRun)
+	if err != nil {
+		return err
+	}
+	return printJSON(report)
+}
+
+// runCreateAdmin 创建管理员账号,用户已存在时提升为管理员
+func runCreateAdmin(args []string) error {
+	fs := flag.NewFlagSet("create-admin", flag.ExitOnError)
+	username := fs.String("username", "", "管理员用户名")
+	password := fs.String("password", "", "管理员密码,也可以用环境变量 BLUEBELL_ADMIN_PASSWORD 指定")
+	_ = fs.Parse(args)
+	if *password == "" {
+		*password = os.Getenv("BLUEBELL_ADMIN_PASSWORD")
+	}
+	if *username == "" || *password == "" {
+		return errors.New("usage: create-admin -username NAME -password PASS")
+	}
+
+	// 提升已有用户为管理员后需要删除redis中的用户缓存
+	closeFn, err := initStores(true)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+	if err := snowflake.Init(setting.Conf.StartTime, setting.Conf.MachineID); err != nil {
+		return fmt.Errorf("init snowflake failed: %w", err)
+	}
+	created, err := logic.CreateAdmin(context.Background(), *username, *password)
+	if err != nil {
+		return err
+	}
+	if created {
+		fmt.Printf("admin %q created\n", *username)
+	} else {
+		fmt.Printf("user %q already exists, promoted to admin (password unchanged)\n", *username)
+	}
+	return nil
+}
+
+// runArchiveVotes 把投票期结束的帖子的票数归档到MySQL
+func runArchiveVotes(_ []string) error {
+	closeFn, err := initStores(true)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+	n, err := logic.ArchiveVotes(context.Background())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("archived votes of %d posts\n", n)
+	return nil
+}
+
+// runCheckConfig 检查配置并打印生效的配置,不连接任何外部服务
+func runCheckConfig(_ []string) error {
+	if err := printJSON(setting.Redacted()); err != nil {
+		return err
+	}
+	return setting.Conf.Validate()
+}
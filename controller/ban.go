@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"bluebell/logger"
+	"bluebell/logic"
+	"bluebell/models"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+// checkRestriction 查询用户是否被禁言或封禁,测试时替换
+var checkRestriction = logic.CheckUserRestriction
+
+// checkUserRestriction 检查当前用户能否在社区内发帖、投票和评论
+// 被禁言或封禁时返回带有到期时间的错误响应,调用方应该结束处理
+func checkUserRestriction(c *gin.Context, userID, communityID int64) bool {
+	err := checkRestriction(c.Request.Context(), userID, communityID)
+	if err == nil {
+		return true
+	}
+	var re *logic.RestrictedError
+	if !errors.As(err, &re) {
+		logger.FromContext(c.Request.Context()).Error("logic.CheckUserRestriction failed",
+			zap.Int64("user_id", userID),
+			zap.Int64("community_id", communityID),
+			zap.Error(err))
+	}
+	HandleError(c, err)
+	return false
+}
+
+// SuspendUserHandler 全站封禁用户
+// @Summary 全站封禁用户
+// @Description 管理员全站封禁用户,duration为0表示永久封禁
+// @Tags 管理员相关接口(api分组展示使用的)
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer JWT"
+// @Param object body models.ParamSuspendUser true "封禁信息"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ResponseSuccess "成功响应"
+// @Success 400 {object} models.ResponseError "响应错误"
+// @Success 500 {object} models.ResponseError "服务器错误"
+// @Router /manager/suspensions [post]
+func SuspendUserHandler(c *gin.Context) {
+	p := new(models.ParamSuspendUser)
+	if err := c.ShouldBindJSON(p); err != nil {
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		ResponseErrorWithMsg(c, CodeInvalidParam, translateErrors(c, errs))
+		return
+	}
+	operatorID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.SuspendUser(c.Request.Context(), operatorID, p); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.SuspendUser failed", zap.Int64("user_id", p.UserID), zap.Error(err))
+		HandleError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// UnsuspendUserHandler 解除全站封禁
+// @Summary 解除全站封禁
+// @Description 管理员解除用户的全站封禁
+// @Tags 管理员相关接口(api分组展示使用的)
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer JWT"
+// @Param user_id path int true "用户ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ResponseSuccess "成功响应"
+// @Success 400 {object} models.ResponseError "响应错误"
+// @Success 500 {object} models.ResponseError "服务器错误"
+// @Router /manager/suspensions/{user_id} [delete]
+func UnsuspendUserHandler(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	if err := logic.UnsuspendUser(c.Request.Context(), userID); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.UnsuspendUser failed", zap.Int64("user_id", userID), zap.Error(err))
+		HandleError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
@@ -0,0 +1,23 @@
+package controller
+
+import (
+	"bluebell/logic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CacheStatsHandler 查询缓存的命中情况
+// @Summary 查询缓存的命中情况
+// @Description 管理员查询帖子详情缓存的命中、未命中及实际加载的次数
+// @Tags 管理员相关接口(api分组展示使用的)
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer JWT"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ResponseSuccess "成功响应"
+// @Router /manager/cache/stats [get]
+func CacheStatsHandler(c *gin.Context) {
+	ResponseSuccess(c, gin.H{
+		"post_detail": logic.GetPostCacheStats(),
+	})
+}
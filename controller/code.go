@@ -1,5 +1,10 @@
 package controller
 
+import (
+	"bluebell/pkg/i18n"
+	"net/http"
+)
+
 type ResCode int64
 
 const (
@@ -12,24 +17,112 @@ const (
 
 	CodeNeedLogin
 	CodeInvalidToken
+	CodeNoPermission
+	CodePostNotExist
+	CodeCommentNotExist
+	CodeUserBanned
+	CodeUserSuspended
+	CodeCommunityNotExist
+	CodeReindexRunning
+	CodeVoteTimeExpire
+	CodeVoteRepeated
+	CodeNotFound
+	CodeSensitiveWord
+	CodeSensitiveAd
+	CodeSensitivePolitics
+	CodeSensitivePorn
+	CodeSensitiveURL
+	CodeSensitiveWeapon
+	CodeModeratorNotExist
+	CodeCommunityExist
 )
 
-var codeMsgMap = map[ResCode]string{
+// codeKeyMap 提示信息的key,各语言的文本见 pkg/i18n/locales
+var codeKeyMap = map[ResCode]string{
 	CodeSuccess:         "success",
-	CodeInvalidParam:    "请求参数错误",
-	CodeUserExist:       "用户名已存在",
-	CodeUserNotExist:    "用户名不存在",
-	CodeInvalidPassword: "用户名或密码错误",
-	CodeServerBusy:      "服务繁忙",
-
-	CodeNeedLogin:    "需要登录",
-	CodeInvalidToken: "无效的token",
+	CodeInvalidParam:    "invalid_param",
+	CodeUserExist:       "user_exist",
+	CodeUserNotExist:    "user_not_exist",
+	CodeInvalidPassword: "invalid_password",
+	CodeServerBusy:      "server_busy",
+
+	CodeNeedLogin:         "need_login",
+	CodeInvalidToken:      "invalid_token",
+	CodeNoPermission:      "no_permission",
+	CodePostNotExist:      "post_not_exist",
+	CodeCommentNotExist:   "comment_not_exist",
+	CodeUserBanned:        "user_banned",
+	CodeUserSuspended:     "user_suspended",
+	CodeCommunityNotExist: "community_not_exist",
+	CodeReindexRunning:    "reindex_running",
+	CodeVoteTimeExpire:    "vote_time_expire",
+	CodeVoteRepeated:      "vote_repeated",
+	CodeNotFound:          "not_found",
+	CodeSensitiveWord:     "sensitive_word",
+	CodeSensitiveAd:       "sensitive_ad",
+	CodeSensitivePolitics: "sensitive_politics",
+	CodeSensitivePorn:     "sensitive_porn",
+	CodeSensitiveURL:      "sensitive_url",
+	CodeSensitiveWeapon:   "sensitive_weapon",
+	CodeModeratorNotExist: "moderator_not_exist",
+	CodeCommunityExist:    "community_exist",
 }
 
+// codeStatusMap 业务状态码对应的HTTP状态码
+var codeStatusMap = map[ResCode]int{
+	CodeSuccess:         http.StatusOK,
+	CodeInvalidParam:    http.StatusBadRequest,
+	CodeUserExist:       http.StatusConflict,
+	CodeUserNotExist:    http.StatusNotFound,
+	CodeInvalidPassword: http.StatusUnauthorized,
+	CodeServerBusy:      http.StatusInternalServerError,
+
+	CodeNeedLogin:         http.StatusUnauthorized,
+	CodeInvalidToken:      http.StatusUnauthorized,
+	CodeNoPermission:      http.StatusForbidden,
+	CodePostNotExist:      http.StatusNotFound,
+	CodeCommentNotExist:   http.StatusNotFound,
+	CodeUserBanned:        http.StatusForbidden,
+	CodeUserSuspended:     http.StatusForbidden,
+	CodeCommunityNotExist: http.StatusNotFound,
+	CodeReindexRunning:    http.StatusConflict,
+	CodeVoteTimeExpire:    http.StatusForbidden,
+	CodeVoteRepeated:      http.StatusConflict,
+	CodeNotFound:          http.StatusNotFound,
+	CodeSensitiveWord:     http.StatusUnprocessableEntity,
+	CodeSensitiveAd:       http.StatusUnprocessableEntity,
+	CodeSensitivePolitics: http.StatusUnprocessableEntity,
+	CodeSensitivePorn:     http.StatusUnprocessableEntity,
+	CodeSensitiveURL:      http.StatusUnprocessableEntity,
+	CodeSensitiveWeapon:   http.StatusUnprocessableEntity,
+	CodeModeratorNotExist: http.StatusNotFound,
+	CodeCommunityExist:    http.StatusConflict,
+}
+
+// Msg 默认语言的提示信息
 func (c ResCode) Msg() string {
-	msg, ok := codeMsgMap[c]
+	return c.MsgIn(i18n.DefaultLocale)
+}
+
+// MsgIn 指定语言的提示信息,没有翻译时使用默认语言
+func (c ResCode) MsgIn(locale string) string {
+	return i18n.Message(locale, c.Key())
+}
+
+// Key 提示信息的key
+func (c ResCode) Key() string {
+	key, ok := codeKeyMap[c]
+	if !ok {
+		key = codeKeyMap[CodeServerBusy]
+	}
+	return key
+}
+
+// Status HTTP状态码
+func (c ResCode) Status() int {
+	status, ok := codeStatusMap[c]
 	if !ok {
-		msg = codeMsgMap[CodeServerBusy]
+		status = http.StatusInternalServerError
 	}
-	return msg
+	return status
 }
@@ -1,10 +1,14 @@
 package controller
 
 import (
+	"bluebell/logger"
 	"bluebell/logic"
+	"bluebell/models"
+	"bluebell/pkg/cursor"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
 )
 
@@ -24,10 +28,43 @@ import (
 // @Router /community [get]
 func CommunityHandler(c *gin.Context) {
 	// 查询到所有的社区（community_id, community_name) 以列表的形式返回
-	data, err := logic.GetCommunityList()
+	data, err := logic.GetCommunityList(c.Request.Context())
 	if err != nil {
-		zap.L().Error("logic.GetCommunityList() failed", zap.Error(err))
-		ResponseError(c, CodeServerBusy) // 不轻易把服务端报错暴露给外面
+		logger.FromContext(c.Request.Context()).Error("logic.GetCommunityList() failed", zap.Error(err))
+		HandleError(c, err) // 不轻易把服务端报错暴露给外面
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// CreateCommunityHandler 创建社区
+// @Summary 创建社区
+// @Description 管理员创建社区,slug由名称生成,名称中没有字母和数字时为 c-社区id,重复时加上后缀
+// @Tags 管理员相关接口(api分组展示使用的)
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer JWT"
+// @Param object body models.ParamCreateCommunity true "社区信息"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ResponseSuccess "成功响应"
+// @Success 400 {object} models.ResponseError "响应错误"
+// @Success 500 {object} models.ResponseError "服务器错误"
+// @Router /manager/communities [post]
+func CreateCommunityHandler(c *gin.Context) {
+	p := new(models.ParamCreateCommunity)
+	if err := c.ShouldBindJSON(p); err != nil {
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		ResponseErrorWithMsg(c, CodeInvalidParam, translateErrors(c, errs))
+		return
+	}
+	data, err := logic.CreateCommunity(c.Request.Context(), p)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.CreateCommunity failed", zap.String("name", p.Name), zap.Error(err))
+		HandleError(c, err)
 		return
 	}
 	ResponseSuccess(c, data)
@@ -56,36 +93,49 @@ func CommunityDetailHandler(c *gin.Context) {
 	}
 
 	// 2. 根据id获取社区详情
-	data, err := logic.GetCommunityDetail(id)
+	data, err := logic.GetCommunityDetail(c.Request.Context(), id)
 	if err != nil {
-		zap.L().Error("logic.GetCommunityList() failed", zap.Error(err))
-		ResponseError(c, CodeServerBusy) // 不轻易把服务端报错暴露给外面
+		logger.FromContext(c.Request.Context()).Error("logic.GetCommunityList() failed", zap.Error(err))
+		HandleError(c, invalidIDAs(err, CodeCommunityNotExist)) // 不轻易把服务端报错暴露给外面
 		return
 	}
 	ResponseSuccess(c, data)
 }
 
-// CommunityByName 社区name得到社区详情
-// @Summary 社区name得到社区详情
-// @Description 用name得到社区详情
+// CommunityByName 社区主页
+// @Summary 社区主页
+// @Description 用slug(兼容社区名称)得到社区详情及按时间或分数排序的帖子列表
 // @Tags 社区相关接口(api分组展示使用的)
 // @Accept application/json
 // @Produce application/json
 // @Param Authorization header string true "Bearer JWT"
-// @Param name path string true "社区ID"
+// @Param name path string true "社区slug"
+// @Param object query models.ParamPostList false "查询参数"
 // @Security ApiKeyAuth
 // @Success 200 {object} models.ResponseSuccess "成功响应"
 // @Success 400 {object} models.ResponseError "响应错误"
 // @Success 500 {object} models.ResponseError "服务器错误"
-// @Router /community/:name [get]
+// @Router /community/name/{name} [get]
 func CommunityByName(c *gin.Context) {
-	communityName := c.Param("name")
-	// 2. 根据id获取社区详情
-	data, err := logic.CommunityByName(communityName)
+	name := c.Param("name")
+	p := &models.ParamPostList{
+		Page:  1,
+		Size:  10,
+		Order: models.OrderTime,
+	}
+	if err := c.ShouldBindQuery(p); err != nil {
+		logger.FromContext(c.Request.Context()).Error("CommunityByName with invalid params", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	p.Size = cursor.ClampSize(p.Size)
+	// 登录用户可以看到自己的投票
+	viewerID, _ := getCurrentUserID(c)
+	data, next, err := logic.CommunityByName(c.Request.Context(), name, p, viewerID)
 	if err != nil {
-		zap.L().Error("logic.CommunityByName(id) failed", zap.Error(err))
-		ResponseError(c, CodeServerBusy) // 不轻易把服务端报错暴露给外面
+		logger.FromContext(c.Request.Context()).Error("logic.CommunityByName failed", zap.String("name", name), zap.Error(err))
+		HandleError(c, invalidIDAs(err, CodeCommunityNotExist)) // 不轻易把服务端报错暴露给外面
 		return
 	}
-	ResponseSuccess(c, data)
+	ResponseSuccessWithCursor(c, data, next)
 }
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"bluebell/logger"
 	"bluebell/logic"
 	"strconv"
 
@@ -28,11 +29,11 @@ func DeletePost(c *gin.Context) {
 	postID, err := strconv.ParseInt(poststr, 10, 64)
 	if err != nil {
 		ResponseError(c, CodeInvalidParam)
-		zap.L().Error("delete post invalid param", zap.Error(err))
+		logger.FromContext(c.Request.Context()).Error("delete post invalid param", zap.Error(err))
 		return
 	}
 	// 传入参数到logic层
-	logic.DeletePost(postID)
+	logic.DeletePost(c.Request.Context(), postID)
 	// 响应参数
 	ResponseSuccess(c, CodeSuccess)
 
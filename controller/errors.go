@@ -0,0 +1,189 @@
+package controller
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/logger"
+	"bluebell/logic"
+	"bluebell/pkg/cursor"
+	"bluebell/pkg/i18n"
+	"bluebell/setting"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AppError 返回给客户端的错误,决定响应中的业务状态码、HTTP状态码和提示信息
+// 处理函数调用 HandleError 后由 ErrorHandler 中间件统一生成响应
+type AppError struct {
+	Code   ResCode     // 业务状态码
+	Status int         // HTTP状态码
+	Key    string      // 提示信息的key
+	Detail interface{} // 可选的详细信息,如参数校验失败的字段
+	Err    error       // 原始错误,只记录在日志中,不返回给客户端
+}
+
+// NewAppError 使用业务状态码对应的HTTP状态码和提示信息
+func NewAppError(code ResCode) *AppError {
+	return &AppError{Code: code, Status: code.Status(), Key: code.Key()}
+}
+
+func (e *AppError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Key, e.Err)
+	}
+	return e.Key
+}
+
+func (e *AppError) Unwrap() error { return e.Err }
+
+// WithDetail 返回带有详细信息的副本
+func (e *AppError) WithDetail(detail interface{}) *AppError {
+	ne := *e
+	ne.Detail = detail
+	return &ne
+}
+
+// WithErr 返回记录了原始错误的副本
+func (e *AppError) WithErr(err error) *AppError {
+	ne := *e
+	ne.Err = err
+	return &ne
+}
+
+// errorMappings dao和logic中的错误对应的业务状态码,按顺序匹配
+var errorMappings = []struct {
+	target error
+	code   ResCode
+}{
+	{mysql.ErrorUserExist, CodeUserExist},
+	{mysql.ErrorUserNotExist, CodeUserNotExist},
+	{mysql.ErrorInvalidPassword, CodeInvalidPassword},
+	{mysql.ErrorInvalidID, CodeInvalidParam},
+	{mysql.ErrorCommunityExist, CodeCommunityExist},
+	{redis.ErrVoteTimeExpire, CodeVoteTimeExpire},
+	{redis.ErrVoteRepeated, CodeVoteRepeated},
+	{logic.ErrorPostNotExist, CodePostNotExist},
+	{logic.ErrorNotPostAuthor, CodeNoPermission},
+	{logic.ErrorCommentNotExist, CodeCommentNotExist},
+	{logic.ErrorModeratorNotExist, CodeModeratorNotExist},
+	{logic.ErrorReindexRunning, CodeReindexRunning},
+	{logic.ErrorInvalidSince, CodeInvalidParam},
+	{logic.ErrorInvalidSensitiveWord, CodeInvalidParam},
+	{logic.ErrorInvalidCategory, CodeInvalidParam},
+	{cursor.ErrInvalidCursor, CodeInvalidParam},
+	{ErrorUserNotLogin, CodeNeedLogin},
+}
+
+// sensitiveCategoryCodes 敏感词分类(词库文件名)对应的业务状态码,其他分类使用 CodeSensitiveWord
+var sensitiveCategoryCodes = map[string]ResCode{
+	"广告":          CodeSensitiveAd,
+	"政治类":         CodeSensitivePolitics,
+	"色情类":         CodeSensitivePorn,
+	"网址":          CodeSensitiveURL,
+	"涉枪涉爆违法信息关键词": CodeSensitiveWeapon,
+}
+
+func sensitiveWordCode(category string) ResCode {
+	if code, ok := sensitiveCategoryCodes[category]; ok {
+		return code
+	}
+	return CodeSensitiveWord
+}
+
+// toAppError 把错误转换成 AppError,无法识别的错误按服务繁忙处理
+func toAppError(err error) *AppError {
+	var ae *AppError
+	if errors.As(err, &ae) {
+		return ae
+	}
+	var se *logic.SensitiveWordError
+	if errors.As(err, &se) {
+		return NewAppError(sensitiveWordCode(se.Category)).WithErr(err).WithDetail(gin.H{
+			"field":    se.Field,
+			"category": se.Category,
+		})
+	}
+	var re *logic.RestrictedError
+	if errors.As(err, &re) {
+		code := CodeUserBanned
+		if re.Suspended {
+			code = CodeUserSuspended
+		}
+		return NewAppError(code).WithErr(err).WithDetail(gin.H{
+			"reason":      re.Reason,
+			"expire_time": re.ExpireTime, // 为null表示永久
+		})
+	}
+	for _, m := range errorMappings {
+		if errors.Is(err, m.target) {
+			return NewAppError(m.code).WithErr(err)
+		}
+	}
+	return NewAppError(CodeServerBusy).WithErr(err)
+}
+
+// invalidIDAs 查询的id不存在时使用更具体的状态码,如 CodePostNotExist
+func invalidIDAs(err error, code ResCode) error {
+	if errors.Is(err, mysql.ErrorInvalidID) {
+		return NewAppError(code).WithErr(err)
+	}
+	return err
+}
+
+// HandleError 记录错误并结束处理,由 ErrorHandler 生成响应
+// 原始错误会出现在访问日志的errors字段中
+func HandleError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// ErrorHandler 处理函数通过 HandleError 记录了错误且还没有写响应时,按最后一个错误生成响应
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		renderError(c, toAppError(c.Errors.Last().Err))
+	}
+}
+
+// legacyStatusOK 兼容旧的客户端,所有响应都使用HTTP 200,只通过code区分错误
+func legacyStatusOK() bool {
+	return setting.Conf.ServerConfig != nil && setting.Conf.ServerConfig.LegacyStatusOK
+}
+
+func renderError(c *gin.Context, e *AppError) {
+	key := e.Key
+	if key == "" {
+		key = e.Code.Key()
+	}
+	res := &ResponseData{
+		Code:      e.Code,
+		Msg:       i18n.Message(i18n.FromContext(c.Request.Context()), key),
+		Detail:    e.Detail,
+		RequestID: logger.RequestID(c.Request.Context()),
+	}
+	status := e.Status
+	if legacyStatusOK() {
+		status = http.StatusOK
+		legacyBody(res)
+	}
+	c.JSON(status, res)
+}
+
+// legacyBody 旧版本的响应中参数校验失败的字段放在msg中,其他详细信息放在data中
+func legacyBody(res *ResponseData) {
+	if res.Detail == nil {
+		return
+	}
+	if res.Code == CodeInvalidParam {
+		res.Msg = res.Detail
+	} else {
+		res.Data = res.Detail
+	}
+	res.Detail = nil
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/logic"
+	"bluebell/setting"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestToAppError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   ResCode
+		wantStatus int
+	}{
+		{name: "user exist", err: mysql.ErrorUserExist, wantCode: CodeUserExist, wantStatus: http.StatusConflict},
+		{name: "wrapped", err: fmt.Errorf("signup: %w", mysql.ErrorUserExist), wantCode: CodeUserExist, wantStatus: http.StatusConflict},
+		{name: "invalid password", err: mysql.ErrorInvalidPassword, wantCode: CodeInvalidPassword, wantStatus: http.StatusUnauthorized},
+		{name: "vote expired", err: redis.ErrVoteTimeExpire, wantCode: CodeVoteTimeExpire, wantStatus: http.StatusForbidden},
+		{name: "vote repeated", err: redis.ErrVoteRepeated, wantCode: CodeVoteRepeated, wantStatus: http.StatusConflict},
+		{name: "post not exist", err: logic.ErrorPostNotExist, wantCode: CodePostNotExist, wantStatus: http.StatusNotFound},
+		{name: "moderator not exist", err: logic.ErrorModeratorNotExist, wantCode: CodeModeratorNotExist, wantStatus: http.StatusNotFound},
+		{name: "community exist", err: mysql.ErrorCommunityExist, wantCode: CodeCommunityExist, wantStatus: http.StatusConflict},
+		{name: "invalid id", err: mysql.ErrorInvalidID, wantCode: CodeInvalidParam, wantStatus: http.StatusBadRequest},
+		{name: "invalid id as community", err: invalidIDAs(mysql.ErrorInvalidID, CodeCommunityNotExist), wantCode: CodeCommunityNotExist, wantStatus: http.StatusNotFound},
+		{name: "suspended", err: &logic.RestrictedError{Suspended: true}, wantCode: CodeUserSuspended, wantStatus: http.StatusForbidden},
+		{name: "sensitive ad", err: &logic.SensitiveWordError{Field: "content", Category: "广告"}, wantCode: CodeSensitiveAd, wantStatus: http.StatusUnprocessableEntity},
+		{name: "sensitive other", err: &logic.SensitiveWordError{Field: "title", Category: "自定义"}, wantCode: CodeSensitiveWord, wantStatus: http.StatusUnprocessableEntity},
+		{name: "invalid sensitive word", err: logic.ErrorInvalidSensitiveWord, wantCode: CodeInvalidParam, wantStatus: http.StatusBadRequest},
+		{name: "app error", err: NewAppError(CodeNoPermission), wantCode: CodeNoPermission, wantStatus: http.StatusForbidden},
+		{name: "unknown", err: errors.New("connection refused"), wantCode: CodeServerBusy, wantStatus: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := toAppError(tt.err)
+			if e.Code != tt.wantCode || e.Status != tt.wantStatus {
+				t.Fatalf("toAppError(%v) = %d/%d, want %d/%d", tt.err, e.Code, e.Status, tt.wantCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+// serveError 经过 ErrorHandler 处理返回err的请求
+func serveError(t *testing.T, err error) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ErrorHandler())
+	r.GET("/", func(c *gin.Context) { HandleError(c, err) })
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	return w, body
+}
+
+func TestErrorHandler(t *testing.T) {
+	detail := map[string]string{"username": "username为必填字段"}
+	err := NewAppError(CodeInvalidParam).WithDetail(detail)
+
+	w, body := serveError(t, err)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", w.Code)
+	}
+	if body["msg"] != CodeInvalidParam.Msg() || body["detail"] == nil {
+		t.Fatalf("body = %v, want msg and detail", body)
+	}
+
+	// 兼容模式: HTTP 200,参数校验失败的字段放在msg中
+	prev := setting.Conf.ServerConfig
+	setting.Conf.ServerConfig = &setting.ServerConfig{LegacyStatusOK: true}
+	t.Cleanup(func() { setting.Conf.ServerConfig = prev })
+	w, body = serveError(t, err)
+	if w.Code != http.StatusOK {
+		t.Fatalf("legacy status = %d, want 200", w.Code)
+	}
+	if _, ok := body["msg"].(map[string]interface{}); !ok || body["detail"] != nil {
+		t.Fatalf("legacy body = %v, want detail in msg", body)
+	}
+	if code := body["code"].(float64); ResCode(code) != CodeInvalidParam {
+		t.Fatalf("legacy code = %v, want %d", code, CodeInvalidParam)
+	}
+}
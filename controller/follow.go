@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"bluebell/logger"
+	"bluebell/logic"
+	"bluebell/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// FollowCommunityHandler 关注社区
+// @Summary 关注社区
+// @Description 关注社区后可以在 /feed 中看到社区内的帖子
+// @Tags 社区相关接口(api分组展示使用的)
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer JWT"
+// @Param id path int true "社区ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ResponseSuccess "成功响应"
+// @Success 400 {object} models.ResponseError "响应错误"
+// @Success 500 {object} models.ResponseError "服务器错误"
+// @Router /community/id/{id}/follow [post]
+func FollowCommunityHandler(c *gin.Context) {
+	communityID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.FollowCommunity(c.Request.Context(), userID, communityID); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.FollowCommunity failed", zap.Int64("community_id", communityID), zap.Error(err))
+		HandleError(c, invalidIDAs(err, CodeCommunityNotExist))
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// UnfollowCommunityHandler 取消关注社区
+// @Summary 取消关注社区
+// @Description 取消关注社区
+// @Tags 社区相关接口(api分组展示使用的)
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer JWT"
+// @Param id path int true "社区ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ResponseSuccess "成功响应"
+// @Success 400 {object} models.ResponseError "响应错误"
+// @Success 500 {object} models.ResponseError "服务器错误"
+// @Router /community/id/{id}/follow [delete]
+func UnfollowCommunityHandler(c *gin.Context) {
+	communityID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.UnfollowCommunity(c.Request.Context(), userID, communityID); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.UnfollowCommunity failed", zap.Int64("community_id", communityID), zap.Error(err))
+		HandleError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// GetFeedHandler 关注的社区内的帖子列表
+// @Summary 关注的社区内的帖子列表
+// @Description 按时间或分数排序查询当前用户关注的所有社区内的帖子
+// @Tags 帖子相关接口(api分组展示使用的)
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer JWT"
+// @Param object query models.ParamPostList false "查询参数"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ResponseSuccess
+// @Success 400 {object} models.ResponseError "响应错误"
+// @Success 500 {object} models.ResponseError "服务器错误"
+// @Router /feed [get]
+func GetFeedHandler(c *gin.Context) {
+	p := &models.ParamPostList{
+		Page:  1,
+		Size:  10,
+		Order: models.OrderTime,
+	}
+	if err := c.ShouldBindQuery(p); err != nil {
+		logger.FromContext(c.Request.Context()).Error("GetFeedHandler with invalid params", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	data, next, err := logic.GetFeed(c.Request.Context(), userID, p)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.GetFeed failed", zap.Int64("user_id", userID), zap.Error(err))
+		HandleError(c, err)
+		return
+	}
+	ResponseSuccessWithCursor(c, data, next)
+}
@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"bluebell/logger"
+	"bluebell/logic"
+	"bluebell/pkg/buildinfo"
+	"bluebell/setting"
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 探活接口给负载均衡和容器编排使用,直接用HTTP状态码表示结果,不使用统一的响应格式
+
+// HealthzHandler 存活检查,进程能处理请求就返回200
+func HealthzHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// ReadyzHandler 就绪检查,MySQL和redis都可用时返回200,否则返回503
+// 服务开始退出后也返回503
+func ReadyzHandler(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), setting.Conf.ReadinessTimeout)
+	defer cancel()
+	report := logic.CheckReadiness(ctx)
+	if !report.Ready() {
+		logger.FromContext(c.Request.Context()).Warn("readiness check failed", zap.String("status", report.Status), zap.Any("checks", report.Checks))
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// VersionHandler 返回应用版本以及编译时的git提交和时间
+func VersionHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, buildinfo.Get(setting.Conf.Version))
+}
@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"bluebell/models"
+	"bluebell/pkg/i18n"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// 每个业务状态码在每种语言中都要有提示信息
+func TestCodeMessagesTranslated(t *testing.T) {
+	for code, key := range codeKeyMap {
+		if _, ok := codeStatusMap[code]; !ok {
+			t.Errorf("code %d (%s) has no HTTP status", code, key)
+		}
+		for _, locale := range i18n.Locales() {
+			if _, ok := i18n.Lookup(locale, key); !ok {
+				t.Errorf("code %d: missing %q in locale %s", code, key, locale)
+			}
+		}
+	}
+	if len(codeStatusMap) != len(codeKeyMap) {
+		t.Errorf("len(codeStatusMap) = %d, len(codeKeyMap) = %d", len(codeStatusMap), len(codeKeyMap))
+	}
+}
+
+func TestLocalizedResponse(t *testing.T) {
+	if err := InitTrans(); err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(i18n.GinLocale())
+	r.POST("/signup", func(c *gin.Context) {
+		p := new(models.ParamSignUp)
+		if err := c.ShouldBindJSON(p); err != nil {
+			ResponseErrorWithMsg(c, CodeInvalidParam, translateErrors(c, err.(validator.ValidationErrors)))
+			return
+		}
+		ResponseSuccess(c, nil)
+	})
+
+	tests := []struct {
+		accept      string
+		wantMsg     string
+		wantContain string // 校验错误中的用词
+	}{
+		{accept: "en-US", wantMsg: "Invalid request parameters", wantContain: "required"},
+		{accept: "zh-CN", wantMsg: "请求参数错误", wantContain: "必填"},
+		{accept: "fr", wantMsg: "请求参数错误", wantContain: "必填"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/signup", bytes.NewReader([]byte(`{}`)))
+			req.Header.Set("Accept-Language", tt.accept)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			var body struct {
+				Msg    string            `json:"msg"`
+				Detail map[string]string `json:"detail"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Msg != tt.wantMsg {
+				t.Fatalf("msg = %q, want %q", body.Msg, tt.wantMsg)
+			}
+			if !strings.Contains(body.Detail["username"], tt.wantContain) {
+				t.Fatalf("detail = %v, want username error containing %q", body.Detail, tt.wantContain)
+			}
+		})
+	}
+}
@@ -0,0 +1,405 @@
+package controller
+
+import (
+	"bluebell/logger"
+	"bluebell/logic"
+	"bluebell/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+// ---- 跟社区版主相关的 ----
+
+// GetModeratorsHandler 查询社区版主
+// @Summary 查询社区版主
+// @Description 查询社区的版主及所有者列表
+// @Tags 版主相关接口(api分组展示使用的)
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer JWT"
+// @Param community_id path int true "社区ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ResponseSuccess "成功响应"
+// @Success 400 {object} models.ResponseError "响应错误"
+// @Success 500 {object} models.ResponseError "服务器错误"
+// @Router /moderation/communities/{community_id}/moderators [get]
+func GetModeratorsHandler(c *gin.Context) {
+	communityID, err := strconv.ParseInt(c.Param("community_id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	data, err := logic.GetModerators(c.Request.Context(), communityID)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.GetModerators failed", zap.Int64("community_id", communityID), zap.Error(err))
+		HandleError(c, err)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// AddModeratorHandler 添加社区版主
+// @Summary 添加社区版主
+// @Description 社区所有者为社区添加版主
+// @Tags 版主相关接口(api分组展示使用的)
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer JWT"
+// @Param community_id path int true "社区ID"
+// @Param object body models.ParamModerator true "版主信息"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ResponseSuccess "成功响应"
+// @Success 400 {object} models.ResponseError "响应错误"
+// @Success 500 {object} models.ResponseError "服务器错误"
+// @Router /moderation/communities/{community_id}/moderators [post]
+func AddModeratorHandler(c *gin.Context) {
+	communityID, _ := getCurrentCommunityID(c)
+	p := new(models.ParamModerator)
+	if err := c.ShouldBindJSON(p); err != nil {
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		ResponseErrorWithMsg(c, CodeInvalidParam, translateErrors(c, errs))
+		return
+	}
+	// 只有站点管理员可以任命社区所有者,也只有站点管理员可以修改社区所有者的角色
+	if p.Role == models.ModeratorRoleOwner && getCurrentUserRole(c) != "root" {
+		ResponseError(c, CodeNoPermission)
+		return
+	}
+	if !checkTargetRole(c, communityID, p.UserID) {
+		return
+	}
+	if err := logic.AddModerator(c.Request.Context(), communityID, p); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.AddModerator failed", zap.Int64("community_id", communityID), zap.Error(err))
+		HandleError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// RemoveModeratorHandler 移除社区版主
+// @Summary 移除社区版主
+// @Description 社区所有者移除社区的版主,只有站点管理员可以移除社区所有者
+// @Tags 版主相关接口(api分组展示使用的)
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer JWT"
+// @Param community_id path int true "社区ID"
+// @Param user_id path int true "用户ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ResponseSuccess "成功响应"
+// @Success 400 {object} models.ResponseError "响应错误"
+// @Success 500 {object} models.ResponseError "服务器错误"
+// @Router /moderation/communities/{community_id}/moderators/{user_id} [delete]
+func RemoveModeratorHandler(c *gin.Context) {
+	communityID, _ := getCurrentCommunityID(c)
+	userID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	// 只有站点管理员可以移除社区所有者
+	if !checkTargetRole(c, communityID, userID) {
+		return
+	}
+	if err := logic.RemoveModerator(c.Request.Context(), communityID, userID); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.RemoveModerator failed", zap.Int64("community_id", communityID), zap.Error(err))
+		HandleError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// getModeratorRole 查询用户在社区中的版主角色,测试时替换
+var getModeratorRole = logic.GetModeratorRole
+
+// checkTargetRole 非站点管理员只能操作社区角色比自己低的用户,例如版主不能禁言社区所有者或其他版主
+// 不允许时已经写入响应
+func checkTargetRole(c *gin.Context, communityID, targetID int64) bool {
+	if getCurrentUserRole(c) == "root" {
+		return true
+	}
+	operatorID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return false
+	}
+	ctx := c.Request.Context()
+	operatorRole, err := getModeratorRole(ctx, communityID, operatorID)
+	if err != nil {
+		logger.FromContext(ctx).Error("logic.GetModeratorRole failed",
+			zap.Int64("community_id", communityID),
+			zap.Int64("user_id", operatorID),
+			zap.Error(err))
+		HandleError(c, err)
+		return false
+	}
+	targetRole, err := getModeratorRole(ctx, communityID, targetID)
+	if err != nil {
+		logger.FromContext(ctx).Error("logic.GetModeratorRole failed",
+			zap.Int64("community_id", communityID),
+			zap.Int64("user_id", targetID),
+			zap.Error(err))
+		HandleError(c, err)
+		return false
+	}
+	if targetRole >= operatorRole {
+		ResponseError(c, CodeNoPermission)
+		return false
+	}
+	return true
+}
+
+// ModeratePostHandler 版主删除帖子
+// @Summary 版主删除帖子
+// @Description 版主删除自己社区内的帖子
+// @Tags 版主相关接口(api分组展示使用的)
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer JWT"
+// @Param post_id path int true "帖子ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ResponseSuccess "成功响应"
+// @Success 400 {object} models.ResponseError "响应错误"
+// @Success 500 {object} models.ResponseError "服务器错误"
+// @Router /moderation/posts/{post_id} [delete]
+func ModeratePostHandler(c *gin.Context) {
+	communityID, _ := getCurrentCommunityID(c)
+	postID, err := strconv.ParseInt(c.Param("post_id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	if err := logic.ModeratePost(c.Request.Context(), communityID, postID); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.ModeratePost failed", zap.Int64("post_id", postID), zap.Error(err))
+		HandleError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// RemoveCommentHandler 版主删除评论
+// @Summary 版主删除评论
+// @Description 版主删除自己社区内帖子下的评论
+// @Tags 版主相关接口(api分组展示使用的)
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer JWT"
+// @Param post_id path int true "帖子ID"
+// @Param comment_id path int true "评论ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ResponseSuccess "成功响应"
+// @Success 400 {object} models.ResponseError "响应错误"
+// @Success 500 {object} models.ResponseError "服务器错误"
+// @Router /moderation/posts/{post_id}/comments/{comment_id} [delete]
+func RemoveCommentHandler(c *gin.Context) {
+	postID, err := strconv.ParseInt(c.Param("post_id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	commentID, err := strconv.ParseInt(c.Param("comment_id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	if err := logic.RemoveComment(c.Request.Context(), postID, commentID); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.RemoveComment failed",
+			zap.Int64("post_id", postID),
+			zap.Int64("comment_id", commentID),
+			zap.Error(err))
+		HandleError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// PinPostHandler 置顶帖子
+// @Summary 置顶帖子
+// @Description 版主在自己的社区内置顶帖子
+// @Tags 版主相关接口(api分组展示使用的)
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer JWT"
+// @Param post_id path int true "帖子ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ResponseSuccess "成功响应"
+// @Success 400 {object} models.ResponseError "响应错误"
+// @Success 500 {object} models.ResponseError "服务器错误"
+// @Router /moderation/posts/{post_id}/pin [post]
+func PinPostHandler(c *gin.Context) {
+	communityID, _ := getCurrentCommunityID(c)
+	postID, err := strconv.ParseInt(c.Param("post_id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	if err := logic.PinPost(c.Request.Context(), communityID, postID); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.PinPost failed", zap.Int64("post_id", postID), zap.Error(err))
+		HandleError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// UnpinPostHandler 取消置顶帖子
+// @Summary 取消置顶帖子
+// @Description 版主取消自己社区内帖子的置顶
+// @Tags 版主相关接口(api分组展示使用的)
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer JWT"
+// @Param post_id path int true "帖子ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ResponseSuccess "成功响应"
+// @Success 400 {object} models.ResponseError "响应错误"
+// @Success 500 {object} models.ResponseError "服务器错误"
+// @Router /moderation/posts/{post_id}/pin [delete]
+func UnpinPostHandler(c *gin.Context) {
+	communityID, _ := getCurrentCommunityID(c)
+	postID, err := strconv.ParseInt(c.Param("post_id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	if err := logic.UnpinPost(c.Request.Context(), communityID, postID); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.UnpinPost failed", zap.Int64("post_id", postID), zap.Error(err))
+		HandleError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// BanUserHandler 社区内禁言用户
+// @Summary 社区内禁言用户
+// @Description 版主在自己的社区内禁言用户,duration为0表示永久禁言,只能禁言社区角色比自己低的用户
+// @Tags 版主相关接口(api分组展示使用的)
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer JWT"
+// @Param community_id path int true "社区ID"
+// @Param object body models.ParamBanUser true "禁言信息"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ResponseSuccess "成功响应"
+// @Success 400 {object} models.ResponseError "响应错误"
+// @Success 500 {object} models.ResponseError "服务器错误"
+// @Router /moderation/communities/{community_id}/bans [post]
+func BanUserHandler(c *gin.Context) {
+	communityID, _ := getCurrentCommunityID(c)
+	p := new(models.ParamBanUser)
+	if err := c.ShouldBindJSON(p); err != nil {
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		ResponseErrorWithMsg(c, CodeInvalidParam, translateErrors(c, errs))
+		return
+	}
+	operatorID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	// 版主不能禁言社区所有者或其他版主
+	if !checkTargetRole(c, communityID, p.UserID) {
+		return
+	}
+	if err := logic.BanUser(c.Request.Context(), communityID, operatorID, p); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.BanUser failed", zap.Int64("community_id", communityID), zap.Error(err))
+		HandleError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// UnbanUserHandler 解除社区内的禁言
+// @Summary 解除社区内的禁言
+// @Description 版主解除自己社区内用户的禁言,只能解除社区角色比自己低的用户的禁言
+// @Tags 版主相关接口(api分组展示使用的)
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer JWT"
+// @Param community_id path int true "社区ID"
+// @Param user_id path int true "用户ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ResponseSuccess "成功响应"
+// @Success 400 {object} models.ResponseError "响应错误"
+// @Success 500 {object} models.ResponseError "服务器错误"
+// @Router /moderation/communities/{community_id}/bans/{user_id} [delete]
+func UnbanUserHandler(c *gin.Context) {
+	communityID, _ := getCurrentCommunityID(c)
+	userID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	if !checkTargetRole(c, communityID, userID) {
+		return
+	}
+	if err := logic.UnbanUser(c.Request.Context(), communityID, userID); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.UnbanUser failed", zap.Int64("community_id", communityID), zap.Error(err))
+		HandleError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// GetReviewPostsHandler 查询待审核的帖子
+// @Summary 查询待审核的帖子
+// @Description 查询社区内命中需要审核的敏感词的帖子,先发布的在前
+// @Tags 版主相关接口(api分组展示使用的)
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer JWT"
+// @Param community_id path int true "社区ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ResponseSuccess "成功响应"
+// @Success 400 {object} models.ResponseError "响应错误"
+// @Success 500 {object} models.ResponseError "服务器错误"
+// @Router /moderation/communities/{community_id}/reviews [get]
+func GetReviewPostsHandler(c *gin.Context) {
+	communityID, _ := getCurrentCommunityID(c)
+	data, err := logic.GetReviewPosts(c.Request.Context(), communityID)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.GetReviewPosts failed", zap.Int64("community_id", communityID), zap.Error(err))
+		HandleError(c, err)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// ApprovePostHandler 审核通过帖子
+// @Summary 审核通过帖子
+// @Description 版主审核通过帖子,把帖子移出审核队列;审核不通过时删除帖子
+// @Tags 版主相关接口(api分组展示使用的)
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer JWT"
+// @Param community_id path int true "社区ID"
+// @Param post_id path int true "帖子ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ResponseSuccess "成功响应"
+// @Success 400 {object} models.ResponseError "响应错误"
+// @Success 500 {object} models.ResponseError "服务器错误"
+// @Router /moderation/communities/{community_id}/reviews/{post_id} [delete]
+func ApprovePostHandler(c *gin.Context) {
+	communityID, _ := getCurrentCommunityID(c)
+	postID, err := strconv.ParseInt(c.Param("post_id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	if err := logic.ApprovePost(c.Request.Context(), communityID, postID); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.ApprovePost failed", zap.Int64("post_id", postID), zap.Error(err))
+		HandleError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"bluebell/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBanUserTargetRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// 社区1中用户1是所有者,用户2和3是版主
+	roles := map[int64]int8{
+		1: models.ModeratorRoleOwner,
+		2: models.ModeratorRoleModerator,
+		3: models.ModeratorRoleModerator,
+	}
+	orig := getModeratorRole
+	getModeratorRole = func(_ context.Context, communityID, userID int64) (int8, error) {
+		return roles[userID], nil
+	}
+	t.Cleanup(func() { getModeratorRole = orig })
+
+	r := gin.New()
+	// 模拟JWT和版主中间件: 当前用户是社区1的版主2
+	r.Use(func(c *gin.Context) {
+		c.Set(CtxUserIDKey, int64(2))
+		c.Set(CtxUserRoleKey, "user")
+		c.Set(CtxCommunityIDKey, int64(1))
+	})
+	r.POST("/bans", BanUserHandler)
+	r.DELETE("/bans/:user_id", UnbanUserHandler)
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		body   string
+	}{
+		{"ban owner", http.MethodPost, "/bans", `{"user_id": "1"}`},
+		{"ban peer moderator", http.MethodPost, "/bans", `{"user_id": "3"}`},
+		{"unban owner", http.MethodDelete, "/bans/1", ""},
+		{"unban peer moderator", http.MethodDelete, "/bans/3", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.url, bytes.NewReader([]byte(tt.body)))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusForbidden, w.Code)
+			res := new(ResponseData)
+			if err := json.Unmarshal(w.Body.Bytes(), res); err != nil {
+				t.Fatalf("json.Unmarshal w.Body failed, err:%v\n", err)
+			}
+			assert.Equal(t, CodeNoPermission, res.Code)
+		})
+	}
+}
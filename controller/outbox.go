@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"bluebell/logger"
+	"bluebell/logic"
+	"bluebell/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+// GetDeadOutboxEventsHandler 查询不再自动重试的帖子变更事件
+// @Summary 查询不再自动重试的帖子变更事件
+// @Description 管理员分页查询重试次数用完仍然失败的帖子变更事件,排查失败原因后重新加入队列
+// @Tags 管理员相关接口(api分组展示使用的)
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer JWT"
+// @Param page query int false "页码"
+// @Param size query int false "每页数据量"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ResponseSuccess "成功响应"
+// @Success 500 {object} models.ResponseError "服务器错误"
+// @Router /manager/outbox/dead [get]
+func GetDeadOutboxEventsHandler(c *gin.Context) {
+	page, size := getPageInfo(c)
+	data, err := logic.GetDeadOutboxEvents(c.Request.Context(), page, size)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.GetDeadOutboxEvents failed", zap.Error(err))
+		HandleError(c, err)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// RequeueOutboxEventsHandler 重新处理不再自动重试的帖子变更事件
+// @Summary 重新处理帖子变更事件
+// @Description 管理员把不再自动重试的帖子变更事件重新加入队列,ids为空时重新加入所有这样的事件,返回重新加入的事件数
+// @Tags 管理员相关接口(api分组展示使用的)
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer JWT"
+// @Param object body models.ParamRequeueOutbox true "事件ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ResponseSuccess "成功响应"
+// @Success 400 {object} models.ResponseError "响应错误"
+// @Success 500 {object} models.ResponseError "服务器错误"
+// @Router /manager/outbox/requeue [post]
+func RequeueOutboxEventsHandler(c *gin.Context) {
+	p := new(models.ParamRequeueOutbox)
+	if err := c.ShouldBindJSON(p); err != nil {
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		ResponseErrorWithMsg(c, CodeInvalidParam, translateErrors(c, errs))
+		return
+	}
+	n, err := logic.RequeueOutboxEvents(c.Request.Context(), p.IDs)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.RequeueOutboxEvents failed", zap.Int64s("ids", p.IDs), zap.Error(err))
+		HandleError(c, err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"requeued": n})
+}
@@ -1,14 +1,15 @@
 package controller
 
 import (
+	"bluebell/logger"
 	"bluebell/logic"
 	"bluebell/models"
-	"bluebell/pkg/badword"
-	"fmt"
+	"bluebell/pkg/cursor"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
 	// swagger 嵌入文件
 )
@@ -31,28 +32,11 @@ func CreatePostHandler(c *gin.Context) {
 	//c.ShouldBindJSON()  // validator --> binding tag
 	p := new(models.Post)
 	if err := c.ShouldBindJSON(p); err != nil {
-		zap.L().Debug("c.ShouldBindJSON(p) error", zap.Any("err", err))
-		zap.L().Error("create post with invalid param")
+		logger.FromContext(c.Request.Context()).Debug("c.ShouldBindJSON(p) error", zap.Any("err", err))
+		logger.FromContext(c.Request.Context()).Error("create post with invalid param")
 		ResponseError(c, CodeInvalidParam)
 		return
 	}
-	// 初始化敏感词树
-	SensitiveTree := badword.NewTrieV1()
-	// 初始化敏感词库
-_:
-	SensitiveTree.LoadWordsFromFile("./pkg/sensitivewords/广告.txt ")
-_:
-	SensitiveTree.LoadWordsFromFile("./pkg/sensitivewords/政治类.txt ")
-_:
-	SensitiveTree.LoadWordsFromFile("./pkg/sensitivewords/涉枪涉爆违法信息关键词.txt ")
-_:
-	SensitiveTree.LoadWordsFromFile("./pkg/sensitivewords/网址.txt ")
-_:
-	SensitiveTree.LoadWordsFromFile("./pkg/sensitivewords/色情类.txt ")
-	if err := SensitiveTree.Contains(p.Content); err != false {
-		ResponseError(c, CodeServerBusy)
-		return
-	}
 	// 从 c 取到当前发请求的用户的ID
 	userID, err := getCurrentUserID(c)
 	if err != nil {
@@ -60,10 +44,14 @@ _:
 		return
 	}
 	p.AuthorID = userID
+	// 被禁言或封禁的用户不能发帖
+	if !checkUserRestriction(c, userID, p.CommunityID) {
+		return
+	}
 	// 2. 创建帖子
-	if err := logic.CreatePost(p); err != nil {
-		zap.L().Error("logic.CreatePost(p) failed", zap.Error(err))
-		ResponseError(c, CodeServerBusy)
+	if err := logic.CreatePost(c.Request.Context(), p); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.CreatePost(p) failed", zap.Error(err))
+		HandleError(c, err)
 		return
 	}
 
@@ -89,34 +77,89 @@ func GetPostDetailHandler(c *gin.Context) {
 	pidStr := c.Param("id")
 	pid, err := strconv.ParseInt(pidStr, 10, 64)
 	if err != nil {
-		zap.L().Error("get post detail with invalid param", zap.Error(err))
+		logger.FromContext(c.Request.Context()).Error("get post detail with invalid param", zap.Error(err))
 		ResponseError(c, CodeInvalidParam)
 		return
 	}
 
 	// 2. 根据id取出帖子数据（查数据库）
-	data, err := logic.GetPostById(pid)
+	viewerID, _ := getCurrentUserID(c)
+	data, err := logic.GetPostById(c.Request.Context(), pid, viewerID)
 	if err != nil {
-		zap.L().Error("logic.GetPostById(pid) failed", zap.Error(err))
-		ResponseError(c, CodeServerBusy)
+		logger.FromContext(c.Request.Context()).Error("logic.GetPostById(pid) failed", zap.Error(err))
+		HandleError(c, err)
 		return
 	}
 	// 3. 返回响应
 	ResponseSuccess(c, data)
 }
 
+// EditPostHandler 修改帖子
+// @Summary 修改帖子
+// @Description 作者修改自己帖子的标题和内容
+// @Tags 帖子相关接口(api分组展示使用的)
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer JWT"
+// @Param id path int true "帖子ID"
+// @Param object body models.ParamEditPost true "帖子信息"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ResponseSuccess "成功响应"
+// @Success 400 {object} models.ResponseError "响应错误"
+// @Success 500 {object} models.ResponseError "服务器错误"
+// @Router /post/{id} [put]
+func EditPostHandler(c *gin.Context) {
+	postID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	p := new(models.ParamEditPost)
+	if err := c.ShouldBindJSON(p); err != nil {
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		ResponseErrorWithMsg(c, CodeInvalidParam, translateErrors(c, errs))
+		return
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	// 被禁言或封禁的用户不能修改帖子
+	communityID, err := logic.GetPostCommunityID(c.Request.Context(), postID)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.GetPostCommunityID failed", zap.Int64("post_id", postID), zap.Error(err))
+		HandleError(c, invalidIDAs(err, CodePostNotExist))
+		return
+	}
+	if !checkUserRestriction(c, userID, communityID) {
+		return
+	}
+	if err := logic.EditPost(c.Request.Context(), userID, postID, p); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.EditPost failed", zap.Int64("post_id", postID), zap.Error(err))
+		HandleError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
 // GetPostListHandler 获取帖子列表的处理函数
 func GetPostListHandler(c *gin.Context) {
 	// 获取分页参数
 	page, size := getPageInfo(c)
 	// 获取数据
-	data, err := logic.GetPostList(page, size)
+	viewerID, _ := getCurrentUserID(c)
+	data, next, err := logic.GetPostList(c.Request.Context(), page, size, c.Query("cursor"), viewerID)
 	if err != nil {
-		zap.L().Error("logic.GetPostList() failed", zap.Error(err))
-		ResponseError(c, CodeServerBusy)
+		logger.FromContext(c.Request.Context()).Error("logic.GetPostList() failed", zap.Error(err))
+		HandleError(c, err)
 		return
 	}
-	ResponseSuccess(c, data)
+	ResponseSuccessWithCursor(c, data, next)
 	// 返回响应
 }
 
@@ -134,7 +177,7 @@ func GetPostListHandler(c *gin.Context) {
 // @Success 500 {object} models.ResponseError "服务器错误"
 // @Router /posts2 [get]
 func GetPostListHandler2(c *gin.Context) {
-	// GET请求参数(query string)：/api/v1/posts2?page=1&size=10&order=time
+	// GET请求参数(query string)：/api/v1/posts2?cursor=xxx&size=10&order=time
 	// 初始化结构体时指定初始参数
 	p := &models.ParamPostList{
 		Page:  1,
@@ -144,19 +187,20 @@ func GetPostListHandler2(c *gin.Context) {
 	//c.ShouldBind()  根据请求的数据类型选择相应的方法去获取数据
 	//c.ShouldBindJSON() 如果请求中携带的是json格式的数据，才能用这个方法获取到数据
 	if err := c.ShouldBindQuery(p); err != nil {
-		zap.L().Error("GetPostListHandler2 with invalid params", zap.Error(err))
+		logger.FromContext(c.Request.Context()).Error("GetPostListHandler2 with invalid params", zap.Error(err))
 		ResponseError(c, CodeInvalidParam)
 		return
 	}
-	data, err := logic.GetPostListNew(p) // 更新：合二为一
-	fmt.Println(data)
+	p.Size = cursor.ClampSize(p.Size)
+	viewerID, _ := getCurrentUserID(c)
+	data, next, err := logic.GetPostListNew(c.Request.Context(), p, viewerID) // 更新：合二为一
 	// 获取数据
 	if err != nil {
-		zap.L().Error("logic.GetPostList() failed", zap.Error(err))
-		ResponseError(c, CodeServerBusy)
+		logger.FromContext(c.Request.Context()).Error("logic.GetPostList() failed", zap.Error(err))
+		HandleError(c, err)
 		return
 	}
-	ResponseSuccess(c, data)
+	ResponseSuccessWithCursor(c, data, next)
 	// 返回响应
 }
 
@@ -176,14 +220,24 @@ func GetPostListHandler2(c *gin.Context) {
 // @Router /user/:user_id/avatar [post]
 func PostAvatar(c *gin.Context) {
 	// 获取参数，id与file
-	userID := c.PostForm("user_id")
+	userID, err := strconv.ParseInt(c.PostForm("user_id"), 10, 64)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("PostAvatar with invalid user_id", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
 	file, err := c.FormFile("avatar")
 	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("PostAvatar with invalid params", zap.Error(err))
 		ResponseError(c, CodeInvalidParam)
-		zap.Error(err)
+		return
 	}
 	// 传递参数到logic层
-	logic.PostAvatar(c, userID, file)
+	if err := logic.PostAvatar(c, userID, file); err != nil {
+		HandleError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
 }
 
 // PostTop 置顶帖子功能实现
@@ -210,14 +264,21 @@ func GetPostBySelect(c *gin.Context) {
 		ResponseError(c, CodeInvalidParam)
 		return
 	}
-	post, err := logic.GetPostByTitle(title)
+	post, err := logic.GetPostByTitle(c.Request.Context(), title)
 	if err != nil {
-		ResponseError(c, CodeInvalidParam)
+		logger.FromContext(c.Request.Context()).Error("logic.GetPostByTitle failed", zap.String("title", title), zap.Error(err))
+		HandleError(c, err)
 		return
 	}
 	ResponseSuccess(c, post)
 }
 
+// 发布评论依赖的logic函数,测试时替换
+var (
+	getPostCommunityID = logic.GetPostCommunityID
+	postComment        = logic.PostComment
+)
+
 // PostComment 发布帖子评论的处理函数
 // @Summary 发布帖子评论的处理函数
 // @Description 获取有关帖子的所有评论
@@ -236,15 +297,33 @@ func PostComment(c *gin.Context) {
 		Time: time.Now().Unix(),
 	}
 	if err := c.ShouldBindJSON(comment); err != nil {
-		zap.L().Error("PostComment with invalid params", zap.Error(err))
+		logger.FromContext(c.Request.Context()).Error("PostComment with invalid params", zap.Error(err))
 		ResponseError(c, CodeInvalidParam)
 		return
 	}
-	if err := logic.PostComment(comment); err != nil {
-		zap.L().Error("logic.PostComment(comment) err", zap.Error(err))
-		ResponseError(c, CodeInvalidParam)
+	// 评论者就是当前登录的用户
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
 		return
 	}
+	comment.UserID = userID
+	// 被禁言或封禁的用户不能评论
+	communityID, err := getPostCommunityID(c.Request.Context(), comment.ID)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.GetPostCommunityID failed", zap.Int64("post_id", comment.ID), zap.Error(err))
+		HandleError(c, invalidIDAs(err, CodePostNotExist))
+		return
+	}
+	if !checkUserRestriction(c, userID, communityID) {
+		return
+	}
+	if err := postComment(c.Request.Context(), comment); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.PostComment(comment) err", zap.Error(err))
+		HandleError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
 }
 
 // GetCommentsHandler 获取帖子评论的处理函数
@@ -254,29 +333,32 @@ func PostComment(c *gin.Context) {
 // @Accept application/json
 // @Produce application/json
 // @Param Authorization header string true "Bearer JWT"
-// @Param post_id path int true "帖子ID"
+// @Param post_id query int true "帖子ID"
+// @Param cursor query string false "上一页响应中的next_cursor"
+// @Param size query int false "每页数据量"
 // @Security ApiKeyAuth
 // @Success 200 {object} models.ResponseSuccess "成功响应"
 // @Failure 400 {object} models.ResponseError "响应错误"
 // @Failure 500 {object} models.ResponseError "服务器错误"
-// @Router /api/v1/comments/{post_id} [get]
+// @Router /comment/get [get]
 func GetCommentsHandler(c *gin.Context) {
-	postIDStr := c.Param("post_id")
+	postIDStr := c.Query("post_id")
 	postID, err := strconv.ParseInt(postIDStr, 10, 64)
 	if err != nil {
-		zap.L().Error("GetCommentsHandler invalid post_id", zap.Error(err))
+		logger.FromContext(c.Request.Context()).Error("GetCommentsHandler invalid post_id", zap.Error(err))
 		ResponseError(c, CodeInvalidParam)
 		return
 	}
+	_, size := getPageInfo(c)
 
-	comments, err := logic.GetComments(postID)
+	comments, next, err := logic.GetComments(c.Request.Context(), postID, c.Query("cursor"), size)
 	if err != nil {
-		zap.L().Error("GetCommentsHandler logic.GetComments error", zap.Error(err))
-		ResponseError(c, CodeServerBusy)
+		logger.FromContext(c.Request.Context()).Error("GetCommentsHandler logic.GetComments error", zap.Error(err))
+		HandleError(c, err)
 		return
 	}
 
-	ResponseSuccess(c, comments)
+	ResponseSuccessWithCursor(c, comments, next)
 }
 
 //// 根据社区去查询帖子列表
@@ -292,7 +374,7 @@ func GetCommentsHandler(c *gin.Context) {
 //	//c.ShouldBind()  根据请求的数据类型选择相应的方法去获取数据
 //	//c.ShouldBindJSON() 如果请求中携带的是json格式的数据，才能用这个方法获取到数据
 //	if err := c.ShouldBindQuery(p); err != nil {
-//		zap.L().Error("GetCommunityPostListHandler with invalid params", zap.Error(err))
+//		logger.FromContext(c.Request.Context()).Error("GetCommunityPostListHandler with invalid params", zap.Error(err))
 //		ResponseError(c, CodeInvalidParam)
 //		return
 //	}
@@ -300,7 +382,7 @@ func GetCommentsHandler(c *gin.Context) {
 //	// 获取数据
 //	data, err := logic.GetCommunityPostList(p)
 //	if err != nil {
-//		zap.L().Error("logic.GetPostList() failed", zap.Error(err))
+//		logger.FromContext(c.Request.Context()).Error("logic.GetPostList() failed", zap.Error(err))
 //		ResponseError(c, CodeServerBusy)
 //		return
 //	}
@@ -1,7 +1,10 @@
 package controller
 
 import (
+	"bluebell/logic"
+	"bluebell/models"
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -29,7 +32,7 @@ func TestCreatePostHandler(t *testing.T) {
 	w := httptest.NewRecorder()
 	r.ServeHTTP(w, req)
 
-	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
 
 	// 判断响应的内容是不是按预期返回了需要登录的错误
 
@@ -43,3 +46,33 @@ func TestCreatePostHandler(t *testing.T) {
 	}
 	assert.Equal(t, res.Code, CodeNeedLogin)
 }
+
+func TestPostCommentSensitiveWord(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	origCommunity, origRestriction, origComment := getPostCommunityID, checkRestriction, postComment
+	t.Cleanup(func() {
+		getPostCommunityID, checkRestriction, postComment = origCommunity, origRestriction, origComment
+	})
+	getPostCommunityID = func(context.Context, int64) (int64, error) { return 1, nil }
+	checkRestriction = func(context.Context, int64, int64) error { return nil }
+	postComment = func(context.Context, *models.Comment) error {
+		return &logic.SensitiveWordError{Field: "content", Category: "广告", Word: "加微信"}
+	}
+
+	r := gin.New()
+	r.Use(ErrorHandler(), func(c *gin.Context) { c.Set(CtxUserIDKey, int64(7)) })
+	url := "/api/v1/comments"
+	r.POST(url, PostComment)
+	body := `{"id": "1", "content": "加微信领红包"}`
+	req, _ := http.NewRequest(http.MethodPost, url, bytes.NewReader([]byte(body)))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	// 评论命中敏感词时和发帖一样返回分类对应的错误码
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	res := new(ResponseData)
+	if err := json.Unmarshal(w.Body.Bytes(), res); err != nil {
+		t.Fatalf("json.Unmarshal w.Body failed, err:%v\n", err)
+	}
+	assert.Equal(t, CodeSensitiveAd, res.Code)
+}
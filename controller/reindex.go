@@ -0,0 +1,32 @@
+package controller
+
+import (
+	"bluebell/logger"
+	"bluebell/logic"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ReindexHandler 从MySQL重建redis中的帖子索引
+// @Summary 重建redis中的帖子索引
+// @Description 管理员从MySQL重建帖子的时间zset、分数zset和社区set,使用bleve搜索时同时重建本实例的搜索索引,dry_run为true时只统计不一致的数量
+// @Tags 管理员相关接口(api分组展示使用的)
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer JWT"
+// @Param dry_run query bool false "只统计不修改"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ResponseSuccess "成功响应"
+// @Success 500 {object} models.ResponseError "服务器错误"
+// @Router /manager/reindex [post]
+func ReindexHandler(c *gin.Context) {
+	dryRun := c.Query("dry_run") == "true"
+	report, err := logic.Reindex(c.Request.Context(), dryRun)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.Reindex failed", zap.Bool("dry_run", dryRun), zap.Error(err))
+		HandleError(c, err)
+		return
+	}
+	ResponseSuccess(c, report)
+}
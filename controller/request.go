@@ -1,13 +1,18 @@
 package controller
 
 import (
+	"bluebell/pkg/cursor"
 	"errors"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
-const CtxUserIDKey = "userID"
+const (
+	CtxUserIDKey      = "userID"
+	CtxUserRoleKey    = "userRole"
+	CtxCommunityIDKey = "communityID"
+)
 
 var ErrorUserNotLogin = errors.New("用户未登录")
 
@@ -26,6 +31,21 @@ func getCurrentUserID(c *gin.Context) (userID int64, err error) {
 	return
 }
 
+// getCurrentUserRole 获取当前登录用户在JWT中的角色
+func getCurrentUserRole(c *gin.Context) string {
+	return c.GetString(CtxUserRoleKey)
+}
+
+// getCurrentCommunityID 获取版主中间件解析出的社区ID
+func getCurrentCommunityID(c *gin.Context) (communityID int64, ok bool) {
+	cid, ok := c.Get(CtxCommunityIDKey)
+	if !ok {
+		return
+	}
+	communityID, ok = cid.(int64)
+	return
+}
+
 func getPageInfo(c *gin.Context) (int64, int64) {
 	pageStr := c.Query("page")
 	sizeStr := c.Query("size")
@@ -44,5 +64,5 @@ func getPageInfo(c *gin.Context) (int64, int64) {
 	if err != nil {
 		size = 10
 	}
-	return page, size
+	return page, cursor.ClampSize(size)
 }
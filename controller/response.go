@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"bluebell/pkg/i18n"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -11,36 +12,55 @@ import (
 	"code": 10000, // 程序中的错误码
 	"msg": xx,     // 提示信息
 	"data": {},    // 数据
+	"detail": {},  // 错误的详细信息,只在出错时返回
+	"request_id": "", // 请求id,只在出错时返回,用于查找日志
 }
 
 */
 
 type ResponseData struct {
-	Code ResCode     `json:"code"`
-	Msg  interface{} `json:"msg"`
-	Data interface{} `json:"data,omitempty"`
+	Code       ResCode     `json:"code"`
+	Msg        interface{} `json:"msg"`
+	Data       interface{} `json:"data,omitempty"`
+	NextCursor string      `json:"next_cursor,omitempty"` // 列表接口下一页的游标,没有下一页时不返回
+	Detail     interface{} `json:"detail,omitempty"`      // 错误的详细信息,如参数校验失败的字段
+	RequestID  string      `json:"request_id,omitempty"`  // 出错时返回请求id,与日志中的request_id对应
 }
 
+// ResponseError 返回业务状态码对应的错误,HTTP状态码见 ResCode.Status
 func ResponseError(c *gin.Context, code ResCode) {
-	c.JSON(http.StatusOK, &ResponseData{
-		Code: code,
-		Msg:  code.Msg(),
-		Data: nil,
-	})
+	renderError(c, NewAppError(code))
 }
 
+// ResponseErrorWithMsg 返回带有详细信息的错误,如参数校验失败的字段
 func ResponseErrorWithMsg(c *gin.Context, code ResCode, msg interface{}) {
-	c.JSON(http.StatusOK, &ResponseData{
-		Code: code,
-		Msg:  msg,
-		Data: nil,
-	})
+	renderError(c, NewAppError(code).WithDetail(msg))
+}
+
+// ResponseErrorWithData 同 ResponseErrorWithMsg,兼容模式下详细信息放在data中
+func ResponseErrorWithData(c *gin.Context, code ResCode, data interface{}) {
+	renderError(c, NewAppError(code).WithDetail(data))
 }
 
 func ResponseSuccess(c *gin.Context, data interface{}) {
 	c.JSON(http.StatusOK, &ResponseData{
 		Code: CodeSuccess,
-		Msg:  CodeSuccess.Msg(),
+		Msg:  msgOf(c, CodeSuccess),
 		Data: data,
 	})
 }
+
+// ResponseSuccessWithCursor 列表接口的成功响应,带上下一页的游标
+func ResponseSuccessWithCursor(c *gin.Context, data interface{}, next string) {
+	c.JSON(http.StatusOK, &ResponseData{
+		Code:       CodeSuccess,
+		Msg:        msgOf(c, CodeSuccess),
+		Data:       data,
+		NextCursor: next,
+	})
+}
+
+// msgOf 请求的语言对应的提示信息
+func msgOf(c *gin.Context, code ResCode) string {
+	return code.MsgIn(i18n.FromContext(c.Request.Context()))
+}
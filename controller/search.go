@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"bluebell/logger"
+	"bluebell/logic"
+	"bluebell/models"
+	"bluebell/pkg/cursor"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+// SearchHandler 全文搜索帖子
+// @Summary 全文搜索帖子
+// @Description 按关键词搜索帖子的标题和内容,可按社区、作者及发帖时间过滤,返回高亮后的片段及命中总数
+// @Tags 帖子相关接口(api分组展示使用的)
+// @Accept application/json
+// @Produce application/json
+// @Param object query models.ParamSearch true "查询参数"
+// @Success 200 {object} models.ResponseSuccess "成功响应"
+// @Success 400 {object} models.ResponseError "响应错误"
+// @Success 500 {object} models.ResponseError "服务器错误"
+// @Router /search [get]
+func SearchHandler(c *gin.Context) {
+	// GET请求参数(query string)：/api/v1/search?q=golang&community_id=1&author=q1mi&since=2020-08-01&cursor=xxx
+	p := &models.ParamSearch{
+		Page: 1,
+		Size: 10,
+	}
+	if err := c.ShouldBindQuery(p); err != nil {
+		logger.FromContext(c.Request.Context()).Error("SearchHandler with invalid params", zap.Error(err))
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		ResponseErrorWithMsg(c, CodeInvalidParam, translateErrors(c, errs))
+		return
+	}
+	p.Size = cursor.ClampSize(p.Size)
+	viewerID, _ := getCurrentUserID(c)
+	data, next, err := logic.Search(c.Request.Context(), p, viewerID)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.Search failed", zap.String("q", p.Q), zap.Error(err))
+		HandleError(c, err)
+		return
+	}
+	ResponseSuccessWithCursor(c, data, next)
+}
@@ -0,0 +1,190 @@
+package controller
+
+import (
+	"bluebell/logger"
+	"bluebell/logic"
+	"bluebell/models"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+// ---- 跟敏感词库相关的 ----
+
+// maxSensitiveWordFileSize 导入的词库文件的大小上限
+const maxSensitiveWordFileSize = 4 << 20
+
+// GetSensitiveWordCategoriesHandler 查询敏感词分类
+// @Summary 查询敏感词分类
+// @Description 管理员查询所有的敏感词分类及每个分类中词的数量
+// @Tags 管理员相关接口(api分组展示使用的)
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer JWT"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ResponseSuccess "成功响应"
+// @Success 500 {object} models.ResponseError "服务器错误"
+// @Router /manager/sensitive-words [get]
+func GetSensitiveWordCategoriesHandler(c *gin.Context) {
+	data, err := logic.GetSensitiveWordCategories(c.Request.Context())
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.GetSensitiveWordCategories failed", zap.Error(err))
+		HandleError(c, err)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// ExportSensitiveWordsHandler 导出敏感词
+// @Summary 导出敏感词
+// @Description 管理员导出分类中的所有敏感词,每行一个词,可以直接用于导入
+// @Tags 管理员相关接口(api分组展示使用的)
+// @Accept application/json
+// @Produce text/plain
+// @Param Authorization header string true "Bearer JWT"
+// @Param category path string true "分类"
+// @Security ApiKeyAuth
+// @Success 200 {string} string "词库文件"
+// @Success 400 {object} models.ResponseError "响应错误"
+// @Success 500 {object} models.ResponseError "服务器错误"
+// @Router /manager/sensitive-words/{category} [get]
+func ExportSensitiveWordsHandler(c *gin.Context) {
+	category := c.Param("category")
+	words, err := logic.ExportSensitiveWords(c.Request.Context(), category)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.ExportSensitiveWords failed", zap.String("category", category), zap.Error(err))
+		HandleError(c, err)
+		return
+	}
+	var b strings.Builder
+	for _, w := range words {
+		b.WriteString(w)
+		b.WriteByte('\n')
+	}
+	c.Header("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(category+".txt"))
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(b.String()))
+}
+
+// AddSensitiveWordsHandler 添加敏感词
+// @Summary 添加敏感词
+// @Description 管理员向分类中添加敏感词,分类不存在时自动创建,所有实例立即生效
+// @Tags 管理员相关接口(api分组展示使用的)
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer JWT"
+// @Param category path string true "分类"
+// @Param object body models.ParamSensitiveWords true "敏感词"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ResponseSuccess "成功响应"
+// @Success 400 {object} models.ResponseError "响应错误"
+// @Success 500 {object} models.ResponseError "服务器错误"
+// @Router /manager/sensitive-words/{category} [post]
+func AddSensitiveWordsHandler(c *gin.Context) {
+	category := c.Param("category")
+	p, ok := bindSensitiveWords(c)
+	if !ok {
+		return
+	}
+	data, err := logic.AddSensitiveWords(c.Request.Context(), category, p.Words)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.AddSensitiveWords failed", zap.String("category", category), zap.Error(err))
+		HandleError(c, err)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// RemoveSensitiveWordsHandler 删除敏感词
+// @Summary 删除敏感词
+// @Description 管理员从分类中删除敏感词,所有实例立即生效
+// @Tags 管理员相关接口(api分组展示使用的)
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer JWT"
+// @Param category path string true "分类"
+// @Param object body models.ParamSensitiveWords true "敏感词"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ResponseSuccess "成功响应"
+// @Success 400 {object} models.ResponseError "响应错误"
+// @Success 500 {object} models.ResponseError "服务器错误"
+// @Router /manager/sensitive-words/{category} [delete]
+func RemoveSensitiveWordsHandler(c *gin.Context) {
+	category := c.Param("category")
+	p, ok := bindSensitiveWords(c)
+	if !ok {
+		return
+	}
+	data, err := logic.RemoveSensitiveWords(c.Request.Context(), category, p.Words)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.RemoveSensitiveWords failed", zap.String("category", category), zap.Error(err))
+		HandleError(c, err)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// ImportSensitiveWordsHandler 导入敏感词
+// @Summary 导入敏感词
+// @Description 管理员上传每行一个词的文本文件导入到分类中,replace为true时替换分类中原有的词,所有实例立即生效
+// @Tags 管理员相关接口(api分组展示使用的)
+// @Accept multipart/form-data
+// @Produce application/json
+// @Param Authorization header string true "Bearer JWT"
+// @Param category path string true "分类"
+// @Param replace query bool false "是否替换分类中原有的词"
+// @Param file formData file true "词库文件"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ResponseSuccess "成功响应"
+// @Success 400 {object} models.ResponseError "响应错误"
+// @Success 500 {object} models.ResponseError "服务器错误"
+// @Router /manager/sensitive-words/{category}/import [post]
+func ImportSensitiveWordsHandler(c *gin.Context) {
+	category := c.Param("category")
+	replace, err := strconv.ParseBool(c.DefaultQuery("replace", "false"))
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	header, err := c.FormFile("file")
+	if err != nil || header.Size > maxSensitiveWordFileSize {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("open uploaded sensitive word file failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	defer file.Close()
+	data, err := logic.ImportSensitiveWords(c.Request.Context(), category, file, replace)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.ImportSensitiveWords failed",
+			zap.String("category", category),
+			zap.Bool("replace", replace),
+			zap.Error(err))
+		HandleError(c, err)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// bindSensitiveWords 解析请求中的敏感词,失败时已经写入响应
+func bindSensitiveWords(c *gin.Context) (*models.ParamSensitiveWords, bool) {
+	p := new(models.ParamSensitiveWords)
+	if err := c.ShouldBindJSON(p); err != nil {
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(c, CodeInvalidParam)
+			return nil, false
+		}
+		ResponseErrorWithMsg(c, CodeInvalidParam, translateErrors(c, errs))
+		return nil, false
+	}
+	return p, true
+}
@@ -2,8 +2,10 @@ package controller
 
 import (
 	"bluebell/dao/mysql"
+	"bluebell/logger"
 	"bluebell/logic"
 	"bluebell/models"
+	"bluebell/pkg/metrics"
 	"bluebell/setting"
 	"strconv"
 
@@ -44,24 +46,20 @@ func SignUpHandler(c *gin.Context) {
 	p := new(models.ParamSignUp)
 	if err := c.ShouldBindJSON(p); err != nil {
 		// 请求参数有误，直接返回响应
-		zap.L().Error("SignUp with invalid param", zap.Error(err))
+		logger.FromContext(c.Request.Context()).Error("SignUp with invalid param", zap.Error(err))
 		// 判断err是不是validator.ValidationErrors 类型
 		errs, ok := err.(validator.ValidationErrors)
 		if !ok {
 			ResponseError(c, CodeInvalidParam)
 			return
 		}
-		ResponseErrorWithMsg(c, CodeInvalidParam, removeTopStruct(errs.Translate(trans)))
+		ResponseErrorWithMsg(c, CodeInvalidParam, translateErrors(c, errs))
 		return
 	}
 	// 2. 业务处理
-	if err := logic.SignUp(p); err != nil {
-		zap.L().Error("logic.SignUp failed", zap.Error(err))
-		if errors.Is(err, mysql.ErrorUserExist) {
-			ResponseError(c, CodeUserExist)
-			return
-		}
-		ResponseError(c, CodeServerBusy)
+	if err := logic.SignUp(c.Request.Context(), p); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.SignUp failed", zap.Error(err))
+		HandleError(c, err)
 		return
 	}
 	// 3. 返回响应
@@ -86,25 +84,29 @@ func LoginHandler(c *gin.Context) {
 	p := new(models.ParamLogin)
 	if err := c.ShouldBindJSON(p); err != nil {
 		// 请求参数有误，直接返回响应
-		zap.L().Error("Login with invalid param", zap.Error(err))
+		logger.FromContext(c.Request.Context()).Error("Login with invalid param", zap.Error(err))
 		// 判断err是不是validator.ValidationErrors 类型
 		errs, ok := err.(validator.ValidationErrors)
 		if !ok {
 			ResponseError(c, CodeInvalidParam)
 			return
 		}
-		ResponseErrorWithMsg(c, CodeInvalidParam, removeTopStruct(errs.Translate(trans)))
+		ResponseErrorWithMsg(c, CodeInvalidParam, translateErrors(c, errs))
 		return
 	}
 	// 2.业务逻辑处理
-	user, err := logic.Login(p)
+	user, err := logic.Login(c.Request.Context(), p)
 	if err != nil {
-		zap.L().Error("logic.Login failed", zap.String("username", p.Username), zap.Error(err))
-		if errors.Is(err, mysql.ErrorUserNotExist) {
-			ResponseError(c, CodeUserNotExist)
-			return
+		logger.FromContext(c.Request.Context()).Error("logic.Login failed", zap.String("username", p.Username), zap.Error(err))
+		switch {
+		case errors.Is(err, mysql.ErrorUserNotExist):
+			metrics.LoginFailures.WithLabelValues("user_not_exist").Inc()
+		case errors.Is(err, mysql.ErrorInvalidPassword):
+			metrics.LoginFailures.WithLabelValues("invalid_password").Inc()
+		default:
+			metrics.LoginFailures.WithLabelValues("error").Inc()
 		}
-		ResponseError(c, CodeInvalidPassword)
+		HandleError(c, err)
 		return
 	}
 
@@ -135,17 +137,17 @@ func GetUserPage(c *gin.Context) {
 	postIDStr := c.Query("ID")
 	userID, err := strconv.ParseInt(userIDStr, 10, 64)
 	if err != nil {
-		zap.L().Error("get userID detail with invalid param")
+		logger.FromContext(c.Request.Context()).Error("get userID detail with invalid param")
 		ResponseError(c, CodeInvalidParam)
 		return
 	}
 	postID, err := strconv.ParseInt(postIDStr, 10, 64)
 	if err != nil {
-		zap.L().Error("get postID detail with invalid param")
+		logger.FromContext(c.Request.Context()).Error("get postID detail with invalid param")
 		ResponseError(c, CodeInvalidParam)
 		return
 	}
-	user, post := logic.GetUserPage(userID, postID)
+	user, post := logic.GetUserPage(c.Request.Context(), userID, postID)
 	UserPage := models.UserPage{
 		User: user,
 		Post: post,
@@ -176,7 +178,7 @@ func LoginSMSHandler(c *gin.Context) {
 	// 调用阿里云短信服务SDK
 	//if err:=endpoints.AddEndpointMapping("cn-hangzhou", "Dybaseapi", "dybaseapi.aliyuncs.com");err!=nil {
 	//	ResponseError(c,CodeServerBusy)
-	//	zap.L().Error("endpoints.AddEndpointMapping error",zap.Error(err))
+	//	logger.FromContext(c.Request.Context()).Error("endpoints.AddEndpointMapping error",zap.Error(err))
 	//}
 	// 阿里云账号AccessKey拥有所有API的访问权限，建议您使用RAM用户进行API访问或日常运维。
 	// 强烈建议不要把AccessKey ID和AccessKey Secret保存到工程代码里，否则可能导致AccessKey泄露，威胁您账号下所有资源的安全。
@@ -203,6 +205,6 @@ func LoginSMSHandler(c *gin.Context) {
 	response, err := client.SendSms(request)
 	fmt.Println(response)
 	if err != nil {
-		zap.L().Error("client.SendSms(request) error", zap.Error(err))
+		logger.FromContext(c.Request.Context()).Error("client.SendSms(request) error", zap.Error(err))
 	}
 }
@@ -2,10 +2,12 @@ package controller
 
 import (
 	"bluebell/models"
+	"bluebell/pkg/i18n"
 	"fmt"
 	"reflect"
 	"strings"
 
+	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/locales/en"
 	"github.com/go-playground/locales/zh"
@@ -15,11 +17,17 @@ import (
 	zhTranslations "github.com/go-playground/validator/v10/translations/zh"
 )
 
-// 定义一个全局翻译器T
-var trans ut.Translator
+// translators 每种语言的校验错误翻译器,在InitTrans中创建
+var translators map[string]ut.Translator
 
-// InitTrans 初始化翻译器
-func InitTrans(locale string) (err error) {
+// registerFuncs 各语言注册校验错误翻译的方法,没有的语言使用默认语言的翻译
+var registerFuncs = map[string]func(v *validator.Validate, trans ut.Translator) error{
+	"en": enTranslations.RegisterDefaultTranslations,
+	"zh": zhTranslations.RegisterDefaultTranslations,
+}
+
+// InitTrans 初始化所有支持的语言的翻译器,处理请求时按请求的语言选择
+func InitTrans() (err error) {
 	// 修改gin框架中的Validator引擎属性，实现自定制
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
 
@@ -40,31 +48,37 @@ func InitTrans(locale string) (err error) {
 
 		// 第一个参数是备用（fallback）的语言环境
 		// 后面的参数是应该支持的语言环境（支持多个）
-		// uni := ut.New(zhT, zhT) 也是可以的
-		uni := ut.New(enT, zhT, enT)
-
-		// locale 通常取决于 http 请求头的 'Accept-Language'
-		var ok bool
-		// 也可以使用 uni.FindTranslator(...) 传入多个locale进行查找
-		trans, ok = uni.GetTranslator(locale)
-		if !ok {
-			return fmt.Errorf("uni.GetTranslator(%s) failed", locale)
-		}
+		uni := ut.New(zhT, zhT, enT)
 
-		// 注册翻译器
-		switch locale {
-		case "en":
-			err = enTranslations.RegisterDefaultTranslations(v, trans)
-		case "zh":
-			err = zhTranslations.RegisterDefaultTranslations(v, trans)
-		default:
-			err = enTranslations.RegisterDefaultTranslations(v, trans)
+		res := make(map[string]ut.Translator, len(registerFuncs))
+		for locale, register := range registerFuncs {
+			trans, ok := uni.GetTranslator(locale)
+			if !ok {
+				return fmt.Errorf("uni.GetTranslator(%s) failed", locale)
+			}
+			if err = register(v, trans); err != nil {
+				return err
+			}
+			res[locale] = trans
 		}
-		return
+		translators = res
 	}
 	return
 }
 
+// translator 请求的语言对应的校验错误翻译器
+func translator(c *gin.Context) ut.Translator {
+	if trans, ok := translators[i18n.FromContext(c.Request.Context())]; ok {
+		return trans
+	}
+	return translators[i18n.DefaultLocale]
+}
+
+// translateErrors 翻译校验错误并去除掉错误提示中的结构体标识
+func translateErrors(c *gin.Context, errs validator.ValidationErrors) map[string]string {
+	return removeTopStruct(errs.Translate(translator(c)))
+}
+
 // removeTopStruct 去除提示信息中的结构体名称
 func removeTopStruct(fields map[string]string) map[string]string {
 	res := map[string]string{}
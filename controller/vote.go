@@ -1,8 +1,10 @@
 package controller
 
 import (
+	"bluebell/logger"
 	"bluebell/logic"
 	"bluebell/models"
+	"strconv"
 
 	"go.uber.org/zap"
 
@@ -41,7 +43,7 @@ func PostVoteController(c *gin.Context) {
 			ResponseError(c, CodeInvalidParam)
 			return
 		}
-		errData := removeTopStruct(errs.Translate(trans)) // 翻译并去除掉错误提示中的结构体标识
+		errData := translateErrors(c, errs) // 翻译并去除掉错误提示中的结构体标识
 		ResponseErrorWithMsg(c, CodeInvalidParam, errData)
 		return
 	}
@@ -51,10 +53,25 @@ func PostVoteController(c *gin.Context) {
 		ResponseError(c, CodeNeedLogin)
 		return
 	}
+	// 被禁言或封禁的用户不能投票
+	postID, err := strconv.ParseInt(p.PostID, 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	communityID, err := logic.GetPostCommunityID(c.Request.Context(), postID)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.GetPostCommunityID failed", zap.Int64("post_id", postID), zap.Error(err))
+		HandleError(c, invalidIDAs(err, CodePostNotExist))
+		return
+	}
+	if !checkUserRestriction(c, userID, communityID) {
+		return
+	}
 	// 具体投票的业务逻辑
-	if err := logic.VoteForPost(userID, p); err != nil {
-		zap.L().Error("logic.VoteForPost() failed", zap.Error(err))
-		ResponseError(c, CodeServerBusy)
+	if err := logic.VoteForPost(c.Request.Context(), userID, p); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.VoteForPost() failed", zap.Error(err))
+		HandleError(c, err)
 		return
 	}
 	ResponseSuccess(c, nil)
@@ -0,0 +1,417 @@
+package memory
+
+import (
+	"bluebell/models"
+	"bluebell/pkg/cursor"
+	"context"
+	"database/sql"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Store 进程内的存储,实现 logic 中的 PostRepo、UserRepo、CommunityRepo、VoteStore 和 FeedIndex,
+// 用于不依赖MySQL和redis的测试
+// 与线上不同的是,创建帖子时直接更新帖子列表的索引,不经过outbox事件
+type Store struct {
+	mu sync.Mutex
+
+	posts       map[int64]*models.Post
+	users       map[int64]*models.User
+	communities map[int64]*models.CommunityDetail
+
+	postTime  map[string]float64            // 帖子id -> 发帖时间
+	postScore map[string]float64            // 帖子id -> 分数
+	votes     map[string]map[string]float64 // 帖子id -> 用户id -> 投票
+	pinned    map[int64][]string            // 社区id -> 置顶的帖子id,最近置顶的在前
+	follows   map[int64]map[int64]bool      // 用户id -> 关注的社区id
+	comments  map[string]int64              // 帖子id -> 评论数
+
+	// Now 创建帖子时使用的时间,默认为 time.Now
+	Now func() time.Time
+}
+
+// NewStore 创建一个空的Store
+func NewStore() *Store {
+	return &Store{
+		posts:       make(map[int64]*models.Post),
+		users:       make(map[int64]*models.User),
+		communities: make(map[int64]*models.CommunityDetail),
+		postTime:    make(map[string]float64),
+		postScore:   make(map[string]float64),
+		votes:       make(map[string]map[string]float64),
+		pinned:      make(map[int64][]string),
+		follows:     make(map[int64]map[int64]bool),
+		comments:    make(map[string]int64),
+		Now:         time.Now,
+	}
+}
+
+// ---- 准备测试数据 ----
+
+// AddUser 添加用户
+func (s *Store) AddUser(u *models.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[u.UserID] = u
+}
+
+// AddCommunity 添加社区
+func (s *Store) AddCommunity(c *models.CommunityDetail) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.communities[c.ID] = c
+}
+
+// AddPost 添加帖子并加入索引,CreateTime为空时使用 Now
+func (s *Store) AddPost(p *models.Post) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addPost(p)
+}
+
+// Pin 置顶社区内的帖子
+func (s *Store) Pin(communityID int64, postID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pinned[communityID] = append([]string{strconv.FormatInt(postID, 10)}, s.pinned[communityID]...)
+}
+
+// Follow 关注社区
+func (s *Store) Follow(userID, communityID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.follows[userID] == nil {
+		s.follows[userID] = make(map[int64]bool)
+	}
+	s.follows[userID][communityID] = true
+}
+
+// SetCommentCount 设置帖子的评论数
+func (s *Store) SetCommentCount(postID, n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.comments[strconv.FormatInt(postID, 10)] = n
+}
+
+// Post 查询保存的帖子
+func (s *Store) Post(postID int64) (*models.Post, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.posts[postID]
+	return p, ok
+}
+
+// PostScore 查询帖子的分数
+func (s *Store) PostScore(postID int64) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.postScore[strconv.FormatInt(postID, 10)]
+}
+
+func (s *Store) addPost(p *models.Post) {
+	if p.CreateTime.IsZero() {
+		p.CreateTime = s.Now()
+	}
+	s.posts[p.ID] = p
+	id := strconv.FormatInt(p.ID, 10)
+	s.postTime[id] = float64(p.CreateTime.Unix())
+	s.postScore[id] = float64(p.CreateTime.Unix())
+}
+
+// ---- PostRepo ----
+
+func (s *Store) CreatePostWithOutbox(_ context.Context, p *models.Post) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addPost(p)
+	return nil
+}
+
+func (s *Store) GetPostById(_ context.Context, pid int64) (*models.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.posts[pid]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return p, nil
+}
+
+func (s *Store) GetPostListByIDs(_ context.Context, ids []string) ([]*models.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	posts := make([]*models.Post, 0, len(ids))
+	for _, id := range ids {
+		pid, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			continue
+		}
+		if p, ok := s.posts[pid]; ok {
+			posts = append(posts, p)
+		}
+	}
+	return posts, nil
+}
+
+func (s *Store) GetPostList(_ context.Context, page, size int64) ([]*models.Post, error) {
+	return postsOfPage(s.postsByTime(func(*models.Post) bool { return true }), page, size), nil
+}
+
+func (s *Store) GetPostListByAuthorPage(_ context.Context, authorID, page, size int64) ([]*models.Post, error) {
+	return postsOfPage(s.postsByTime(func(p *models.Post) bool { return p.AuthorID == authorID }), page, size), nil
+}
+
+// postsOfPage 第page页的帖子,page从1开始
+func postsOfPage(posts []*models.Post, page, size int64) []*models.Post {
+	start := (page - 1) * size
+	if start >= int64(len(posts)) {
+		return []*models.Post{}
+	}
+	end := start + size
+	if end > int64(len(posts)) {
+		end = int64(len(posts))
+	}
+	return posts[start:end]
+}
+
+func (s *Store) GetPostListByCursor(_ context.Context, c *cursor.Cursor, size int64) ([]*models.Post, *cursor.Cursor, error) {
+	return s.postsByCursor(func(*models.Post) bool { return true }, c, size)
+}
+
+func (s *Store) GetPostListByAuthor(_ context.Context, authorID int64, c *cursor.Cursor, size int64) ([]*models.Post, *cursor.Cursor, error) {
+	return s.postsByCursor(func(p *models.Post) bool { return p.AuthorID == authorID }, c, size)
+}
+
+// postsByTime 按发帖时间和id倒序排列的帖子,与MySQL中的排序相同
+func (s *Store) postsByTime(match func(*models.Post) bool) []*models.Post {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	posts := make([]*models.Post, 0, len(s.posts))
+	for _, p := range s.posts {
+		if match(p) {
+			posts = append(posts, p)
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		if !posts[i].CreateTime.Equal(posts[j].CreateTime) {
+			return posts[i].CreateTime.After(posts[j].CreateTime)
+		}
+		return posts[i].ID > posts[j].ID
+	})
+	return posts
+}
+
+func (s *Store) postsByCursor(match func(*models.Post) bool, c *cursor.Cursor, size int64) ([]*models.Post, *cursor.Cursor, error) {
+	res := make([]*models.Post, 0, size)
+	for _, p := range s.postsByTime(match) {
+		t := p.CreateTime.Unix()
+		if c != nil && (t > int64(c.Score) || (t == int64(c.Score) && p.ID >= c.ID)) {
+			continue
+		}
+		res = append(res, p)
+		if int64(len(res)) == size {
+			last := res[len(res)-1]
+			return res, &cursor.Cursor{Score: float64(last.CreateTime.Unix()), ID: last.ID}, nil
+		}
+	}
+	return res, nil, nil
+}
+
+// ---- UserRepo、CommunityRepo ----
+
+func (s *Store) GetUsersByIDs(_ context.Context, ids []int64) ([]*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users := make([]*models.User, 0, len(ids))
+	for _, id := range ids {
+		if u, ok := s.users[id]; ok {
+			users = append(users, u)
+		}
+	}
+	return users, nil
+}
+
+func (s *Store) GetCommunitiesByIDs(_ context.Context, ids []int64) ([]*models.CommunityDetail, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	communities := make([]*models.CommunityDetail, 0, len(ids))
+	for _, id := range ids {
+		if c, ok := s.communities[id]; ok {
+			communities = append(communities, c)
+		}
+	}
+	return communities, nil
+}
+
+// ---- VoteStore ----
+
+func (s *Store) GetPostCreateTime(_ context.Context, postID string) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.postTime[postID], nil
+}
+
+func (s *Store) GetUserVote(_ context.Context, userID, postID string) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.votes[postID][userID], nil
+}
+
+func (s *Store) SaveVote(_ context.Context, userID, postID string, value, scoreDelta float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.postScore[postID] += scoreDelta
+	if s.votes[postID] == nil {
+		s.votes[postID] = make(map[string]float64)
+	}
+	if value == 0 {
+		delete(s.votes[postID], userID)
+	} else {
+		s.votes[postID][userID] = value
+	}
+	return nil
+}
+
+func (s *Store) GetPostVoteData(_ context.Context, ids []string) ([]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		var n int64
+		for _, v := range s.votes[id] {
+			if v == 1 {
+				n++
+			}
+		}
+		data = append(data, n)
+	}
+	return data, nil
+}
+
+func (s *Store) GetUserVotes(_ context.Context, userID int64, ids []string) ([]int8, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	uid := strconv.FormatInt(userID, 10)
+	data := make([]int8, 0, len(ids))
+	for _, id := range ids {
+		data = append(data, int8(s.votes[id][uid]))
+	}
+	return data, nil
+}
+
+// ---- FeedIndex ----
+
+func (s *Store) GetPostIDsInOrder(_ context.Context, p *models.ParamPostList) ([]string, error) {
+	return s.idsInOrder(s.rank(p.Order, s.all), p), nil
+}
+
+func (s *Store) GetPostIDsByCursor(_ context.Context, p *models.ParamPostList, c *cursor.Cursor) ([]string, *cursor.Cursor, error) {
+	ids, next := s.idsByCursor(s.rank(p.Order, s.all), c, p.Size)
+	return ids, next, nil
+}
+
+func (s *Store) GetCommunityPostIDsInOrder(_ context.Context, p *models.ParamPostList) ([]string, error) {
+	return s.idsInOrder(s.rank(p.Order, s.inCommunities(p.CommunityID)), p), nil
+}
+
+func (s *Store) GetCommunityPostIDsByCursor(_ context.Context, p *models.ParamPostList, c *cursor.Cursor) ([]string, *cursor.Cursor, error) {
+	ids, next := s.idsByCursor(s.rank(p.Order, s.inCommunities(p.CommunityID)), c, p.Size)
+	return ids, next, nil
+}
+
+func (s *Store) GetFollowedPostIDsInOrder(_ context.Context, userID int64, p *models.ParamPostList) ([]string, error) {
+	return s.idsInOrder(s.rank(p.Order, s.followedBy(userID)), p), nil
+}
+
+func (s *Store) GetFollowedPostIDsByCursor(_ context.Context, userID int64, p *models.ParamPostList, c *cursor.Cursor) ([]string, *cursor.Cursor, error) {
+	ids, next := s.idsByCursor(s.rank(p.Order, s.followedBy(userID)), c, p.Size)
+	return ids, next, nil
+}
+
+func (s *Store) GetPinnedPostIDs(_ context.Context, communityID int64) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.pinned[communityID]...), nil
+}
+
+func (s *Store) GetPostCommentCounts(_ context.Context, ids []string) ([]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		data = append(data, s.comments[id])
+	}
+	return data, nil
+}
+
+// ranked 索引中的一个帖子
+type ranked struct {
+	id    string
+	score float64
+}
+
+func (s *Store) all(*models.Post) bool { return true }
+
+func (s *Store) inCommunities(communityID int64) func(*models.Post) bool {
+	return func(p *models.Post) bool { return p.CommunityID == communityID }
+}
+
+// followedBy 调用时已经持有锁
+func (s *Store) followedBy(userID int64) func(*models.Post) bool {
+	return func(p *models.Post) bool { return s.follows[userID][p.CommunityID] }
+}
+
+// rank 按时间或分数倒序排列的帖子,分数相同时按id的字典序倒序,与redis的ZREVRANGEBYSCORE相同
+func (s *Store) rank(order string, match func(*models.Post) bool) []ranked {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	scores := s.postTime
+	if order == models.OrderScore {
+		scores = s.postScore
+	}
+	res := make([]ranked, 0, len(s.posts))
+	for _, p := range s.posts {
+		if !match(p) {
+			continue
+		}
+		id := strconv.FormatInt(p.ID, 10)
+		res = append(res, ranked{id: id, score: scores[id]})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].score != res[j].score {
+			return res[i].score > res[j].score
+		}
+		return res[i].id > res[j].id
+	})
+	return res
+}
+
+func (s *Store) idsInOrder(list []ranked, p *models.ParamPostList) []string {
+	start := (p.Page - 1) * p.Size
+	ids := make([]string, 0, p.Size)
+	for i := start; i < int64(len(list)) && i < start+p.Size; i++ {
+		ids = append(ids, list[i].id)
+	}
+	return ids
+}
+
+func (s *Store) idsByCursor(list []ranked, c *cursor.Cursor, size int64) ([]string, *cursor.Cursor) {
+	ids := make([]string, 0, size)
+	var last string
+	if c != nil {
+		last = strconv.FormatInt(c.ID, 10)
+	}
+	for _, r := range list {
+		if c != nil && (r.score > c.Score || (r.score == c.Score && r.id >= last)) {
+			continue
+		}
+		ids = append(ids, r.id)
+		if int64(len(ids)) == size {
+			id, _ := strconv.ParseInt(r.id, 10, 64)
+			return ids, &cursor.Cursor{Score: r.score, ID: id}
+		}
+	}
+	return ids, nil
+}
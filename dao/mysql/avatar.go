@@ -1,10 +1,10 @@
 package mysql
 
-// UploadAvatar 保存头像路径到数据库
-func UploadAvatar(id string, fileName string) {
-	sqlstr := `insert into user (user_id,avatar)values (?,?)`
-	_, err := db.Exec(sqlstr, id, fileName)
-	if err != nil {
-		return
-	}
+import "context"
+
+// UploadAvatar 保存头像路径到用户表
+func UploadAvatar(ctx context.Context, id int64, fileName string) error {
+	sqlStr := `update user set avatar = ? where user_id = ?`
+	_, err := db.ExecContext(ctx, sqlStr, fileName, id)
+	return err
 }
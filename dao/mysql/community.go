@@ -1,17 +1,19 @@
 package mysql
 
 import (
+	"bluebell/logger"
 	"bluebell/models"
+	"context"
 	"database/sql"
 
-	"go.uber.org/zap"
+	"github.com/jmoiron/sqlx"
 )
 
-func GetCommunityList() (communityList []*models.Community, err error) {
-	sqlStr := "select community_id, community_name from community"
-	if err := db.Select(&communityList, sqlStr); err != nil {
+func GetCommunityList(ctx context.Context) (communityList []*models.Community, err error) {
+	sqlStr := "select community_id, community_name, slug from community"
+	if err = db.SelectContext(ctx, &communityList, sqlStr); err != nil {
 		if err == sql.ErrNoRows {
-			zap.L().Warn("there is no community in db")
+			logger.FromContext(ctx).Warn("there is no community in db")
 			err = nil
 		}
 	}
@@ -19,14 +21,14 @@ func GetCommunityList() (communityList []*models.Community, err error) {
 }
 
 // GetCommunityDetailByID 根据ID查询社区详情
-func GetCommunityDetailByID(id int64) (community *models.CommunityDetail, err error) {
+func GetCommunityDetailByID(ctx context.Context, id int64) (community *models.CommunityDetail, err error) {
 	community = new(models.CommunityDetail)
 	sqlStr := `select 
-			community_id, community_name, introduction, create_time
+			community_id, community_name, slug, introduction, create_time
 			from community 
 			where community_id = ?
 	`
-	if err := db.Get(community, sqlStr, id); err != nil {
+	if err = db.GetContext(ctx, community, sqlStr, id); err != nil {
 		if err == sql.ErrNoRows {
 			err = ErrorInvalidID
 		}
@@ -34,13 +36,85 @@ func GetCommunityDetailByID(id int64) (community *models.CommunityDetail, err er
 	return community, err
 }
 
-func GetCommunityIDByName(communityName string) int64 {
-	var id int64
+// GetCommunityIDByName 根据社区名称查询社区ID
+func GetCommunityIDByName(ctx context.Context, communityName string) (id int64, err error) {
 	sqlStr := "select community_id from community where community_name = ?"
-	if err := db.Get(id, sqlStr, communityName); err != nil {
+	if err = db.GetContext(ctx, &id, sqlStr, communityName); err != nil {
 		if err == sql.ErrNoRows {
 			err = ErrorInvalidID
 		}
 	}
-	return id
+	return
+}
+
+// GetCommunityDetailBySlug 根据slug查询社区详情
+func GetCommunityDetailBySlug(ctx context.Context, slug string) (community *models.CommunityDetail, err error) {
+	community = new(models.CommunityDetail)
+	sqlStr := `select 
+			community_id, community_name, slug, introduction, create_time
+			from community 
+			where slug = ?
+	`
+	if err := db.GetContext(ctx, community, sqlStr, slug); err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrorInvalidID
+		}
+		return nil, err
+	}
+	return community, nil
+}
+
+// GetCommunitiesByIDs 根据id列表批量查询社区详情,不存在的id会被忽略
+func GetCommunitiesByIDs(ctx context.Context, ids []int64) (communities []*models.CommunityDetail, err error) {
+	communities = make([]*models.CommunityDetail, 0, len(ids))
+	if len(ids) == 0 {
+		return
+	}
+	sqlStr := `select 
+			community_id, community_name, slug, introduction, create_time
+			from community 
+			where community_id in (?)
+	`
+	query, args, err := sqlx.In(sqlStr, ids)
+	if err != nil {
+		return nil, err
+	}
+	query = db.Rebind(query)
+	err = db.SelectContext(ctx, &communities, query, args...)
+	return
+}
+
+// CheckCommunityExist 检查指定名称的社区是否存在
+func CheckCommunityExist(ctx context.Context, name string) error {
+	sqlStr := `select count(community_id) from community where community_name = ?`
+	var count int64
+	if err := db.GetContext(ctx, &count, sqlStr, name); err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrorCommunityExist
+	}
+	return nil
+}
+
+// CommunitySlugExists 查询slug是否已经被使用
+func CommunitySlugExists(ctx context.Context, slug string) (bool, error) {
+	sqlStr := `select count(community_id) from community where slug = ?`
+	var count int64
+	err := db.GetContext(ctx, &count, sqlStr, slug)
+	return count > 0, err
+}
+
+// NextCommunityID 新社区使用的id,社区id是从1开始的连续整数
+func NextCommunityID(ctx context.Context) (id int64, err error) {
+	sqlStr := `select coalesce(max(community_id), 0) + 1 from community`
+	err = db.GetContext(ctx, &id, sqlStr)
+	return
+}
+
+// InsertCommunity 插入一条新的社区记录,并发创建时由唯一索引保证id、名称和slug不重复
+func InsertCommunity(ctx context.Context, c *models.CommunityDetail) (err error) {
+	sqlStr := `insert into community(community_id, community_name, slug, introduction) values(?,?,?,?)`
+	_, err = db.ExecContext(ctx, sqlStr, c.ID, c.Name, c.Slug, c.Introduction)
+	return
 }
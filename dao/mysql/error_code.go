@@ -7,4 +7,5 @@ var (
 	ErrorUserNotExist    = errors.New("用户不存在")
 	ErrorInvalidPassword = errors.New("用户名或密码错误")
 	ErrorInvalidID       = errors.New("无效的ID")
+	ErrorCommunityExist  = errors.New("社区已存在")
 )
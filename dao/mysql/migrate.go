@@ -0,0 +1,256 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 数据库迁移
+// 迁移文件放在 migrations 目录中,编译时嵌入到程序里,
+// 命名为 版本号_名称.up.sql 和 版本号_名称.down.sql,如 0001_create_user.up.sql
+// 已执行的版本记录在 schema_migrations 表中,up按版本号从小到大执行未执行过的迁移,
+// down按版本号从大到小回滚最近执行的迁移
+// 执行期间持有MySQL的命名锁,多个实例同时启动时只有一个在执行迁移
+// 0001到0003和以前的 bluebell_*.sql 建的表完全一致,使用 create table if not exists,
+// 用sql文件建好的数据库执行时跳过建表,之后增加的列和索引由后面的 alter table 迁移补上
+// 已经发布的迁移不能再修改,表结构的变化都要放在新的迁移中
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+const (
+	migrateLockName    = "bluebell:migrate"
+	migrateLockTimeout = 30 // 等待其他实例执行迁移的秒数
+)
+
+var ErrorMigrateLocked = errors.New("其他实例正在执行数据库迁移")
+
+const createMigrationsTable = `create table if not exists schema_migrations
+(
+    version      bigint                              not null primary key,
+    name         varchar(128)                        not null,
+    applied_time timestamp default CURRENT_TIMESTAMP not null
+)
+    collate = utf8mb4_general_ci`
+
+// Migration 一个版本的迁移
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Migrations 返回嵌入在程序中的迁移,按版本号排序
+func Migrations() ([]*Migration, error) {
+	sub, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return LoadMigrations(sub)
+}
+
+// LoadMigrations 读取目录中的迁移文件,按版本号排序
+func LoadMigrations(fsys fs.FS) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]*Migration)
+	for _, e := range entries {
+		if e.IsDir() || path.Ext(e.Name()) != ".sql" {
+			continue
+		}
+		version, name, direction, err := parseMigrationName(e.Name())
+		if err != nil {
+			return nil, err
+		}
+		data, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration version %d used by both %q and %q", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// parseMigrationName 解析 0001_init.up.sql 形式的文件名
+func parseMigrationName(filename string) (version int64, name, direction string, err error) {
+	base := strings.TrimSuffix(filename, ".sql")
+	dot := strings.LastIndexByte(base, '.')
+	if dot < 0 {
+		return 0, "", "", fmt.Errorf("invalid migration file name %q", filename)
+	}
+	base, direction = base[:dot], base[dot+1:]
+	if direction != "up" && direction != "down" {
+		return 0, "", "", fmt.Errorf("invalid migration file name %q", filename)
+	}
+	underscore := strings.IndexByte(base, '_')
+	if underscore < 0 {
+		return 0, "", "", fmt.Errorf("invalid migration file name %q", filename)
+	}
+	version, err = strconv.ParseInt(base[:underscore], 10, 64)
+	if err != nil {
+		return 0, "", "", fmt.Errorf("invalid migration version in %q", filename)
+	}
+	return version, base[underscore+1:], direction, nil
+}
+
+// appliedVersions 查询已经执行过的迁移版本
+func appliedVersions(ctx context.Context) (map[int64]bool, error) {
+	if _, err := db.ExecContext(ctx, createMigrationsTable); err != nil {
+		return nil, err
+	}
+	var versions []int64
+	if err := db.SelectContext(ctx, &versions, `select version from schema_migrations`); err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]bool, len(versions))
+	for _, v := range versions {
+		applied[v] = true
+	}
+	return applied, nil
+}
+
+// withMigrateLock 持有迁移锁执行fn,等待超时返回 ErrorMigrateLocked
+// GET_LOCK 的锁属于连接,所以加锁和释放要使用同一个连接
+func withMigrateLock(ctx context.Context, fn func() error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, `select get_lock(?, ?)`, migrateLockName, migrateLockTimeout).Scan(&locked); err != nil {
+		return err
+	}
+	if locked.Int64 != 1 {
+		return ErrorMigrateLocked
+	}
+	defer conn.ExecContext(ctx, `select release_lock(?)`, migrateLockName)
+	return fn()
+}
+
+// MigrateUp 执行所有未执行过的迁移,返回执行的迁移
+func MigrateUp(ctx context.Context, migrations []*Migration) (done []*Migration, err error) {
+	err = withMigrateLock(ctx, func() error {
+		done, err = migrateUp(ctx, migrations)
+		return err
+	})
+	return done, err
+}
+
+// MigrateDown 回滚最近执行的steps个迁移,返回回滚的迁移
+func MigrateDown(ctx context.Context, migrations []*Migration, steps int) (done []*Migration, err error) {
+	err = withMigrateLock(ctx, func() error {
+		done, err = migrateDown(ctx, migrations, steps)
+		return err
+	})
+	return done, err
+}
+
+func migrateUp(ctx context.Context, migrations []*Migration) (done []*Migration, err error) {
+	applied, err := appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range migrations {
+		if applied[m.Version] {
+			continue
+		}
+		if err := execStatements(ctx, m.Up); err != nil {
+			return done, fmt.Errorf("migrate up %d_%s: %w", m.Version, m.Name, err)
+		}
+		sqlStr := `insert into schema_migrations(version, name) values(?,?)`
+		if _, err := db.ExecContext(ctx, sqlStr, m.Version, m.Name); err != nil {
+			return done, err
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+func migrateDown(ctx context.Context, migrations []*Migration, steps int) (done []*Migration, err error) {
+	applied, err := appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		m := migrations[i]
+		if !applied[m.Version] {
+			continue
+		}
+		if m.Down == "" {
+			return done, fmt.Errorf("migration %d_%s has no down file", m.Version, m.Name)
+		}
+		if err := execStatements(ctx, m.Down); err != nil {
+			return done, fmt.Errorf("migrate down %d_%s: %w", m.Version, m.Name, err)
+		}
+		if _, err := db.ExecContext(ctx, `delete from schema_migrations where version = ?`, m.Version); err != nil {
+			return done, err
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// execStatements 依次执行以分号结尾的多条语句,DSN没有开启multiStatements
+func execStatements(ctx context.Context, script string) error {
+	for _, stmt := range splitStatements(script) {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitStatements 按行尾的分号拆分语句,忽略空行和以 -- 开头的注释行
+func splitStatements(script string) []string {
+	var (
+		stmts []string
+		buf   strings.Builder
+	)
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+		if strings.HasSuffix(trimmed, ";") {
+			stmts = append(stmts, strings.TrimSuffix(strings.TrimSpace(buf.String()), ";"))
+			buf.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(buf.String()); rest != "" {
+		stmts = append(stmts, rest)
+	}
+	return stmts
+}
@@ -0,0 +1,118 @@
+package mysql
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no embedded migrations")
+	}
+	for i, m := range migrations {
+		if m.Version != int64(i+1) {
+			t.Errorf("migration %d_%s: versions should be continuous, want %d", m.Version, m.Name, i+1)
+		}
+		if m.Down == "" {
+			t.Errorf("migration %d_%s has no down file", m.Version, m.Name)
+		}
+		for _, stmt := range splitStatements(m.Up) {
+			if strings.HasSuffix(stmt, ";") {
+				t.Errorf("migration %d_%s: statement not split: %q", m.Version, m.Name, stmt)
+			}
+		}
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_index.up.sql":   {Data: []byte("create index idx_a on t (a);")},
+		"0001_create_t.up.sql":    {Data: []byte("create table t (a int);")},
+		"0001_create_t.down.sql":  {Data: []byte("drop table t;")},
+		"README.md":               {Data: []byte("ignored")},
+		"0002_add_index.down.sql": {Data: []byte("drop index idx_a on t;")},
+	}
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 || migrations[0].Name != "create_t" || migrations[1].Name != "add_index" {
+		t.Fatalf("unexpected migrations: %+v", migrations)
+	}
+
+	bad := []fstest.MapFS{
+		{"0001_create_t.down.sql": {Data: []byte("drop table t;")}},                                  // 没有up文件
+		{"create_t.up.sql": {Data: []byte("create table t (a int);")}},                               // 没有版本号
+		{"0001_create_t.sql": {Data: []byte("create table t (a int);")}},                             // 没有方向
+		{"0001_a.up.sql": {Data: []byte("select 1;")}, "0001_b.up.sql": {Data: []byte("select 2;")}}, // 版本号重复
+	}
+	for _, fsys := range bad {
+		if _, err := LoadMigrations(fsys); err == nil {
+			t.Errorf("LoadMigrations(%v) should fail", fsys)
+		}
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- 注释
+create table t
+(
+    a int
+);
+
+insert into t values (1);
+select 1`
+	got := splitStatements(script)
+	want := []string{"create table t\n(\n    a int\n)", "insert into t values (1)", "select 1"}
+	if len(got) != len(want) {
+		t.Fatalf("splitStatements() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("statement %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+// TestBaselineMigrations 以前用sql文件建的表会跳过 create table if not exists,
+// 之后增加的列和索引只能放在单独的 alter table 迁移中
+func TestBaselineMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	later := map[string]string{
+		"role":                "add_user_role",
+		"slug":                "add_community_slug",
+		"idx_title_content":   "add_post_fulltext_index",
+		"avatar varchar(256)": "widen_user_avatar",
+	}
+	names := make(map[string]bool)
+	for _, m := range migrations {
+		names[m.Name] = true
+		if m.Version > 3 {
+			continue
+		}
+		for _, stmt := range splitStatements(m.Up) {
+			if !strings.HasPrefix(stmt, "create table") {
+				continue
+			}
+			stmt = strings.Join(strings.Fields(stmt), " ")
+			for column, migration := range later {
+				if strings.Contains(stmt, column) {
+					t.Errorf("migration %d_%s: %s belongs in the %s migration", m.Version, m.Name, column, migration)
+				}
+			}
+		}
+	}
+	for _, migration := range later {
+		if !names[migration] {
+			t.Errorf("migration %s is missing", migration)
+		}
+	}
+}
@@ -1,4 +1,5 @@
-create table user
+-- 和原来的 bluebell_user.sql 一致,之后增加的列见 0009、0012
+create table if not exists user
 (
     id          bigint auto_increment
         primary key,
@@ -6,9 +7,9 @@ create table user
     username    varchar(64)                         not null,
     password    varchar(64)                         not null,
     email       varchar(64)                         null,
-    phone       varchar(64)          ,
+    phone       varchar(64)                         null,
     gender      tinyint   default 0                 not null,
-    avatar      varchar(64)    ,
+    avatar      varchar(64)                         null,
     create_time timestamp default CURRENT_TIMESTAMP null,
     update_time timestamp default CURRENT_TIMESTAMP null on update CURRENT_TIMESTAMP,
     constraint idx_user_id
@@ -17,6 +18,3 @@ create table user
         unique (username)
 )
     collate = utf8mb4_general_ci;
-
-INSERT INTO bluebell.user (id, user_id, username, password, email, gender,avatar, create_time, update_time) VALUES (1, 28018727488323585, 'q1mi', '313233343536639a9119599647d841b1bef6ce5ea293', null, 0, null,'2020-07-12 07:01:03', '2020-07-12 07:01:03');
-INSERT INTO bluebell.user (id, user_id, username, password, email, gender, avatar,create_time, update_time) VALUES (2, 4183532125556736, '七米', '313233639a9119599647d841b1bef6ce5ea293', null, 0,null, '2020-07-12 13:03:51', '2020-07-12 13:03:51');
@@ -0,0 +1,19 @@
+-- 和原来的 bluebell_post.sql 一致,全文索引见 0011
+create table if not exists post
+(
+    id           bigint auto_increment
+        primary key,
+    post_id      bigint                              not null comment '帖子id',
+    title        varchar(128)                        not null comment '标题',
+    content      varchar(8192)                       not null comment '内容',
+    author_id    bigint                              not null comment '作者的用户id',
+    community_id bigint                              not null comment '所属社区',
+    status       tinyint   default 1                 not null comment '帖子状态',
+    create_time  timestamp default CURRENT_TIMESTAMP null comment '创建时间',
+    update_time  timestamp default CURRENT_TIMESTAMP null on update CURRENT_TIMESTAMP comment '更新时间',
+    constraint idx_post_id
+        unique (post_id),
+    index idx_author_id (author_id),
+    index idx_community_id (community_id)
+)
+    collate = utf8mb4_general_ci;
@@ -0,0 +1,2 @@
+drop table if exists community_ban;
+drop table if exists community_moderator;
@@ -0,0 +1,30 @@
+create table if not exists community_moderator
+(
+    id           bigint auto_increment
+        primary key,
+    community_id bigint                              not null comment '社区id',
+    user_id      bigint                              not null comment '版主的用户id',
+    role         tinyint   default 1                 not null comment '1:版主 2:社区所有者',
+    create_time  timestamp default CURRENT_TIMESTAMP null comment '创建时间',
+    update_time  timestamp default CURRENT_TIMESTAMP null on update CURRENT_TIMESTAMP comment '更新时间',
+    constraint idx_community_user
+        unique (community_id, user_id),
+    index idx_user_id (user_id)
+)
+    collate = utf8mb4_general_ci;
+
+create table if not exists community_ban
+(
+    id           bigint auto_increment
+        primary key,
+    community_id bigint                              not null comment '社区id',
+    user_id      bigint                              not null comment '被禁言的用户id',
+    operator_id  bigint                              not null comment '操作人的用户id',
+    reason       varchar(256) default ''             not null comment '禁言原因',
+    expire_time  timestamp                           null comment '到期时间,为空表示永久',
+    create_time  timestamp default CURRENT_TIMESTAMP null comment '创建时间',
+    update_time  timestamp default CURRENT_TIMESTAMP null on update CURRENT_TIMESTAMP comment '更新时间',
+    constraint idx_community_user
+        unique (community_id, user_id)
+)
+    collate = utf8mb4_general_ci;
@@ -0,0 +1,14 @@
+create table if not exists user_suspension
+(
+    id          bigint auto_increment
+        primary key,
+    user_id     bigint                              not null comment '被封禁的用户id',
+    operator_id bigint                              not null comment '操作人的用户id',
+    reason      varchar(256) default ''             not null comment '封禁原因',
+    expire_time timestamp                           null comment '到期时间,为空表示永久',
+    create_time timestamp default CURRENT_TIMESTAMP null comment '创建时间',
+    update_time timestamp default CURRENT_TIMESTAMP null on update CURRENT_TIMESTAMP comment '更新时间',
+    constraint idx_user_id
+        unique (user_id)
+)
+    collate = utf8mb4_general_ci;
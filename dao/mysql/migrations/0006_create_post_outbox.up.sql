@@ -0,0 +1,15 @@
+create table if not exists post_outbox
+(
+    id              bigint auto_increment
+        primary key,
+    event_type      varchar(32)                         not null comment '事件类型:post_created/post_updated/post_deleted',
+    post_id         bigint                              not null comment '帖子id',
+    community_id    bigint                              not null comment '帖子所属社区',
+    attempts        int       default 0                 not null comment '已经尝试处理的次数',
+    last_error      varchar(512) default ''             not null comment '最后一次处理失败的原因',
+    next_retry_time timestamp                           null comment '下次重试的时间,为空表示立即处理',
+    create_time     timestamp default CURRENT_TIMESTAMP null comment '创建时间',
+    processed_time  timestamp                           null comment '处理完成的时间,为空表示待处理',
+    index idx_processed_time (processed_time, next_retry_time)
+)
+    collate = utf8mb4_general_ci;
@@ -0,0 +1,13 @@
+create table if not exists post_vote_archive
+(
+    id          bigint auto_increment
+        primary key,
+    post_id     bigint                              not null comment '帖子id',
+    up_votes    bigint    default 0                 not null comment '赞成票数',
+    down_votes  bigint    default 0                 not null comment '反对票数',
+    create_time timestamp default CURRENT_TIMESTAMP null comment '创建时间',
+    update_time timestamp default CURRENT_TIMESTAMP null on update CURRENT_TIMESTAMP comment '更新时间',
+    constraint idx_post_id
+        unique (post_id)
+)
+    collate = utf8mb4_general_ci;
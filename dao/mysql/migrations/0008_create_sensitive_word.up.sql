@@ -0,0 +1,11 @@
+create table if not exists sensitive_word
+(
+    id          bigint auto_increment
+        primary key,
+    category    varchar(64)                         not null comment '分类,对应处理方式配置中的分类名',
+    word        varchar(128) collate utf8mb4_bin    not null comment '敏感词,区分大小写和全半角,由过滤器统一转换',
+    create_time timestamp default CURRENT_TIMESTAMP null comment '创建时间',
+    constraint idx_category_word
+        unique (category, word)
+)
+    collate = utf8mb4_general_ci;
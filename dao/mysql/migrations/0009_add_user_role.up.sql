@@ -0,0 +1,2 @@
+alter table user
+    add column role varchar(16) default 'user' not null comment '用户角色,root为管理员' after avatar;
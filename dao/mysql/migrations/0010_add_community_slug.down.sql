@@ -0,0 +1,3 @@
+alter table community
+    drop index idx_community_slug,
+    drop column slug;
@@ -0,0 +1,25 @@
+-- 先允许为空,给已有的社区生成slug后再加上非空和唯一约束
+alter table community
+    add column slug varchar(128) null comment 'URL中使用的社区标识' after community_name;
+
+-- 规则和 pkg/slug 一致: 名称中的ASCII字母和数字转换成小写,其余连续的字符替换成一个'-'
+update community
+set slug = lower(trim(both '-' from regexp_replace(community_name, '[^A-Za-z0-9]+', '-')));
+
+-- 名称中没有字母和数字(如纯中文)时使用 c-社区id
+update community
+set slug = concat('c-', community_id)
+where slug = '';
+
+-- slug重复时,除community_id最小的社区外都加上 -社区id 的后缀
+update community c
+    join (select slug, min(community_id) as keep_id
+          from community
+          group by slug
+          having count(*) > 1) d on c.slug = d.slug and c.community_id <> d.keep_id
+set c.slug = concat(c.slug, '-', c.community_id);
+
+alter table community
+    modify slug varchar(128) not null comment 'URL中使用的社区标识',
+    add constraint idx_community_slug
+        unique (slug);
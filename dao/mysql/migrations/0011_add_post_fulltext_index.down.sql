@@ -0,0 +1,2 @@
+alter table post
+    drop index idx_title_content;
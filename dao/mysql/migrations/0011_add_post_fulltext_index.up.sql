@@ -0,0 +1,3 @@
+-- 全文索引,使用ngram分词器支持中文搜索
+alter table post
+    add fulltext index idx_title_content (title, content) with parser ngram;
@@ -0,0 +1,2 @@
+alter table user
+    modify avatar varchar(64) null;
@@ -0,0 +1,2 @@
+alter table user
+    modify avatar varchar(256) null comment '头像文件的路径';
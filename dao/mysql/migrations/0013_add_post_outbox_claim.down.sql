@@ -0,0 +1,4 @@
+alter table post_outbox
+    drop index idx_claimed_by,
+    drop column claim_expire_time,
+    drop column claimed_by;
@@ -0,0 +1,5 @@
+-- 多个实例的后台任务先领取事件再处理,每个事件同一时间只由一个实例处理
+alter table post_outbox
+    add column claimed_by        varchar(32) null comment '领取事件的处理方,为空表示没有被领取' after processed_time,
+    add column claim_expire_time timestamp   null comment '领取的过期时间,过期后其他处理方可以重新领取' after claimed_by,
+    add index idx_claimed_by (claimed_by);
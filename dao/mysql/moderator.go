@@ -0,0 +1,114 @@
+package mysql
+
+import (
+	"bluebell/models"
+	"context"
+	"database/sql"
+)
+
+// AddCommunityModerator 添加社区版主,已存在时更新角色
+func AddCommunityModerator(ctx context.Context, m *models.CommunityModerator) (err error) {
+	sqlStr := `insert into community_moderator(community_id, user_id, role)
+	values (?, ?, ?)
+	on duplicate key update role = values(role)
+	`
+	_, err = db.ExecContext(ctx, sqlStr, m.CommunityID, m.UserID, m.Role)
+	return
+}
+
+// RemoveCommunityModerator 移除社区版主,found为false表示用户不是该社区的版主
+func RemoveCommunityModerator(ctx context.Context, communityID, userID int64) (found bool, err error) {
+	sqlStr := `delete from community_moderator where community_id = ? and user_id = ?`
+	res, err := db.ExecContext(ctx, sqlStr, communityID, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// GetCommunityModerators 查询社区的所有版主
+func GetCommunityModerators(ctx context.Context, communityID int64) (moderators []*models.CommunityModerator, err error) {
+	sqlStr := `select community_id, user_id, role, create_time
+	from community_moderator
+	where community_id = ?
+	order by role desc, create_time
+	`
+	moderators = make([]*models.CommunityModerator, 0)
+	err = db.SelectContext(ctx, &moderators, sqlStr, communityID)
+	return
+}
+
+// GetModeratorRole 查询用户在社区中的版主角色,不是版主时返回 models.ModeratorRoleNone
+func GetModeratorRole(ctx context.Context, communityID, userID int64) (role int8, err error) {
+	sqlStr := `select role from community_moderator where community_id = ? and user_id = ?`
+	err = db.GetContext(ctx, &role, sqlStr, communityID, userID)
+	if err == sql.ErrNoRows {
+		return models.ModeratorRoleNone, nil
+	}
+	return
+}
+
+// BanUserInCommunity 在社区内禁言用户,重复禁言时覆盖原有记录
+func BanUserInCommunity(ctx context.Context, b *models.CommunityBan) (err error) {
+	sqlStr := `insert into community_ban(community_id, user_id, operator_id, reason, expire_time)
+	values (?, ?, ?, ?, ?)
+	on duplicate key update operator_id = values(operator_id),
+	reason = values(reason), expire_time = values(expire_time)
+	`
+	_, err = db.ExecContext(ctx, sqlStr, b.CommunityID, b.UserID, b.OperatorID, b.Reason, b.ExpireTime)
+	return
+}
+
+// UnbanUserInCommunity 解除社区内的禁言
+func UnbanUserInCommunity(ctx context.Context, communityID, userID int64) (err error) {
+	sqlStr := `delete from community_ban where community_id = ? and user_id = ?`
+	_, err = db.ExecContext(ctx, sqlStr, communityID, userID)
+	return
+}
+
+// GetCommunityBan 查询用户在社区内的禁言记录,没有记录时返回nil
+func GetCommunityBan(ctx context.Context, communityID, userID int64) (ban *models.CommunityBan, err error) {
+	ban = new(models.CommunityBan)
+	sqlStr := `select community_id, user_id, operator_id, reason, expire_time
+	from community_ban
+	where community_id = ? and user_id = ?
+	`
+	err = db.GetContext(ctx, ban, sqlStr, communityID, userID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return
+}
+
+// SuspendUser 全站封禁用户,重复封禁时覆盖原有记录
+func SuspendUser(ctx context.Context, s *models.UserSuspension) (err error) {
+	sqlStr := `insert into user_suspension(user_id, operator_id, reason, expire_time)
+	values (?, ?, ?, ?)
+	on duplicate key update operator_id = values(operator_id),
+	reason = values(reason), expire_time = values(expire_time)
+	`
+	_, err = db.ExecContext(ctx, sqlStr, s.UserID, s.OperatorID, s.Reason, s.ExpireTime)
+	return
+}
+
+// UnsuspendUser 解除全站封禁
+func UnsuspendUser(ctx context.Context, userID int64) (err error) {
+	sqlStr := `delete from user_suspension where user_id = ?`
+	_, err = db.ExecContext(ctx, sqlStr, userID)
+	return
+}
+
+// GetUserSuspension 查询用户的全站封禁记录,没有记录时返回nil
+func GetUserSuspension(ctx context.Context, userID int64) (s *models.UserSuspension, err error) {
+	s = new(models.UserSuspension)
+	sqlStr := `select user_id, operator_id, reason, expire_time
+	from user_suspension
+	where user_id = ?
+	`
+	err = db.GetContext(ctx, s, sqlStr, userID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return
+}
@@ -1,10 +1,13 @@
 package mysql
 
 import (
+	"bluebell/pkg/metrics"
 	"bluebell/setting"
+	"context"
+	"database/sql"
 	"fmt"
 
-	_ "github.com/go-sql-driver/mysql"
+	mysqldriver "github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -14,12 +17,24 @@ var db *sqlx.DB
 func Init(cfg *setting.MySQLConfig) (err error) {
 	// "user:password@tcp(host:port)/dbname"
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true&loc=Local", cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.DB)
-	db, err = sqlx.Connect("mysql", dsn)
+	dsnCfg, err := mysqldriver.ParseDSN(dsn)
 	if err != nil {
-		return
+		return err
 	}
+	connector, err := mysqldriver.NewConnector(dsnCfg)
+	if err != nil {
+		return err
+	}
+	// 包装驱动,每条SQL语句生成一个span
+	conn := sqlx.NewDb(sql.OpenDB(tracedConnector{connector}), "mysql")
+	if err = conn.Ping(); err != nil {
+		_ = conn.Close()
+		return err
+	}
+	db = conn
 	db.SetMaxOpenConns(cfg.MaxOpenConns)
 	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	metrics.Register(metrics.NewDBStatsCollector(db.DB, cfg.DB))
 	return
 }
 
@@ -27,3 +42,8 @@ func Init(cfg *setting.MySQLConfig) (err error) {
 func Close() {
 	_ = db.Close()
 }
+
+// Ping 检查MySQL连接是否可用
+func Ping(ctx context.Context) error {
+	return db.PingContext(ctx)
+}
@@ -0,0 +1,190 @@
+package mysql
+
+import (
+	"bluebell/models"
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// 帖子的写操作与变更事件在同一个事务中写入post_outbox表
+// 即使redis暂时不可用,事件也不会丢失,由后台任务重试
+
+// CreatePostWithOutbox 创建帖子并写入post_created事件
+func CreatePostWithOutbox(ctx context.Context, p *models.Post) error {
+	return withTx(ctx, func(tx *sqlx.Tx) error {
+		sqlStr := `insert into post(
+		post_id, title, content, author_id, community_id)
+		values (?, ?, ?, ?, ?)
+		`
+		if _, err := tx.ExecContext(ctx, sqlStr, p.ID, p.Title, p.Content, p.AuthorID, p.CommunityID); err != nil {
+			return err
+		}
+		return insertOutbox(ctx, tx, models.OutboxPostCreated, p.ID, p.CommunityID)
+	})
+}
+
+// UpdatePostWithOutbox 修改帖子的标题和内容并写入post_updated事件
+func UpdatePostWithOutbox(ctx context.Context, p *models.Post) error {
+	return withTx(ctx, func(tx *sqlx.Tx) error {
+		sqlStr := `update post set title = ?, content = ? where post_id = ?`
+		if _, err := tx.ExecContext(ctx, sqlStr, p.Title, p.Content, p.ID); err != nil {
+			return err
+		}
+		return insertOutbox(ctx, tx, models.OutboxPostUpdated, p.ID, p.CommunityID)
+	})
+}
+
+// DeletePostWithOutbox 删除帖子并写入post_deleted事件,帖子不存在时什么也不做
+func DeletePostWithOutbox(ctx context.Context, postID int64) error {
+	return withTx(ctx, func(tx *sqlx.Tx) error {
+		var communityID int64
+		err := tx.GetContext(ctx, &communityID, `select community_id from post where post_id = ? for update`, postID)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `delete from post where post_id = ?`, postID); err != nil {
+			return err
+		}
+		return insertOutbox(ctx, tx, models.OutboxPostDeleted, postID, communityID)
+	})
+}
+
+// ClaimOutboxEvents 领取一批到了处理时间的事件,按写入顺序返回,跳过已经重试了maxAttempts次的事件
+// 领取的事件在lease秒内不会被其他处理方领取,处理方退出或崩溃时,过期后由其他处理方重新领取
+// claimer每次领取都要不同,用来查出这次领取到的事件
+func ClaimOutboxEvents(ctx context.Context, claimer string, limit, maxAttempts int, lease int64) (events []*models.OutboxEvent, err error) {
+	sqlStr := `update post_outbox
+	set claimed_by = ?, claim_expire_time = now() + interval ? second
+	where processed_time is null and attempts < ?
+	and (next_retry_time is null or next_retry_time <= now())
+	and (claim_expire_time is null or claim_expire_time <= now())
+	order by id
+	limit ?
+	`
+	res, err := db.ExecContext(ctx, sqlStr, claimer, lease, maxAttempts, limit)
+	if err != nil {
+		return nil, err
+	}
+	events = make([]*models.OutboxEvent, 0, limit)
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return events, err
+	}
+	sqlStr = `select id, event_type, post_id, community_id, attempts, last_error, create_time, processed_time
+	from post_outbox
+	where claimed_by = ?
+	order by id
+	`
+	err = db.SelectContext(ctx, &events, sqlStr, claimer)
+	return
+}
+
+// MarkOutboxEventProcessed 标记事件已经处理完成
+func MarkOutboxEventProcessed(ctx context.Context, id int64) (err error) {
+	sqlStr := `update post_outbox set processed_time = now() where id = ?`
+	_, err = db.ExecContext(ctx, sqlStr, id)
+	return
+}
+
+// MarkOutboxEventFailed 记录事件处理失败的原因,retryAfter秒之后再重试
+func MarkOutboxEventFailed(ctx context.Context, id int64, reason string, retryAfter int64) (err error) {
+	if r := []rune(reason); len(r) > 512 {
+		reason = string(r[:512])
+	}
+	sqlStr := `update post_outbox
+	set attempts = attempts + 1, last_error = ?, next_retry_time = now() + interval ? second,
+	claimed_by = null, claim_expire_time = null
+	where id = ?
+	`
+	_, err = db.ExecContext(ctx, sqlStr, reason, retryAfter, id)
+	return
+}
+
+// GetDeadOutboxEvents 分页查询重试了maxAttempts次仍然失败、不再自动重试的事件,按写入顺序返回
+func GetDeadOutboxEvents(ctx context.Context, maxAttempts int, page, size int64) (events []*models.OutboxEvent, err error) {
+	events = make([]*models.OutboxEvent, 0, size)
+	sqlStr := `select id, event_type, post_id, community_id, attempts, last_error, create_time, processed_time
+	from post_outbox
+	where processed_time is null and attempts >= ?
+	order by id
+	limit ? offset ?
+	`
+	err = db.SelectContext(ctx, &events, sqlStr, maxAttempts, size, (page-1)*size)
+	return
+}
+
+// CountDeadOutboxEvents 统计不再自动重试的事件数
+func CountDeadOutboxEvents(ctx context.Context, maxAttempts int) (n int64, err error) {
+	sqlStr := `select count(*) from post_outbox where processed_time is null and attempts >= ?`
+	err = db.GetContext(ctx, &n, sqlStr, maxAttempts)
+	return
+}
+
+// RequeueDeadOutboxEvents 把不再自动重试的事件重新加入处理队列,重试次数从0开始计算
+// ids为空时重新加入所有这样的事件,返回重新加入的事件数
+func RequeueDeadOutboxEvents(ctx context.Context, maxAttempts int, ids []int64) (n int64, err error) {
+	sqlStr := `update post_outbox
+	set attempts = 0, next_retry_time = null, claimed_by = null, claim_expire_time = null
+	where processed_time is null and attempts >= ?
+	`
+	args := []interface{}{maxAttempts}
+	if len(ids) > 0 {
+		query, inArgs, err := sqlx.In(sqlStr+" and id in (?)", maxAttempts, ids)
+		if err != nil {
+			return 0, err
+		}
+		sqlStr, args = db.Rebind(query), inArgs
+	}
+	res, err := db.ExecContext(ctx, sqlStr, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// WithPostShareLocked 在事务中给帖子加共享锁后调用fn,帖子不存在时返回 sql.ErrNoRows
+// 修改和删除帖子需要排他锁,fn返回之前帖子不会被修改或删除
+func WithPostShareLocked(ctx context.Context, postID int64, fn func(post *models.Post) error) error {
+	return withTx(ctx, func(tx *sqlx.Tx) error {
+		post := new(models.Post)
+		sqlStr := `select post_id, title, content, author_id, community_id, create_time
+		from post
+		where post_id = ?
+		lock in share mode
+		`
+		if err := tx.GetContext(ctx, post, sqlStr, postID); err != nil {
+			return err
+		}
+		return fn(post)
+	})
+}
+
+func insertOutbox(ctx context.Context, tx *sqlx.Tx, eventType string, postID, communityID int64) error {
+	sqlStr := `insert into post_outbox(event_type, post_id, community_id) values (?, ?, ?)`
+	_, err := tx.ExecContext(ctx, sqlStr, eventType, postID, communityID)
+	return err
+}
+
+// withTx 在事务中执行fn,fn返回错误时回滚
+func withTx(ctx context.Context, fn func(tx *sqlx.Tx) error) (err error) {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+	return fn(tx)
+}
@@ -0,0 +1,121 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+)
+
+func TestClaimOutboxEvents(t *testing.T) {
+	ctx := context.Background()
+	requireDB(t)
+	const postID = -20240501 // 不会和真实的帖子冲突
+	t.Cleanup(func() {
+		_, _ = db.ExecContext(ctx, `delete from post_outbox where post_id = ?`, postID)
+	})
+	for i := 0; i < 3; i++ {
+		if _, err := db.ExecContext(ctx, `insert into post_outbox(event_type, post_id, community_id) values ('post_updated', ?, 1)`, postID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	claimed := make(map[int64]string)
+	for _, claimer := range []string{"test-claimer-a", "test-claimer-b"} {
+		events, err := ClaimOutboxEvents(ctx, claimer, 1000, 20, 60)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range events {
+			if e.PostID != postID {
+				continue
+			}
+			if prev, ok := claimed[e.ID]; ok {
+				t.Fatalf("event %d claimed by both %s and %s", e.ID, prev, claimer)
+			}
+			claimed[e.ID] = claimer
+		}
+	}
+	var count int
+	if err := db.GetContext(ctx, &count, `select count(*) from post_outbox where post_id = ? and claimed_by is not null`, postID); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Fatalf("claimed events = %d, want 3", count)
+	}
+
+	// 处理失败的事件释放领取,到了重试时间后可以被重新领取
+	for id := range claimed {
+		if err := MarkOutboxEventFailed(ctx, id, "test", 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	events, err := ClaimOutboxEvents(ctx, "test-claimer-c", 1000, 20, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for _, e := range events {
+		if _, ok := claimed[e.ID]; ok {
+			n++
+		}
+	}
+	if n != 3 {
+		t.Fatalf("reclaimed events = %d, want 3", n)
+	}
+}
+
+func TestRequeueDeadOutboxEvents(t *testing.T) {
+	ctx := context.Background()
+	requireDB(t)
+	const postID = -20240502 // 不会和真实的帖子冲突
+	t.Cleanup(func() {
+		_, _ = db.ExecContext(ctx, `delete from post_outbox where post_id = ?`, postID)
+	})
+	res, err := db.ExecContext(ctx, `insert into post_outbox(event_type, post_id, community_id, attempts, last_error) values ('post_updated', ?, 1, 20, 'test')`, postID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 重试次数用完的事件不会被领取,只能在列表中查到
+	events, err := ClaimOutboxEvents(ctx, "test-claimer-dead", 1000, 20, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range events {
+		if e.ID == id {
+			t.Fatal("dead event claimed")
+		}
+	}
+	if n, err := CountDeadOutboxEvents(ctx, 20); err != nil || n < 1 {
+		t.Fatalf("CountDeadOutboxEvents() = %d, %v, want at least 1", n, err)
+	}
+	dead, err := GetDeadOutboxEvents(ctx, 20, 1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, e := range dead {
+		found = found || e.ID == id
+	}
+	if !found {
+		t.Fatalf("dead event %d not listed", id)
+	}
+
+	// 重新加入队列后可以再次领取
+	if n, err := RequeueDeadOutboxEvents(ctx, 20, []int64{id}); err != nil || n != 1 {
+		t.Fatalf("RequeueDeadOutboxEvents() = %d, %v, want 1", n, err)
+	}
+	events, err = ClaimOutboxEvents(ctx, "test-claimer-requeued", 1000, 20, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range events {
+		if e.ID == id {
+			return
+		}
+	}
+	t.Fatalf("requeued event %d not claimed", id)
+}
@@ -2,36 +2,38 @@ package mysql
 
 import (
 	"bluebell/models"
-	"strconv"
+	"bluebell/pkg/cursor"
+	"context"
 	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
 // CreatePost 创建帖子
-func CreatePost(p *models.Post) (err error) {
+func CreatePost(ctx context.Context, p *models.Post) (err error) {
 	sqlStr := `insert into post(
 	post_id, title, content, author_id, community_id)
 	values (?, ?, ?, ?, ?)
 	`
-	_, err = db.Exec(sqlStr, p.ID, p.Title, p.Content, p.AuthorID, p.CommunityID)
+	_, err = db.ExecContext(ctx, sqlStr, p.ID, p.Title, p.Content, p.AuthorID, p.CommunityID)
 	return
 }
 
 // GetPostById 根据id查询单个贴子数据
-func GetPostById(pid int64) (post *models.Post, err error) {
+func GetPostById(ctx context.Context, pid int64) (post *models.Post, err error) {
 	post = new(models.Post)
 	sqlStr := `select
 	post_id, title, content, author_id, community_id, create_time
 	from post
 	where post_id = ?
 	`
-	err = db.Get(post, sqlStr, pid)
+	err = db.GetContext(ctx, post, sqlStr, pid)
 	return
 }
 
 // GetPostList 查询帖子列表函数
-func GetPostList(page, size int64) (posts []*models.Post, err error) {
+func GetPostList(ctx context.Context, page, size int64) (posts []*models.Post, err error) {
 	sqlStr := `select 
 	post_id, title, content, author_id, community_id, create_time
 	from post
@@ -40,12 +42,73 @@ func GetPostList(page, size int64) (posts []*models.Post, err error) {
 	limit ?,?
 	`
 	posts = make([]*models.Post, 0, 2) // 不要写成make([]*models.Post, 2)
-	err = db.Select(&posts, sqlStr, (page-1)*size, size)
+	err = db.SelectContext(ctx, &posts, sqlStr, (page-1)*size, size)
+	return
+}
+
+// GetPostListByCursor 按发帖时间从新到旧查询排在游标之后的帖子,返回下一页的游标
+// 使用 (create_time, post_id) 作为键值分页,新发的帖子不会让后面的页发生偏移
+func GetPostListByCursor(ctx context.Context, c *cursor.Cursor, size int64) (posts []*models.Post, next *cursor.Cursor, err error) {
+	return getPostListByCursor(ctx, "", nil, c, size)
+}
+
+// GetPostListByAuthor 按发帖时间从新到旧查询作者排在游标之后的帖子,返回下一页的游标
+func GetPostListByAuthor(ctx context.Context, authorID int64, c *cursor.Cursor, size int64) (posts []*models.Post, next *cursor.Cursor, err error) {
+	return getPostListByCursor(ctx, "author_id = ?", []interface{}{authorID}, c, size)
+}
+
+// GetPostListByAuthorPage 按发帖时间从新到旧分页查询作者的帖子(兼容按页码分页的旧客户端)
+func GetPostListByAuthorPage(ctx context.Context, authorID, page, size int64) (posts []*models.Post, err error) {
+	sqlStr := `select 
+	post_id, title, content, author_id, community_id, create_time
+	from post
+	where author_id = ?
+	order by create_time desc, post_id desc
+	limit ?,?
+	`
+	posts = make([]*models.Post, 0, size)
+	err = db.SelectContext(ctx, &posts, sqlStr, authorID, (page-1)*size, size)
+	return
+}
+
+// getPostListByCursor cond为额外的过滤条件,为空表示不过滤
+func getPostListByCursor(ctx context.Context, cond string, args []interface{}, c *cursor.Cursor, size int64) (posts []*models.Post, next *cursor.Cursor, err error) {
+	conds := make([]string, 0, 2)
+	if cond != "" {
+		conds = append(conds, cond)
+	}
+	if c != nil {
+		t := time.Unix(int64(c.Score), 0)
+		conds = append(conds, "(create_time < ? or (create_time = ? and post_id < ?))")
+		args = append(args, t, t, c.ID)
+	}
+	sqlStr := `select 
+	post_id, title, content, author_id, community_id, create_time
+	from post
+	`
+	if len(conds) > 0 {
+		sqlStr += "where " + strings.Join(conds, " and ") + "\n"
+	}
+	sqlStr += `order by create_time desc, post_id desc
+	limit ?
+	`
+	args = append(args, size)
+	posts = make([]*models.Post, 0, size)
+	if err = db.SelectContext(ctx, &posts, sqlStr, args...); err != nil {
+		return nil, nil, err
+	}
+	if n := len(posts); n > 0 && int64(n) == size {
+		last := posts[n-1]
+		next = &cursor.Cursor{
+			Score: float64(last.CreateTime.Unix()),
+			ID:    last.ID,
+		}
+	}
 	return
 }
 
 // GetPostListByIDs 根据给定的id列表查询帖子数据
-func GetPostListByIDs(ids []string) (postList []*models.Post, err error) {
+func GetPostListByIDs(ctx context.Context, ids []string) (postList []*models.Post, err error) {
 	sqlStr := `select post_id, title, content, author_id, community_id, create_time
 	from post
 	where post_id in (?)
@@ -57,14 +120,14 @@ func GetPostListByIDs(ids []string) (postList []*models.Post, err error) {
 		return nil, err
 	}
 	query = db.Rebind(query)
-	err = db.Select(&postList, query, args...) // !!!!!!
+	err = db.SelectContext(ctx, &postList, query, args...) // !!!!!!
 	return
 }
 
 // DeletePost 删除帖子
-func DeletePost(postId int64) (err error) {
+func DeletePost(ctx context.Context, postId int64) (err error) {
 	sqlstr := `delete from post where Post_id = ?`
-	_, err = db.Exec(sqlstr, postId)
+	_, err = db.ExecContext(ctx, sqlstr, postId)
 	if err != nil {
 		return err
 	}
@@ -72,13 +135,13 @@ func DeletePost(postId int64) (err error) {
 }
 
 // GetPostByTitle 用帖子标题模糊查询
-func GetPostsByTitle(title string) ([]*models.Post, error) {
+func GetPostsByTitle(ctx context.Context, title string) ([]*models.Post, error) {
 	var posts []*models.Post
 	// 修改 SQL 查询语句以支持模糊匹配
 	sqlStr := `SELECT post_id, title, content, author_id, community_id FROM post WHERE title LIKE ?`
 	// 使用 % 符号进行模糊匹配
 	searchTitle := "%" + title + "%"
-	err := db.Select(&posts, sqlStr, searchTitle)
+	err := db.SelectContext(ctx, &posts, sqlStr, searchTitle)
 	if err != nil {
 		return nil, err
 	}
@@ -86,14 +149,64 @@ func GetPostsByTitle(title string) ([]*models.Post, error) {
 }
 
 // GetPostListByCommunityIDs 根据给定的社区id列表查询帖子数据
-func GetPostListByCommunityIDs(id int64) (postList []*models.Post, err error) {
-	ids := strconv.FormatInt(id, 10)
+func GetPostListByCommunityIDs(ctx context.Context, ids []int64) (postList []*models.Post, err error) {
 	sqlStr := `select post_id, title, content, author_id, community_id, create_time
 	from post
 	where community_id in (?)
 	order by create_time desc 
 	`
 	// https: //www.liwenzhou.com/posts/Go/sqlx/
-	_ = db.Select(&postList, sqlStr, ids)
+	query, args, err := sqlx.In(sqlStr, ids)
+	if err != nil {
+		return nil, err
+	}
+	query = db.Rebind(query)
+	err = db.SelectContext(ctx, &postList, query, args...)
+	return
+}
+
+// GetPostsAfter 按post_id从小到大查询排在lastID之后的帖子,用于分批遍历所有帖子
+func GetPostsAfter(ctx context.Context, lastID int64, limit int) (posts []*models.Post, err error) {
+	sqlStr := `select post_id, author_id, community_id, create_time
+	from post
+	where post_id > ?
+	order by post_id
+	limit ?
+	`
+	posts = make([]*models.Post, 0, limit)
+	err = db.SelectContext(ctx, &posts, sqlStr, lastID, limit)
+	return
+}
+
+// GetPostDocsAfter 按post_id顺序查询lastID之后的帖子,包括标题和内容,用于重建搜索索引
+func GetPostDocsAfter(ctx context.Context, lastID int64, limit int) (posts []*models.Post, err error) {
+	sqlStr := `select post_id, title, content, author_id, community_id, create_time
+	from post
+	where post_id > ?
+	order by post_id
+	limit ?
+	`
+	posts = make([]*models.Post, 0, limit)
+	err = db.SelectContext(ctx, &posts, sqlStr, lastID, limit)
 	return
 }
+
+// GetPostCommunities 批量查询帖子所属的社区,不存在的帖子不会出现在结果中
+func GetPostCommunities(ctx context.Context, ids []int64) (map[int64]int64, error) {
+	res := make(map[int64]int64, len(ids))
+	if len(ids) == 0 {
+		return res, nil
+	}
+	query, args, err := sqlx.In(`select post_id, community_id from post where post_id in (?)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	var rows []*models.Post
+	if err := db.SelectContext(ctx, &rows, db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		res[row.ID] = row.CommunityID
+	}
+	return res, nil
+}
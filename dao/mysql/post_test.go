@@ -3,10 +3,16 @@ package mysql
 import (
 	"bluebell/models"
 	"bluebell/setting"
+	"context"
 	"testing"
 )
 
-func init() {
+// requireDB 连接本地测试用的MySQL,连接不上时跳过测试
+func requireDB(t *testing.T) {
+	t.Helper()
+	if db != nil {
+		return
+	}
 	dbCfg := setting.MySQLConfig{
 		Host:         "127.0.0.1",
 		User:         "root",
@@ -16,13 +22,15 @@ func init() {
 		MaxOpenConns: 10,
 		MaxIdleConns: 10,
 	}
-	err := Init(&dbCfg)
-	if err != nil {
-		panic(err)
+	if err := Init(&dbCfg); err != nil {
+		db = nil
+		t.Skipf("mysql not available: %v", err)
 	}
 }
 
 func TestCreatePost(t *testing.T) {
+	ctx := context.Background()
+	requireDB(t)
 	post := models.Post{
 		ID:          10,
 		AuthorID:    123,
@@ -30,7 +38,7 @@ func TestCreatePost(t *testing.T) {
 		Title:       "test",
 		Content:     "just a test",
 	}
-	err := CreatePost(&post)
+	err := CreatePost(ctx, &post)
 	if err != nil {
 		t.Fatalf("CreatePost insert record into mysql failed, err:%v\n", err)
 	}
@@ -0,0 +1,65 @@
+package mysql
+
+import (
+	"bluebell/models"
+	"context"
+	"strings"
+	"time"
+)
+
+// SearchPostsParam 全文搜索帖子的条件
+type SearchPostsParam struct {
+	Keyword     string
+	CommunityID int64     // 为0表示不限制社区
+	AuthorName  string    // 为空表示不限制作者
+	Since       time.Time // 为零值表示不限制发帖时间
+	Offset      int64
+	Limit       int64
+}
+
+// SearchPosts 使用全文索引搜索帖子的标题和内容,按相关度从高到低排序
+func SearchPosts(ctx context.Context, p *SearchPostsParam) (posts []*models.Post, total int64, err error) {
+	where, args := buildSearchWhere(p)
+
+	countStr := `select count(*) from post p ` + where
+	if err = db.GetContext(ctx, &total, countStr, args...); err != nil {
+		return nil, 0, err
+	}
+	if total == 0 {
+		return make([]*models.Post, 0), 0, nil
+	}
+
+	sqlStr := `select p.post_id, p.title, p.content, p.author_id, p.community_id, p.create_time
+	from post p ` + where + `
+	order by match(p.title, p.content) against(? in natural language mode) desc, p.create_time desc
+	limit ?, ?
+	`
+	args = append(args, p.Keyword, p.Offset, p.Limit)
+	posts = make([]*models.Post, 0, p.Limit)
+	err = db.SelectContext(ctx, &posts, sqlStr, args...)
+	return
+}
+
+func buildSearchWhere(p *SearchPostsParam) (string, []interface{}) {
+	var (
+		joins []string
+		conds = []string{"match(p.title, p.content) against(? in natural language mode)"}
+		args  = []interface{}{p.Keyword}
+	)
+	if p.AuthorName != "" {
+		joins = append(joins, "join user u on u.user_id = p.author_id")
+		conds = append(conds, "u.username = ?")
+		args = append(args, p.AuthorName)
+	}
+	if p.CommunityID != 0 {
+		conds = append(conds, "p.community_id = ?")
+		args = append(args, p.CommunityID)
+	}
+	if !p.Since.IsZero() {
+		conds = append(conds, "p.create_time >= ?")
+		args = append(args, p.Since)
+	}
+	// join中没有占位符,args的顺序和where中占位符的顺序一致
+	where := strings.Join(joins, " ") + " where " + strings.Join(conds, " and ")
+	return where, args
+}
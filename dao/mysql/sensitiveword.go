@@ -0,0 +1,145 @@
+package mysql
+
+import (
+	"bluebell/models"
+	"context"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// sensitiveWordBatch 批量插入时每条语句最多插入的词数
+const sensitiveWordBatch = 500
+
+// GetSensitiveWords 查询所有分类的敏感词
+func GetSensitiveWords(ctx context.Context) (words []*models.SensitiveWord, err error) {
+	sqlStr := `select category, word from sensitive_word`
+	words = make([]*models.SensitiveWord, 0)
+	err = db.SelectContext(ctx, &words, sqlStr)
+	return
+}
+
+// GetSensitiveWordCategories 查询所有的分类及每个分类中词的数量
+func GetSensitiveWordCategories(ctx context.Context) (categories []*models.SensitiveWordCategory, err error) {
+	sqlStr := `select category, count(*) as count
+	from sensitive_word
+	group by category
+	order by category
+	`
+	categories = make([]*models.SensitiveWordCategory, 0)
+	err = db.SelectContext(ctx, &categories, sqlStr)
+	return
+}
+
+// GetCategorySensitiveWords 查询一个分类中的所有敏感词,按词排序
+func GetCategorySensitiveWords(ctx context.Context, category string) (words []string, err error) {
+	sqlStr := `select word from sensitive_word where category = ? order by word`
+	words = make([]string, 0)
+	err = db.SelectContext(ctx, &words, sqlStr, category)
+	return
+}
+
+// CountSensitiveWords 查询敏感词的总数
+func CountSensitiveWords(ctx context.Context) (count int64, err error) {
+	sqlStr := `select count(*) from sensitive_word`
+	err = db.GetContext(ctx, &count, sqlStr)
+	return
+}
+
+// AddSensitiveWords 向分类中添加敏感词,已经存在的词被忽略,返回新增的词数
+func AddSensitiveWords(ctx context.Context, category string, words []string) (added int64, err error) {
+	err = withTx(ctx, func(tx *sqlx.Tx) error {
+		added, err = insertSensitiveWords(ctx, tx, category, words)
+		return err
+	})
+	return
+}
+
+// ReplaceSensitiveWords 用words替换分类中原有的所有敏感词,返回新增和删除的词数
+func ReplaceSensitiveWords(ctx context.Context, category string, words []string) (added, removed int64, err error) {
+	err = withTx(ctx, func(tx *sqlx.Tx) error {
+		var existing []string
+		sqlStr := `select word from sensitive_word where category = ? for update`
+		if err := tx.SelectContext(ctx, &existing, sqlStr, category); err != nil {
+			return err
+		}
+		keep := make(map[string]bool, len(words))
+		for _, w := range words {
+			keep[w] = true
+		}
+		var stale []string
+		for _, w := range existing {
+			if !keep[w] {
+				stale = append(stale, w)
+			}
+		}
+		if removed, err = deleteSensitiveWords(ctx, tx, category, stale); err != nil {
+			return err
+		}
+		added, err = insertSensitiveWords(ctx, tx, category, words)
+		return err
+	})
+	return
+}
+
+// insertSensitiveWords 分批插入敏感词,返回实际插入的行数
+func insertSensitiveWords(ctx context.Context, tx *sqlx.Tx, category string, words []string) (inserted int64, err error) {
+	for len(words) > 0 {
+		n := len(words)
+		if n > sensitiveWordBatch {
+			n = sensitiveWordBatch
+		}
+		placeholders := make([]string, 0, n)
+		args := make([]interface{}, 0, 2*n)
+		for _, w := range words[:n] {
+			placeholders = append(placeholders, "(?, ?)")
+			args = append(args, category, w)
+		}
+		sqlStr := `insert ignore into sensitive_word(category, word) values ` + strings.Join(placeholders, ", ")
+		res, err := tx.ExecContext(ctx, sqlStr, args...)
+		if err != nil {
+			return inserted, err
+		}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return inserted, err
+		}
+		inserted += rows
+		words = words[n:]
+	}
+	return inserted, nil
+}
+
+// RemoveSensitiveWords 从分类中删除敏感词,返回删除的词数
+func RemoveSensitiveWords(ctx context.Context, category string, words []string) (removed int64, err error) {
+	err = withTx(ctx, func(tx *sqlx.Tx) error {
+		removed, err = deleteSensitiveWords(ctx, tx, category, words)
+		return err
+	})
+	return
+}
+
+// deleteSensitiveWords 分批删除敏感词,返回实际删除的行数
+func deleteSensitiveWords(ctx context.Context, tx *sqlx.Tx, category string, words []string) (deleted int64, err error) {
+	for len(words) > 0 {
+		n := len(words)
+		if n > sensitiveWordBatch {
+			n = sensitiveWordBatch
+		}
+		query, args, err := sqlx.In(`delete from sensitive_word where category = ? and word in (?)`, category, words[:n])
+		if err != nil {
+			return deleted, err
+		}
+		res, err := tx.ExecContext(ctx, tx.Rebind(query), args...)
+		if err != nil {
+			return deleted, err
+		}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return deleted, err
+		}
+		deleted += rows
+		words = words[n:]
+	}
+	return deleted, nil
+}
@@ -0,0 +1,41 @@
+package mysql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestSensitiveWords(t *testing.T) {
+	ctx := context.Background()
+	requireDB(t)
+	const category = "test_sensitive_word"
+	t.Cleanup(func() {
+		_, _ = db.ExecContext(ctx, `delete from sensitive_word where category = ?`, category)
+	})
+
+	added, err := AddSensitiveWords(ctx, category, []string{"刷单", "代购", "SM"})
+	if err != nil || added != 3 {
+		t.Fatalf("AddSensitiveWords() = %d, %v, want 3", added, err)
+	}
+	// 已经存在的词被忽略,区分大小写
+	if added, err = AddSensitiveWords(ctx, category, []string{"刷单", "sm"}); err != nil || added != 1 {
+		t.Fatalf("AddSensitiveWords() again = %d, %v, want 1", added, err)
+	}
+	removed, err := RemoveSensitiveWords(ctx, category, []string{"sm", "不存在"})
+	if err != nil || removed != 1 {
+		t.Fatalf("RemoveSensitiveWords() = %d, %v, want 1", removed, err)
+	}
+
+	added, removed, err = ReplaceSensitiveWords(ctx, category, []string{"刷单", "赌博"})
+	if err != nil || added != 1 || removed != 2 {
+		t.Fatalf("ReplaceSensitiveWords() = %d, %d, %v, want 1, 2", added, removed, err)
+	}
+	words, err := GetCategorySensitiveWords(ctx, category)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"刷单", "赌博"}; !reflect.DeepEqual(words, want) {
+		t.Fatalf("GetCategorySensitiveWords() = %q, want %q", words, want)
+	}
+}
@@ -0,0 +1,166 @@
+package mysql
+
+import (
+	"bluebell/pkg/tracing"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 在数据库驱动外面包一层,每条SQL语句生成一个span,事务内的语句和迁移也包括在内
+// span在语句执行完后按实际的开始结束时间补记,这样驱动返回 driver.ErrSkip
+// 让database/sql改用预处理语句重试时,不会多出一个失败的span
+
+type tracedConnector struct {
+	driver.Connector
+}
+
+func (c tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{Conn: conn}, nil
+}
+
+// recordSpan 补记一条语句的span
+func recordSpan(ctx context.Context, query string, start time.Time, err error) {
+	if errors.Is(err, driver.ErrSkip) || !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+		// 没有上层span的语句(如连接池的后台操作)不单独生成追踪
+		return
+	}
+	_, span := tracing.Start(ctx, spanName(query),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(start),
+		trace.WithAttributes(
+			semconv.DBSystemMySQL,
+			semconv.DBQueryText(query),
+		))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End(trace.WithTimestamp(time.Now()))
+}
+
+// spanName 取SQL语句的第一个单词,如 mysql.select
+func spanName(query string) string {
+	op, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+	return "mysql." + strings.ToLower(op)
+}
+
+type tracedConn struct {
+	driver.Conn
+}
+
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	res, err := execer.ExecContext(ctx, query, args)
+	recordSpan(ctx, query, start, err)
+	return res, err
+}
+
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	recordSpan(ctx, query, start, err)
+	return rows, err
+}
+
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tracedStmt{Stmt: stmt, query: query}, nil
+}
+
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin() //nolint:staticcheck // 驱动不支持BeginTx时的兼容
+}
+
+func (c *tracedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+// IsValid 连接出错后驱动返回false,连接池不再复用这个连接
+func (c *tracedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+func (c *tracedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type tracedStmt struct {
+	driver.Stmt
+	query string
+}
+
+func (s *tracedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := s.Stmt.(driver.StmtExecContext)
+	if !ok {
+		return nil, errors.New("mysql: statement does not support ExecContext")
+	}
+	start := time.Now()
+	res, err := execer.ExecContext(ctx, args)
+	recordSpan(ctx, s.query, start, err)
+	return res, err
+}
+
+func (s *tracedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := s.Stmt.(driver.StmtQueryContext)
+	if !ok {
+		return nil, errors.New("mysql: statement does not support QueryContext")
+	}
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, args)
+	recordSpan(ctx, s.query, start, err)
+	return rows, err
+}
+
+func (s *tracedStmt) ColumnConverter(idx int) driver.ValueConverter {
+	if cc, ok := s.Stmt.(driver.ColumnConverter); ok { //nolint:staticcheck // 转发驱动的实现
+		return cc.ColumnConverter(idx)
+	}
+	return driver.DefaultParameterConverter
+}
@@ -2,10 +2,13 @@ package mysql
 
 import (
 	"bluebell/models"
+	"context"
 	"crypto/md5"
 	"database/sql"
 	"encoding/hex"
 	"fmt"
+
+	"github.com/jmoiron/sqlx"
 )
 
 // 把每一步数据库操作封装成函数
@@ -14,10 +17,10 @@ import (
 const secret = "liwenzhou.com"
 
 // CheckUserExist 检查指定用户名的用户是否存在
-func CheckUserExist(username string) (err error) {
+func CheckUserExist(ctx context.Context, username string) (err error) {
 	sqlStr := `select count(user_id) from user where username = ?`
 	var count int64
-	if err := db.Get(&count, sqlStr, username); err != nil {
+	if err := db.GetContext(ctx, &count, sqlStr, username); err != nil {
 		return err
 	}
 	if count > 0 {
@@ -27,12 +30,29 @@ func CheckUserExist(username string) (err error) {
 }
 
 // InsertUser 想数据库中插入一条新的用户记录
-func InsertUser(user *models.User) (err error) {
+func InsertUser(ctx context.Context, user *models.User) (err error) {
 	// 对密码进行加密
 	user.Password = encryptPassword(user.Password)
 	// 执行SQL语句入库
-	sqlStr := `insert into user(user_id, username, password) values(?,?,?)`
-	_, err = db.Exec(sqlStr, user.UserID, user.Username, user.Password)
+	if user.Role == "" {
+		user.Role = models.UserRoleUser
+	}
+	sqlStr := `insert into user(user_id, username, password, role) values(?,?,?,?)`
+	_, err = db.ExecContext(ctx, sqlStr, user.UserID, user.Username, user.Password, user.Role)
+	return
+}
+
+// SetUserRole 修改用户的角色并返回用户ID,用户不存在时返回 ErrorUserNotExist
+func SetUserRole(ctx context.Context, username, role string) (userID int64, err error) {
+	sqlStr := `select user_id from user where username = ?`
+	if err = db.GetContext(ctx, &userID, sqlStr, username); err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrorUserNotExist
+		}
+		return 0, err
+	}
+	sqlStr = `update user set role = ? where user_id = ?`
+	_, err = db.ExecContext(ctx, sqlStr, role, userID)
 	return
 }
 
@@ -43,10 +63,10 @@ func encryptPassword(oPassword string) string {
 	return hex.EncodeToString(h.Sum([]byte(oPassword)))
 }
 
-func Login(user *models.User) (err error) {
+func Login(ctx context.Context, user *models.User) (err error) {
 	oPassword := user.Password // 用户登录的密码
-	sqlStr := `select user_id, username, password from user where username=?`
-	err = db.Get(user, sqlStr, user.Username)
+	sqlStr := `select user_id, username, password, role from user where username=?`
+	err = db.GetContext(ctx, user, sqlStr, user.Username)
 	if err == sql.ErrNoRows {
 		return ErrorUserNotExist
 	}
@@ -64,9 +84,25 @@ func Login(user *models.User) (err error) {
 }
 
 // GetUserById 根据id获取用户信息
-func GetUserById(uid int64) (user *models.User, err error) {
+func GetUserById(ctx context.Context, uid int64) (user *models.User, err error) {
 	user = new(models.User)
 	sqlStr := `select user_id, username from user where user_id = ?`
-	err = db.Get(user, sqlStr, uid)
+	err = db.GetContext(ctx, user, sqlStr, uid)
+	return
+}
+
+// GetUsersByIDs 根据id列表批量查询用户信息,不存在的id会被忽略
+func GetUsersByIDs(ctx context.Context, ids []int64) (users []*models.User, err error) {
+	users = make([]*models.User, 0, len(ids))
+	if len(ids) == 0 {
+		return
+	}
+	sqlStr := `select user_id, username from user where user_id in (?)`
+	query, args, err := sqlx.In(sqlStr, ids)
+	if err != nil {
+		return nil, err
+	}
+	query = db.Rebind(query)
+	err = db.SelectContext(ctx, &users, query, args...)
 	return
 }
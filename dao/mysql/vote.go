@@ -0,0 +1,47 @@
+package mysql
+
+import (
+	"bluebell/models"
+	"context"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// GetVoteArchives 批量查询帖子归档的投票数据,没有归档的帖子不会出现在结果中
+func GetVoteArchives(ctx context.Context, postIDs []int64) (map[int64]*models.VoteArchive, error) {
+	res := make(map[int64]*models.VoteArchive, len(postIDs))
+	if len(postIDs) == 0 {
+		return res, nil
+	}
+	sqlStr := `select post_id, up_votes, down_votes from post_vote_archive where post_id in (?)`
+	query, args, err := sqlx.In(sqlStr, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	var archives []*models.VoteArchive
+	if err := db.SelectContext(ctx, &archives, db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	for _, a := range archives {
+		res[a.PostID] = a
+	}
+	return res, nil
+}
+
+// SaveVoteArchives 批量保存帖子的投票归档,已经归档过的帖子更新票数
+func SaveVoteArchives(ctx context.Context, archives []*models.VoteArchive) error {
+	if len(archives) == 0 {
+		return nil
+	}
+	placeholders := make([]string, 0, len(archives))
+	args := make([]interface{}, 0, 3*len(archives))
+	for _, a := range archives {
+		placeholders = append(placeholders, "(?,?,?)")
+		args = append(args, a.PostID, a.UpVotes, a.DownVotes)
+	}
+	sqlStr := `insert into post_vote_archive(post_id, up_votes, down_votes) values ` + strings.Join(placeholders, ",") +
+		` on duplicate key update up_votes = values(up_votes), down_votes = values(down_votes)`
+	_, err := db.ExecContext(ctx, sqlStr, args...)
+	return err
+}
@@ -0,0 +1,149 @@
+package redis
+
+import (
+	"bluebell/models"
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 用户名和社区详情很少变化,列表接口每次都要用到,缓存在redis中减少MySQL查询
+
+const (
+	entityCacheTTL = 30 * time.Minute
+	// cacheInvalidationBuffer 还没有处理的删除通知最多保留的数量,超过时丢弃,被丢弃的缓存只能等待过期
+	cacheInvalidationBuffer = 256
+)
+
+// CacheInvalidation 删除进程内缓存的通知
+type CacheInvalidation struct {
+	Name string // 缓存名,如 username、community
+	ID   int64
+}
+
+// GetCachedUsernames 批量查询缓存的用户名,只返回命中的部分
+func GetCachedUsernames(ctx context.Context, ids []int64) (map[int64]string, error) {
+	vals, err := mgetByIDs(ctx, KeyCacheUsernamePF, ids)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[int64]string, len(ids))
+	for i, val := range vals {
+		if s, ok := val.(string); ok {
+			names[ids[i]] = s
+		}
+	}
+	return names, nil
+}
+
+// SetCachedUsernames 批量缓存用户名
+func SetCachedUsernames(ctx context.Context, names map[int64]string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	pipeline := rdb(ctx).Pipeline()
+	for id, name := range names {
+		pipeline.Set(getRedisKey(KeyCacheUsernamePF+strconv.FormatInt(id, 10)), name, entityCacheTTL)
+	}
+	_, err := pipeline.Exec()
+	return err
+}
+
+// DeleteCachedUsername 用户名变化时删除缓存
+func DeleteCachedUsername(ctx context.Context, id int64) error {
+	return rdb(ctx).Del(getRedisKey(KeyCacheUsernamePF + strconv.FormatInt(id, 10))).Err()
+}
+
+// GetCachedCommunities 批量查询缓存的社区详情,只返回命中的部分
+func GetCachedCommunities(ctx context.Context, ids []int64) (map[int64]*models.CommunityDetail, error) {
+	vals, err := mgetByIDs(ctx, KeyCacheCommunityPF, ids)
+	if err != nil {
+		return nil, err
+	}
+	communities := make(map[int64]*models.CommunityDetail, len(ids))
+	for i, val := range vals {
+		s, ok := val.(string)
+		if !ok {
+			continue
+		}
+		community := new(models.CommunityDetail)
+		if err := json.Unmarshal([]byte(s), community); err != nil {
+			// 格式不对的缓存当作未命中处理,重新从MySQL加载后会被覆盖
+			continue
+		}
+		communities[ids[i]] = community
+	}
+	return communities, nil
+}
+
+// SetCachedCommunities 批量缓存社区详情
+func SetCachedCommunities(ctx context.Context, communities map[int64]*models.CommunityDetail) error {
+	if len(communities) == 0 {
+		return nil
+	}
+	pipeline := rdb(ctx).Pipeline()
+	for id, community := range communities {
+		data, err := json.Marshal(community)
+		if err != nil {
+			return err
+		}
+		pipeline.Set(getRedisKey(KeyCacheCommunityPF+strconv.FormatInt(id, 10)), data, entityCacheTTL)
+	}
+	_, err := pipeline.Exec()
+	return err
+}
+
+// DeleteCachedCommunity 社区信息变化时删除缓存
+func DeleteCachedCommunity(ctx context.Context, id int64) error {
+	return rdb(ctx).Del(getRedisKey(KeyCacheCommunityPF + strconv.FormatInt(id, 10))).Err()
+}
+
+// PublishCacheInvalidated 通知所有实例删除进程内缓存中的一项
+func PublishCacheInvalidated(ctx context.Context, name string, id int64) error {
+	return rdb(ctx).Publish(getRedisKey(KeyChannelCache), name+":"+strconv.FormatInt(id, 10)).Err()
+}
+
+// SubscribeCacheInvalidated 订阅删除进程内缓存的通知
+// 调用stop取消订阅后返回的channel被关闭
+// 连接断开期间的通知会丢失,订阅方的进程内缓存需要设置较短的过期时间作为补偿
+func SubscribeCacheInvalidated() (invalidations <-chan CacheInvalidation, stop func() error, err error) {
+	ps := client.Subscribe(getRedisKey(KeyChannelCache))
+	// 等待订阅确认,这样返回之后发布的通知都能收到
+	if _, err := ps.Receive(); err != nil {
+		_ = ps.Close()
+		return nil, nil, err
+	}
+	ch := make(chan CacheInvalidation, cacheInvalidationBuffer)
+	go func() {
+		defer close(ch)
+		for msg := range ps.Channel() {
+			i := strings.LastIndexByte(msg.Payload, ':')
+			if i < 0 {
+				continue
+			}
+			id, err := strconv.ParseInt(msg.Payload[i+1:], 10, 64)
+			if err != nil {
+				continue
+			}
+			select {
+			case ch <- CacheInvalidation{Name: msg.Payload[:i], ID: id}:
+			default:
+			}
+		}
+	}()
+	return ch, ps.Close, nil
+}
+
+// mgetByIDs 用一次MGET查询一组以id结尾的key,返回值与ids一一对应,未命中的为nil
+func mgetByIDs(ctx context.Context, prefix string, ids []int64) ([]interface{}, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, getRedisKey(prefix+strconv.FormatInt(id, 10)))
+	}
+	return rdb(ctx).MGet(keys...).Result()
+}
@@ -0,0 +1,38 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCacheInvalidated(t *testing.T) {
+	ctx := context.Background()
+	setupMiniredis(t)
+	invalidations, stop, err := SubscribeCacheInvalidated()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := PublishCacheInvalidated(ctx, "community", 42); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-invalidations:
+		if want := (CacheInvalidation{Name: "community", ID: 42}); got != want {
+			t.Fatalf("got %+v, want %+v", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no invalidation received")
+	}
+	if err := stop(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case _, ok := <-invalidations:
+		if ok {
+			t.Fatal("unexpected invalidation after stop")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("invalidations channel not closed after stop")
+	}
+}
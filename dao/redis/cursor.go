@@ -0,0 +1,76 @@
+package redis
+
+import (
+	"bluebell/pkg/cursor"
+	"context"
+	"strconv"
+
+	"github.com/go-redis/redis"
+)
+
+// zRangeByCursor 取出排在游标c之后的size个成员,desc为true时按分数从大到小排列
+// 先取与游标分数相同且排在游标之后的成员,再用开区间取分数严格小于(或大于)游标分数的成员,
+// 这样新写入的数据不会让后面的页发生偏移,也不会出现重复的数据
+// 分数相同的成员由redis按成员的字典序排列,after用来判断这类成员是否排在游标之后
+func zRangeByCursor(ctx context.Context, key string, c *cursor.Cursor, size int64, desc bool, after func(member string) bool) ([]redis.Z, error) {
+	if c == nil {
+		return zRangeByScore(ctx, key, desc, "-inf", "+inf", size)
+	}
+	score := strconv.FormatFloat(c.Score, 'f', -1, 64)
+	// 1. 与游标分数相同的成员
+	ties, err := zRangeByScore(ctx, key, desc, score, score, 0)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]redis.Z, 0, size)
+	for _, z := range ties {
+		if !after(z.Member.(string)) {
+			continue
+		}
+		result = append(result, z)
+		if int64(len(result)) == size {
+			return result, nil
+		}
+	}
+	// 2. 分数严格小于(或大于)游标分数的成员
+	min, max := "("+score, "+inf"
+	if desc {
+		min, max = "-inf", "("+score
+	}
+	rest, err := zRangeByScore(ctx, key, desc, min, max, size-int64(len(result)))
+	if err != nil {
+		return nil, err
+	}
+	return append(result, rest...), nil
+}
+
+// zRangeByScore count为0时不限制数量
+func zRangeByScore(ctx context.Context, key string, desc bool, min, max string, count int64) ([]redis.Z, error) {
+	opt := redis.ZRangeBy{
+		Min:   min,
+		Max:   max,
+		Count: count,
+	}
+	if desc {
+		return rdb(ctx).ZRevRangeByScoreWithScores(key, opt).Result()
+	}
+	return rdb(ctx).ZRangeByScoreWithScores(key, opt).Result()
+}
+
+// nextCursor 根据本页最后一个成员生成下一页的游标,本页不满时说明没有下一页了,返回nil
+func nextCursor(zs []redis.Z, size int64, id func(member string) int64) *cursor.Cursor {
+	if len(zs) == 0 || int64(len(zs)) < size {
+		return nil
+	}
+	last := zs[len(zs)-1]
+	return &cursor.Cursor{
+		Score: last.Score,
+		ID:    id(last.Member.(string)),
+	}
+}
+
+// postIDOf 帖子相关的zset成员就是帖子id
+func postIDOf(member string) int64 {
+	id, _ := strconv.ParseInt(member, 10, 64)
+	return id
+}
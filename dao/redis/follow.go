@@ -0,0 +1,105 @@
+package redis
+
+import (
+	"bluebell/models"
+	"bluebell/pkg/cursor"
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// FollowCommunity 关注社区
+func FollowCommunity(ctx context.Context, userID, communityID int64) error {
+	key := getRedisKey(KeyUserFollowSetPF + strconv.FormatInt(userID, 10))
+	if err := rdb(ctx).SAdd(key, communityID).Err(); err != nil {
+		return err
+	}
+	return deleteFeedKeys(ctx, userID)
+}
+
+// UnfollowCommunity 取消关注社区
+func UnfollowCommunity(ctx context.Context, userID, communityID int64) error {
+	key := getRedisKey(KeyUserFollowSetPF + strconv.FormatInt(userID, 10))
+	if err := rdb(ctx).SRem(key, communityID).Err(); err != nil {
+		return err
+	}
+	return deleteFeedKeys(ctx, userID)
+}
+
+// GetFollowedCommunityIDs 查询用户关注的社区id
+func GetFollowedCommunityIDs(ctx context.Context, userID int64) ([]int64, error) {
+	key := getRedisKey(KeyUserFollowSetPF + strconv.FormatInt(userID, 10))
+	members, err := rdb(ctx).SMembers(key).Result()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(members))
+	for _, m := range members {
+		id, err := strconv.ParseInt(m, 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// GetFollowedPostIDsInOrder 按页码查询用户关注的社区内的帖子id
+func GetFollowedPostIDsInOrder(ctx context.Context, userID int64, p *models.ParamPostList) ([]string, error) {
+	key, err := followedOrderKey(ctx, userID, p.Order)
+	if err != nil || key == "" {
+		return nil, err
+	}
+	return getIDsFormKey(ctx, key, p.Page, p.Size)
+}
+
+// GetFollowedPostIDsByCursor 按游标查询用户关注的社区内排在上一页之后的帖子id,返回下一页的游标
+func GetFollowedPostIDsByCursor(ctx context.Context, userID int64, p *models.ParamPostList, c *cursor.Cursor) ([]string, *cursor.Cursor, error) {
+	key, err := followedOrderKey(ctx, userID, p.Order)
+	if err != nil || key == "" {
+		return nil, nil, err
+	}
+	return getIDsByCursor(ctx, key, c, p.Size)
+}
+
+// followedOrderKey 返回用户关注的所有社区内帖子按时间或分数排序的zset的key,没有关注社区时返回空字符串
+// 与社区的排序zset一样缓存60秒,关注或取消关注时删除缓存
+func followedOrderKey(ctx context.Context, userID int64, order string) (string, error) {
+	key := feedKey(userID, order)
+	if rdb(ctx).Exists(key).Val() > 0 {
+		return key, nil
+	}
+	communityIDs, err := GetFollowedCommunityIDs(ctx, userID)
+	if err != nil || len(communityIDs) == 0 {
+		return "", err
+	}
+	keys := make([]string, 0, len(communityIDs))
+	for _, id := range communityIDs {
+		cKey, err := communityOrderKey(ctx, &models.ParamPostList{CommunityID: id, Order: order})
+		if err != nil {
+			return "", err
+		}
+		keys = append(keys, cKey)
+	}
+	pipeline := rdb(ctx).Pipeline()
+	pipeline.ZUnionStore(key, redis.ZStore{Aggregate: "MAX"}, keys...)
+	pipeline.Expire(key, 60*time.Second)
+	if _, err := pipeline.Exec(); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+func feedKey(userID int64, order string) string {
+	orderKey := KeyPostTimeZSet
+	if order == models.OrderScore {
+		orderKey = KeyPostScoreZSet
+	}
+	return getRedisKey(orderKey + ":follow:" + strconv.FormatInt(userID, 10))
+}
+
+func deleteFeedKeys(ctx context.Context, userID int64) error {
+	return rdb(ctx).Del(feedKey(userID, models.OrderTime), feedKey(userID, models.OrderScore)).Err()
+}
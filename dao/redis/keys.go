@@ -11,6 +11,18 @@ const (
 	KeyPostVotedZSetPF = "post:voted:"   // zset;记录用户及投票类型;参数是post id
 	KeyPostComment     = "post:comment:" //zset:记录用户评论
 	KeyCommunitySetPF  = "community:"    // set;保存每个分区下帖子的id
+
+	KeyCommunityPinnedZSetPF = "community:pinned:" // zset;社区内置顶的帖子及置顶时间;参数是community id
+	KeyCommunityReviewZSetPF = "community:review:" // zset;社区内等待版主审核的帖子及加入时间;参数是community id
+	KeyUserSuspensionPF      = "user:suspension:"  // string;缓存用户的全站封禁状态;参数是user id
+	KeyUserFollowSetPF       = "user:follow:"      // set;用户关注的社区id;参数是user id
+	KeyCacheUsernamePF       = "cache:username:"   // string;缓存用户名;参数是user id
+	KeyCachePostDetailPF     = "cache:post:"       // string;缓存帖子详情的json;参数是post id
+	KeyCacheCommunityPF      = "cache:community:"  // string;缓存社区详情的json;参数是community id
+	KeyLockPF                = "lock:"             // string;分布式锁,值是持有者的token;参数是锁名
+
+	KeyChannelSensitiveWords = "channel:sensitive_words" // pub/sub;敏感词库变化的通知,消息是变化的分类
+	KeyChannelCache          = "channel:cache"           // pub/sub;删除进程内缓存的通知,消息是"缓存名:id"
 )
 
 // 给redis key加上前缀
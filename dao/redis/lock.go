@@ -0,0 +1,51 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// 基于SET NX的分布式锁,锁的值是获取时生成的随机token,只有持有者能续期和释放
+// 锁在ttl之后自动释放,持有者需要在到期之前续期
+
+var (
+	refreshLockScript = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("pexpire", KEYS[1], ARGV[2])
+end
+return 0`)
+	releaseLockScript = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("del", KEYS[1])
+end
+return 0`)
+)
+
+// AcquireLock 尝试获取锁,锁已经被持有时ok为false
+func AcquireLock(ctx context.Context, name string, ttl time.Duration) (token string, ok bool, err error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", false, err
+	}
+	token = hex.EncodeToString(b)
+	ok, err = rdb(ctx).SetNX(getRedisKey(KeyLockPF+name), token, ttl).Result()
+	if err != nil || !ok {
+		return "", false, err
+	}
+	return token, true, nil
+}
+
+// RefreshLock 把锁的过期时间延长到ttl之后,锁已经过期或被其他持有者获取时返回false
+func RefreshLock(ctx context.Context, name, token string, ttl time.Duration) (bool, error) {
+	n, err := refreshLockScript.Run(rdb(ctx), []string{getRedisKey(KeyLockPF + name)}, token, ttl.Milliseconds()).Int64()
+	return n == 1, err
+}
+
+// ReleaseLock 释放锁,锁已经不属于token时什么也不做
+func ReleaseLock(ctx context.Context, name, token string) error {
+	return releaseLockScript.Run(rdb(ctx), []string{getRedisKey(KeyLockPF + name)}, token).Err()
+}
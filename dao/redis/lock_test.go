@@ -0,0 +1,50 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLock(t *testing.T) {
+	ctx := context.Background()
+	mr := setupMiniredis(t)
+	token, ok, err := AcquireLock(ctx, "test", time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("AcquireLock() = %v, %v, want ok", ok, err)
+	}
+	if _, ok, err := AcquireLock(ctx, "test", time.Minute); err != nil || ok {
+		t.Fatalf("AcquireLock() while held = %v, %v, want not ok", ok, err)
+	}
+	if ok, err := RefreshLock(ctx, "test", "other", time.Minute); err != nil || ok {
+		t.Fatalf("RefreshLock() with wrong token = %v, %v, want false", ok, err)
+	}
+	mr.FastForward(30 * time.Second)
+	if ok, err := RefreshLock(ctx, "test", token, time.Minute); err != nil || !ok {
+		t.Fatalf("RefreshLock() = %v, %v, want true", ok, err)
+	}
+	mr.FastForward(45 * time.Second)
+	if !mr.Exists(getRedisKey(KeyLockPF + "test")) {
+		t.Fatal("refreshed lock expired")
+	}
+
+	// 其他持有者的token不能释放锁
+	if err := ReleaseLock(ctx, "test", "other"); err != nil {
+		t.Fatal(err)
+	}
+	if !mr.Exists(getRedisKey(KeyLockPF + "test")) {
+		t.Fatal("lock released with wrong token")
+	}
+	if err := ReleaseLock(ctx, "test", token); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := AcquireLock(ctx, "test", time.Minute); err != nil || !ok {
+		t.Fatalf("AcquireLock() after release = %v, %v, want ok", ok, err)
+	}
+
+	// 持有者没有续期时锁自动过期
+	mr.FastForward(time.Minute)
+	if _, ok, err := AcquireLock(ctx, "test", time.Minute); err != nil || !ok {
+		t.Fatalf("AcquireLock() after expiry = %v, %v, want ok", ok, err)
+	}
+}
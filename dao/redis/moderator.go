@@ -0,0 +1,86 @@
+package redis
+
+import (
+	"bluebell/models"
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// PinPost 在社区内置顶帖子,分数为置顶时间
+func PinPost(ctx context.Context, communityID, postID int64) error {
+	key := getRedisKey(KeyCommunityPinnedZSetPF + strconv.FormatInt(communityID, 10))
+	return rdb(ctx).ZAdd(key, redis.Z{
+		Score:  float64(time.Now().Unix()),
+		Member: postID,
+	}).Err()
+}
+
+// UnpinPost 取消社区内帖子的置顶
+func UnpinPost(ctx context.Context, communityID, postID int64) error {
+	key := getRedisKey(KeyCommunityPinnedZSetPF + strconv.FormatInt(communityID, 10))
+	return rdb(ctx).ZRem(key, strconv.FormatInt(postID, 10)).Err()
+}
+
+// GetPinnedPostIDs 按置顶时间从新到旧查询社区内置顶的帖子id
+func GetPinnedPostIDs(ctx context.Context, communityID int64) ([]string, error) {
+	key := getRedisKey(KeyCommunityPinnedZSetPF + strconv.FormatInt(communityID, 10))
+	return rdb(ctx).ZRevRange(key, 0, -1).Result()
+}
+
+// RemoveComment 删除帖子下指定id的评论,返回是否找到了该评论
+func RemoveComment(ctx context.Context, postID, commentID int64) (bool, error) {
+	key := getRedisKey(KeyPostComment + strconv.FormatInt(postID, 10))
+	data, err := rdb(ctx).ZRange(key, 0, -1).Result()
+	if err != nil {
+		return false, err
+	}
+	// 评论以json序列化后的字符串作为成员保存,需要逐个反序列化找到对应的成员
+	for _, item := range data {
+		var comment models.Comment
+		if err := json.Unmarshal([]byte(item), &comment); err != nil {
+			continue
+		}
+		if comment.CommentID == commentID {
+			return true, rdb(ctx).ZRem(key, item).Err()
+		}
+	}
+	return false, nil
+}
+
+// AddPostReview 把帖子加入社区的审核队列,分数为加入时间,重复加入时保留第一次的时间
+func AddPostReview(ctx context.Context, communityID, postID int64) error {
+	key := getRedisKey(KeyCommunityReviewZSetPF + strconv.FormatInt(communityID, 10))
+	return rdb(ctx).ZAddNX(key, redis.Z{
+		Score:  float64(time.Now().Unix()),
+		Member: postID,
+	}).Err()
+}
+
+// RemovePostReview 把帖子移出社区的审核队列,返回帖子是否在队列中
+func RemovePostReview(ctx context.Context, communityID, postID int64) (bool, error) {
+	key := getRedisKey(KeyCommunityReviewZSetPF + strconv.FormatInt(communityID, 10))
+	n, err := rdb(ctx).ZRem(key, strconv.FormatInt(postID, 10)).Result()
+	return n > 0, err
+}
+
+// GetReviewPostIDs 按加入时间从早到晚查询社区审核队列中的帖子id
+func GetReviewPostIDs(ctx context.Context, communityID int64) ([]int64, error) {
+	key := getRedisKey(KeyCommunityReviewZSetPF + strconv.FormatInt(communityID, 10))
+	members, err := rdb(ctx).ZRange(key, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(members))
+	for _, m := range members {
+		id, err := strconv.ParseInt(m, 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,44 @@
+package redis
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPostReviewQueue(t *testing.T) {
+	ctx := context.Background()
+	setupMiniredis(t)
+	for _, id := range []int64{1, 2, 3} {
+		if err := AddPostReview(ctx, 1, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := AddPostReview(ctx, 2, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := RemovePostReview(ctx, 1, 2)
+	if err != nil || !found {
+		t.Fatalf("RemovePostReview() = %v, %v, want true", found, err)
+	}
+	if found, _ := RemovePostReview(ctx, 1, 2); found {
+		t.Fatal("RemovePostReview() twice = true, want false")
+	}
+	// 删除帖子时同时移出审核队列
+	if err := AddPostToIndex(ctx, 3, 1, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := RemovePostFromIndex(ctx, 3, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := GetReviewPostIDs(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int64{1}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("GetReviewPostIDs() = %v, want %v", ids, want)
+	}
+}
@@ -1,7 +1,10 @@
 package redis
 
 import (
+	"bluebell/logger"
 	"bluebell/models"
+	"bluebell/pkg/cursor"
+	"context"
 	"encoding/json"
 	"strconv"
 	"time"
@@ -11,14 +14,14 @@ import (
 	"github.com/go-redis/redis"
 )
 
-func getIDsFormKey(key string, page, size int64) ([]string, error) {
+func getIDsFormKey(ctx context.Context, key string, page, size int64) ([]string, error) {
 	start := (page - 1) * size
 	end := start + size - 1
 	// 3. ZREVRANGE 按分数从大到小的顺序查询指定数量的元素
-	return client.ZRevRange(key, start, end).Result()
+	return rdb(ctx).ZRevRange(key, start, end).Result()
 }
 
-func GetPostIDsInOrder(p *models.ParamPostList) ([]string, error) {
+func GetPostIDsInOrder(ctx context.Context, p *models.ParamPostList) ([]string, error) {
 	// 从redis获取id
 	// 1. 根据用户请求中携带的order参数确定要查询的redis key
 	key := getRedisKey(KeyPostTimeZSet)
@@ -26,20 +29,20 @@ func GetPostIDsInOrder(p *models.ParamPostList) ([]string, error) {
 		key = getRedisKey(KeyPostScoreZSet)
 	}
 	// 2. 确定查询的索引起始点
-	return getIDsFormKey(key, p.Page, p.Size)
+	return getIDsFormKey(ctx, key, p.Page, p.Size)
 }
 
 // GetPostVoteData 根据ids查询每篇帖子的投赞成票的数据
-func GetPostVoteData(ids []string) (data []int64, err error) {
+func GetPostVoteData(ctx context.Context, ids []string) (data []int64, err error) {
 	//data = make([]int64, 0, len(ids))
 	//for _, id := range ids {
 	//	key := getRedisKey(KeyPostVotedZSetPF + id)
 	//	// 查找key中分数是1的元素的数量->统计每篇帖子的赞成票的数量
-	//	v := client.ZCount(key, "1", "1").Val()
+	//	v := rdb(ctx).ZCount(key, "1", "1").Val()
 	//	data = append(data, v)
 	//}
 	// 使用pipeline一次发送多条命令,减少RTT
-	pipeline := client.Pipeline()
+	pipeline := rdb(ctx).Pipeline()
 	for _, id := range ids {
 		key := getRedisKey(KeyPostVotedZSetPF + id)
 		pipeline.ZCount(key, "1", "1")
@@ -57,7 +60,17 @@ func GetPostVoteData(ids []string) (data []int64, err error) {
 }
 
 // GetCommunityPostIDsInOrder 按社区查询ids
-func GetCommunityPostIDsInOrder(p *models.ParamPostList) ([]string, error) {
+func GetCommunityPostIDsInOrder(ctx context.Context, p *models.ParamPostList) ([]string, error) {
+	key, err := communityOrderKey(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	// 存在的话就直接根据key查询ids
+	return getIDsFormKey(ctx, key, p.Page, p.Size)
+}
+
+// communityOrderKey 返回社区内帖子按时间或分数排序的zset的key
+func communityOrderKey(ctx context.Context, p *models.ParamPostList) (string, error) {
 	orderKey := getRedisKey(KeyPostTimeZSet)
 	if p.Order == models.OrderScore {
 		orderKey = getRedisKey(KeyPostScoreZSet)
@@ -68,43 +81,78 @@ func GetCommunityPostIDsInOrder(p *models.ParamPostList) ([]string, error) {
 	cKey := getRedisKey(KeyCommunitySetPF + strconv.Itoa(int(p.CommunityID)))
 	// 利用缓存key减少zinterstore执行的次数
 	key := orderKey + strconv.Itoa(int(p.CommunityID))
-	if client.Exists(key).Val() < 1 {
+	if rdb(ctx).Exists(key).Val() < 1 {
 		// 不存在，需要计算
-		pipeline := client.Pipeline()
+		pipeline := rdb(ctx).Pipeline()
 		pipeline.ZInterStore(key, redis.ZStore{
 			Aggregate: "MAX",
 		}, cKey, orderKey) // zinterstore 计算
 		pipeline.Expire(key, 60*time.Second) // 设置超时时间
 		_, err := pipeline.Exec()
 		if err != nil {
-			return nil, err
+			return "", err
 		}
 	}
-	// 存在的话就直接根据key查询ids
-	return getIDsFormKey(key, p.Page, p.Size)
+	return key, nil
+}
+
+// GetPostIDsByCursor 按游标查询排在上一页之后的帖子id,返回下一页的游标
+func GetPostIDsByCursor(ctx context.Context, p *models.ParamPostList, c *cursor.Cursor) ([]string, *cursor.Cursor, error) {
+	key := getRedisKey(KeyPostTimeZSet)
+	if p.Order == models.OrderScore {
+		key = getRedisKey(KeyPostScoreZSet)
+	}
+	return getIDsByCursor(ctx, key, c, p.Size)
+}
+
+// GetCommunityPostIDsByCursor 按游标查询社区内排在上一页之后的帖子id,返回下一页的游标
+func GetCommunityPostIDsByCursor(ctx context.Context, p *models.ParamPostList, c *cursor.Cursor) ([]string, *cursor.Cursor, error) {
+	key, err := communityOrderKey(ctx, p)
+	if err != nil {
+		return nil, nil, err
+	}
+	return getIDsByCursor(ctx, key, c, p.Size)
+}
+
+func getIDsByCursor(ctx context.Context, key string, c *cursor.Cursor, size int64) ([]string, *cursor.Cursor, error) {
+	// ZREVRANGEBYSCORE 分数相同的成员按字典序从大到小排列
+	var after func(string) bool
+	if c != nil {
+		last := strconv.FormatInt(c.ID, 10)
+		after = func(member string) bool { return member < last }
+	}
+	zs, err := zRangeByCursor(ctx, key, c, size, true, after)
+	if err != nil {
+		return nil, nil, err
+	}
+	ids := make([]string, 0, len(zs))
+	for _, z := range zs {
+		ids = append(ids, z.Member.(string))
+	}
+	return ids, nextCursor(zs, size, postIDOf), nil
 }
 
-func AddComment(comment *models.Comment) error {
+func AddComment(ctx context.Context, comment *models.Comment) error {
 	key := getRedisKey(KeyPostComment + strconv.FormatInt(comment.ID, 10))
 	// 序列化评论对象
 	data, err := json.Marshal(comment)
 	if err != nil {
-		zap.L().Error("AddComment json.Marshal(comment) error", zap.Error(err))
+		logger.FromContext(ctx).Error("AddComment json.Marshal(comment) error", zap.Error(err))
 		return err
 	}
 	// 将评论存储到Redis中，使用有序集合存储，以评论时间为分数
-	if _, err := client.ZAdd(key, redis.Z{Score: float64(comment.Time), Member: data}).Result(); err != nil {
-		zap.L().Error("AddComment client.ZAdd error", zap.Error(err))
+	if _, err := rdb(ctx).ZAdd(key, redis.Z{Score: float64(comment.Time), Member: data}).Result(); err != nil {
+		logger.FromContext(ctx).Error("AddComment client.ZAdd error", zap.Error(err))
 		return err
 	}
 	return nil
 }
-func GetComments(postID int64) ([]*models.Comment, error) {
+func GetComments(ctx context.Context, postID int64) ([]*models.Comment, error) {
 	key := getRedisKey(KeyPostComment + strconv.FormatInt(postID, 10))
 	// 获取有序集合中的所有成员
-	data, err := client.ZRange(key, 0, -1).Result()
+	data, err := rdb(ctx).ZRange(key, 0, -1).Result()
 	if err != nil {
-		zap.L().Error("GetComments client.ZRange error", zap.Error(err))
+		logger.FromContext(ctx).Error("GetComments client.ZRange error", zap.Error(err))
 		return nil, err
 	}
 
@@ -113,7 +161,7 @@ func GetComments(postID int64) ([]*models.Comment, error) {
 	for _, item := range data {
 		var comment models.Comment
 		if err := json.Unmarshal([]byte(item), &comment); err != nil {
-			zap.L().Error("GetComments json.Unmarshal error", zap.Error(err))
+			logger.FromContext(ctx).Error("GetComments json.Unmarshal error", zap.Error(err))
 			continue
 		}
 		comments = append(comments, &comment)
@@ -121,3 +169,83 @@ func GetComments(postID int64) ([]*models.Comment, error) {
 
 	return comments, nil
 }
+
+// GetCommentsByCursor 按评论时间从早到晚查询排在上一页之后的评论,返回下一页的游标
+func GetCommentsByCursor(ctx context.Context, postID int64, c *cursor.Cursor, size int64) ([]*models.Comment, *cursor.Cursor, error) {
+	key := getRedisKey(KeyPostComment + strconv.FormatInt(postID, 10))
+	// 同一秒内的评论redis按json的字典序排列,旧的评论没有comment_id(都是0),不能按评论id区分先后
+	// 所以游标中保存上一页最后一条评论的json,跟redis一样按字典序比较
+	var after func(string) bool
+	if c != nil {
+		last := c.Member
+		after = func(member string) bool { return member > last }
+		if last == "" {
+			// 兼容只有评论id的旧游标
+			after = func(member string) bool { return commentIDOf(member) > c.ID }
+		}
+	}
+	zs, err := zRangeByCursor(ctx, key, c, size, false, after)
+	if err != nil {
+		logger.FromContext(ctx).Error("GetCommentsByCursor zRangeByCursor error", zap.Error(err))
+		return nil, nil, err
+	}
+	comments := make([]*models.Comment, 0, len(zs))
+	for _, z := range zs {
+		var comment models.Comment
+		if err := json.Unmarshal([]byte(z.Member.(string)), &comment); err != nil {
+			logger.FromContext(ctx).Error("GetCommentsByCursor json.Unmarshal error", zap.Error(err))
+			continue
+		}
+		comments = append(comments, &comment)
+	}
+	next := nextCursor(zs, size, commentIDOf)
+	if next != nil {
+		next.Member = zs[len(zs)-1].Member.(string)
+	}
+	return comments, next, nil
+}
+
+// commentIDOf 从评论的json中取出评论id
+func commentIDOf(member string) int64 {
+	var comment models.Comment
+	if err := json.Unmarshal([]byte(member), &comment); err != nil {
+		return 0
+	}
+	return comment.CommentID
+}
+
+// GetUserVotes 查询用户给每篇帖子投的票,没有投票时为0
+func GetUserVotes(ctx context.Context, userID int64, ids []string) (data []int8, err error) {
+	member := strconv.FormatInt(userID, 10)
+	pipeline := rdb(ctx).Pipeline()
+	for _, id := range ids {
+		pipeline.ZScore(getRedisKey(KeyPostVotedZSetPF+id), member)
+	}
+	cmders, err := pipeline.Exec()
+	// 没有投票的帖子返回redis.Nil,不算错误
+	if err != nil && err != Nil {
+		return nil, err
+	}
+	data = make([]int8, 0, len(cmders))
+	for _, cmder := range cmders {
+		data = append(data, int8(cmder.(*redis.FloatCmd).Val()))
+	}
+	return data, nil
+}
+
+// GetPostCommentCounts 查询每篇帖子的评论数
+func GetPostCommentCounts(ctx context.Context, ids []string) (data []int64, err error) {
+	pipeline := rdb(ctx).Pipeline()
+	for _, id := range ids {
+		pipeline.ZCard(getRedisKey(KeyPostComment + id))
+	}
+	cmders, err := pipeline.Exec()
+	if err != nil {
+		return nil, err
+	}
+	data = make([]int64, 0, len(cmders))
+	for _, cmder := range cmders {
+		data = append(data, cmder.(*redis.IntCmd).Val())
+	}
+	return
+}
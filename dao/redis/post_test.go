@@ -0,0 +1,201 @@
+package redis
+
+import (
+	"bluebell/models"
+	"bluebell/pkg/cursor"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testTime = time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local)
+
+// addTestPosts 帖子1-3属于社区1,4-5属于社区2;帖子2和3的发帖时间相同
+func addTestPosts(t *testing.T) {
+	t.Helper()
+	ctx := context.Background()
+	posts := []struct {
+		id, communityID int64
+		createTime      time.Time
+	}{
+		{1, 1, testTime},
+		{2, 1, testTime.Add(time.Hour)},
+		{3, 1, testTime.Add(time.Hour)},
+		{4, 2, testTime.Add(2 * time.Hour)},
+		{5, 2, testTime.Add(3 * time.Hour)},
+	}
+	for _, p := range posts {
+		if err := AddPostToIndex(ctx, p.id, p.communityID, p.createTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// collectPages 按游标翻页直到没有下一页,返回每一页的帖子id
+func collectPages(t *testing.T, list func(c *cursor.Cursor) ([]string, *cursor.Cursor, error)) [][]string {
+	t.Helper()
+	var pages [][]string
+	var c *cursor.Cursor
+	for i := 0; i < 10; i++ {
+		ids, next, err := list(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, ids)
+		if next == nil {
+			return pages
+		}
+		c = next
+	}
+	t.Fatal("too many pages")
+	return nil
+}
+
+func TestGetPostIDsByCursor(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name  string
+		setup func()
+		p     models.ParamPostList
+		want  [][]string
+	}{
+		{
+			name: "by time",
+			p:    models.ParamPostList{Size: 2, Order: models.OrderTime},
+			// 发帖时间相同的帖子按id倒序
+			want: [][]string{{"5", "4"}, {"3", "2"}, {"1"}},
+		},
+		{
+			name: "by score",
+			setup: func() {
+				// 帖子1比帖子5早3小时,30票(12960秒)让它排到最前面
+				_ = SaveVote(ctx, "7", "1", 1, 30*ScorePerVote)
+			},
+			p:    models.ParamPostList{Size: 3, Order: models.OrderScore},
+			want: [][]string{{"1", "5", "4"}, {"3", "2"}},
+		},
+		{
+			name: "community",
+			p:    models.ParamPostList{Size: 2, CommunityID: 1, Order: models.OrderTime},
+			want: [][]string{{"3", "2"}, {"1"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupMiniredis(t)
+			addTestPosts(t)
+			if tt.setup != nil {
+				tt.setup()
+			}
+			p := tt.p
+			got := collectPages(t, func(c *cursor.Cursor) ([]string, *cursor.Cursor, error) {
+				if p.CommunityID != 0 {
+					return GetCommunityPostIDsByCursor(ctx, &p, c)
+				}
+				return GetPostIDsByCursor(ctx, &p, c)
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("pages = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursorStableAfterNewPost(t *testing.T) {
+	ctx := context.Background()
+	setupMiniredis(t)
+	addTestPosts(t)
+	p := &models.ParamPostList{Size: 2, Order: models.OrderTime}
+	_, c, err := GetPostIDsByCursor(ctx, p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 翻页期间发布的新帖子不会让后面的页发生偏移
+	if err := AddPostToIndex(ctx, 6, 1, testTime.Add(4*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	ids, _, err := GetPostIDsByCursor(ctx, p, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"3", "2"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("second page = %v, want %v", ids, want)
+	}
+}
+
+func TestGetFollowedPostIDsByCursor(t *testing.T) {
+	ctx := context.Background()
+	setupMiniredis(t)
+	addTestPosts(t)
+	p := &models.ParamPostList{Size: 2, Order: models.OrderTime}
+
+	// 没有关注社区
+	ids, next, err := GetFollowedPostIDsByCursor(ctx, 7, p, nil)
+	if err != nil || len(ids) != 0 || next != nil {
+		t.Fatalf("no follow: ids = %v, next = %v, err = %v", ids, next, err)
+	}
+
+	if err := FollowCommunity(ctx, 7, 2); err != nil {
+		t.Fatal(err)
+	}
+	got := collectPages(t, func(c *cursor.Cursor) ([]string, *cursor.Cursor, error) {
+		return GetFollowedPostIDsByCursor(ctx, 7, p, c)
+	})
+	if want := [][]string{{"5", "4"}, {}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("pages = %v, want %v", got, want)
+	}
+
+	// 关注新社区后删除缓存的feed
+	if err := FollowCommunity(ctx, 7, 1); err != nil {
+		t.Fatal(err)
+	}
+	ids, _, err = GetFollowedPostIDsByCursor(ctx, 7, p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"5", "4"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("first page = %v, want %v", ids, want)
+	}
+	all := &models.ParamPostList{Size: 10, Order: models.OrderTime}
+	ids, _, _ = GetFollowedPostIDsByCursor(ctx, 7, all, nil)
+	if len(ids) != 5 {
+		t.Fatalf("followed posts = %v, want 5 posts", ids)
+	}
+}
+
+func TestGetCommentsByCursor(t *testing.T) {
+	ctx := context.Background()
+	setupMiniredis(t)
+	// 旧的评论没有comment_id,同一秒内的评论只能按json区分先后
+	comments := []*models.Comment{
+		{ID: 1, UserID: 7, Content: "a", Time: 100},
+		{ID: 1, UserID: 7, Content: "b", Time: 100},
+		{ID: 1, UserID: 8, Content: "c", Time: 100},
+		{ID: 1, CommentID: 11, UserID: 7, Content: "d", Time: 100},
+		{ID: 1, CommentID: 12, UserID: 7, Content: "e", Time: 101},
+	}
+	for _, c := range comments {
+		if err := AddComment(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got []string
+	var c *cursor.Cursor
+	for i := 0; i < 10; i++ {
+		page, next, err := GetCommentsByCursor(ctx, 1, c, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, comment := range page {
+			got = append(got, comment.Content)
+		}
+		if next == nil {
+			break
+		}
+		c = next
+	}
+	if want := []string{"a", "b", "c", "d", "e"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("comments = %v, want %v", got, want)
+	}
+}
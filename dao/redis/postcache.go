@@ -0,0 +1,29 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// GetCachedPostDetail 查询缓存的帖子详情,ok为false表示缓存未命中
+func GetCachedPostDetail(ctx context.Context, postID int64) (val string, ok bool, err error) {
+	val, err = rdb(ctx).Get(getRedisKey(KeyCachePostDetailPF + strconv.FormatInt(postID, 10))).Result()
+	if err == Nil {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return val, true, nil
+}
+
+// SetCachedPostDetail 缓存帖子详情
+func SetCachedPostDetail(ctx context.Context, postID int64, val string, ttl time.Duration) error {
+	return rdb(ctx).Set(getRedisKey(KeyCachePostDetailPF+strconv.FormatInt(postID, 10)), val, ttl).Err()
+}
+
+// DeleteCachedPostDetail 帖子变化时删除缓存
+func DeleteCachedPostDetail(ctx context.Context, postID int64) error {
+	return rdb(ctx).Del(getRedisKey(KeyCachePostDetailPF + strconv.FormatInt(postID, 10))).Err()
+}
@@ -0,0 +1,43 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// 帖子变更事件会被重试,这里的操作重复执行的结果必须相同
+
+// AddPostToIndex 把帖子加入时间、分数排序及社区的索引
+// 分数只在帖子第一次加入时初始化为发帖时间,重复执行不会覆盖投票产生的分数
+func AddPostToIndex(ctx context.Context, postID, communityID int64, createTime time.Time) error {
+	pipeline := rdb(ctx).TxPipeline()
+	pipeline.ZAdd(getRedisKey(KeyPostTimeZSet), redis.Z{
+		Score:  float64(createTime.Unix()),
+		Member: postID,
+	})
+	pipeline.ZAddNX(getRedisKey(KeyPostScoreZSet), redis.Z{
+		Score:  float64(createTime.Unix()),
+		Member: postID,
+	})
+	pipeline.SAdd(getRedisKey(KeyCommunitySetPF+strconv.FormatInt(communityID, 10)), postID)
+	_, err := pipeline.Exec()
+	return err
+}
+
+// RemovePostFromIndex 把帖子从所有索引中移除,并删除帖子的投票及评论
+func RemovePostFromIndex(ctx context.Context, postID, communityID int64) error {
+	id := strconv.FormatInt(postID, 10)
+	cid := strconv.FormatInt(communityID, 10)
+	pipeline := rdb(ctx).TxPipeline()
+	pipeline.ZRem(getRedisKey(KeyPostTimeZSet), id)
+	pipeline.ZRem(getRedisKey(KeyPostScoreZSet), id)
+	pipeline.SRem(getRedisKey(KeyCommunitySetPF+cid), id)
+	pipeline.ZRem(getRedisKey(KeyCommunityPinnedZSetPF+cid), id)
+	pipeline.ZRem(getRedisKey(KeyCommunityReviewZSetPF+cid), id)
+	pipeline.Del(getRedisKey(KeyPostVotedZSetPF+id), getRedisKey(KeyPostComment+id))
+	_, err := pipeline.Exec()
+	return err
+}
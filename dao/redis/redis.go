@@ -1,7 +1,9 @@
 package redis
 
 import (
+	"bluebell/pkg/metrics"
 	"bluebell/setting"
+	"context"
 	"fmt"
 
 	"github.com/go-redis/redis"
@@ -26,9 +28,15 @@ func Init(cfg *setting.RedisConfig) (err error) {
 	if err != nil {
 		return err
 	}
+	metrics.Register(metrics.NewRedisPoolCollector(client.PoolStats))
 	return nil
 }
 
 func Close() {
 	_ = client.Close()
 }
+
+// Ping 检查redis连接是否可用
+func Ping(ctx context.Context) error {
+	return rdb(ctx).WithContext(ctx).Ping().Err()
+}
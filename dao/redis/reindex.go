@@ -0,0 +1,145 @@
+package redis
+
+import (
+	"bluebell/models"
+	"context"
+	"strconv"
+
+	"github.com/go-redis/redis"
+)
+
+// 从MySQL重建redis索引时使用
+
+// PostIndexState 帖子在redis索引中的状态
+type PostIndexState struct {
+	TimeScore   float64 // 时间zset中的分数
+	HasTime     bool    // 是否在时间zset中
+	HasScore    bool    // 是否在分数zset中
+	InCommunity bool    // 是否在社区set中
+	UpVotes     int64   // 投票记录中的赞成票数
+	DownVotes   int64   // 投票记录中的反对票数
+}
+
+// GetPostIndexStates 批量查询帖子在redis索引中的状态,返回值与posts一一对应
+func GetPostIndexStates(ctx context.Context, posts []*models.Post) ([]*PostIndexState, error) {
+	pipeline := rdb(ctx).Pipeline()
+	for _, p := range posts {
+		id := strconv.FormatInt(p.ID, 10)
+		pipeline.ZScore(getRedisKey(KeyPostTimeZSet), id)
+		pipeline.ZScore(getRedisKey(KeyPostScoreZSet), id)
+		pipeline.SIsMember(getRedisKey(KeyCommunitySetPF+strconv.FormatInt(p.CommunityID, 10)), id)
+		pipeline.ZCount(getRedisKey(KeyPostVotedZSetPF+id), "1", "1")
+		pipeline.ZCount(getRedisKey(KeyPostVotedZSetPF+id), "-1", "-1")
+	}
+	cmders, err := pipeline.Exec()
+	// 不在zset中的成员返回redis.Nil,不算错误
+	if err != nil && err != Nil {
+		return nil, err
+	}
+	states := make([]*PostIndexState, 0, len(posts))
+	for i := 0; i+4 < len(cmders); i += 5 {
+		timeCmd := cmders[i].(*redis.FloatCmd)
+		s := &PostIndexState{
+			TimeScore:   timeCmd.Val(),
+			HasTime:     timeCmd.Err() == nil,
+			HasScore:    cmders[i+1].Err() == nil,
+			InCommunity: cmders[i+2].(*redis.BoolCmd).Val(),
+			UpVotes:     cmders[i+3].(*redis.IntCmd).Val(),
+			DownVotes:   cmders[i+4].(*redis.IntCmd).Val(),
+		}
+		states = append(states, s)
+	}
+	return states, nil
+}
+
+// RebuildPostIndex 批量把帖子写入索引,已经在分数zset中的帖子保留原来的分数
+func RebuildPostIndex(ctx context.Context, entries []*models.PostIndexEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	pipeline := rdb(ctx).Pipeline()
+	for _, e := range entries {
+		pipeline.ZAdd(getRedisKey(KeyPostTimeZSet), redis.Z{
+			Score:  float64(e.CreateTime.Unix()),
+			Member: e.PostID,
+		})
+		pipeline.ZAddNX(getRedisKey(KeyPostScoreZSet), redis.Z{
+			Score:  e.Score,
+			Member: e.PostID,
+		})
+		pipeline.SAdd(getRedisKey(KeyCommunitySetPF+strconv.FormatInt(e.CommunityID, 10)), e.PostID)
+	}
+	_, err := pipeline.Exec()
+	return err
+}
+
+// PostIndexKeys 返回所有需要检查的帖子索引,communityIDs为所有社区的id
+// 返回值的key为redis key,value为社区id,时间和分数zset的社区id为0
+func PostIndexKeys(communityIDs []int64) map[string]int64 {
+	keys := map[string]int64{
+		getRedisKey(KeyPostTimeZSet):  0,
+		getRedisKey(KeyPostScoreZSet): 0,
+	}
+	for _, id := range communityIDs {
+		keys[getRedisKey(KeyCommunitySetPF+strconv.FormatInt(id, 10))] = id
+	}
+	return keys
+}
+
+// ScanIndexMembers 分批遍历帖子索引(zset或set)中的成员
+func ScanIndexMembers(ctx context.Context, key string, fn func(members []string) error) error {
+	typ, err := rdb(ctx).Type(key).Result()
+	if err != nil {
+		return err
+	}
+	var cursor uint64
+	for {
+		var (
+			keys []string
+			err  error
+		)
+		if typ == "zset" {
+			keys, cursor, err = rdb(ctx).ZScan(key, cursor, "", 500).Result()
+			// ZSCAN返回的是成员和分数交替排列的列表
+			members := make([]string, 0, len(keys)/2)
+			for i := 0; i < len(keys); i += 2 {
+				members = append(members, keys[i])
+			}
+			keys = members
+		} else if typ == "set" {
+			keys, cursor, err = rdb(ctx).SScan(key, cursor, "", 500).Result()
+		} else {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// RemoveIndexMembers 从帖子索引(zset或set)中删除成员
+func RemoveIndexMembers(ctx context.Context, key string, members []string) error {
+	if len(members) == 0 {
+		return nil
+	}
+	typ, err := rdb(ctx).Type(key).Result()
+	if err != nil {
+		return err
+	}
+	args := make([]interface{}, 0, len(members))
+	for _, m := range members {
+		args = append(args, m)
+	}
+	if typ == "zset" {
+		return rdb(ctx).ZRem(key, args...).Err()
+	}
+	return rdb(ctx).SRem(key, args...).Err()
+}
@@ -0,0 +1,32 @@
+package redis
+
+import "context"
+
+// PublishSensitiveWordsChanged 通知所有实例分类中的敏感词发生了变化
+func PublishSensitiveWordsChanged(ctx context.Context, category string) error {
+	return rdb(ctx).Publish(getRedisKey(KeyChannelSensitiveWords), category).Err()
+}
+
+// SubscribeSensitiveWordsChanged 订阅敏感词库变化的通知,返回变化的分类
+// 还没有被读取的通知只保留一个,后来的通知被丢弃,订阅方收到通知后应该加载整个词库
+// 调用stop取消订阅后返回的channel被关闭
+// 连接断开期间的通知会丢失,go-redis 重新连接后自动恢复订阅,订阅方需要定期全量加载作为补偿
+func SubscribeSensitiveWordsChanged() (changes <-chan string, stop func() error, err error) {
+	ps := client.Subscribe(getRedisKey(KeyChannelSensitiveWords))
+	// 等待订阅确认,这样返回之后发布的通知都能收到
+	if _, err := ps.Receive(); err != nil {
+		_ = ps.Close()
+		return nil, nil, err
+	}
+	ch := make(chan string, 1)
+	go func() {
+		defer close(ch)
+		for msg := range ps.Channel() {
+			select {
+			case ch <- msg.Payload:
+			default:
+			}
+		}
+	}()
+	return ch, ps.Close, nil
+}
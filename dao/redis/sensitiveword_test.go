@@ -0,0 +1,48 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSensitiveWordsChanged(t *testing.T) {
+	ctx := context.Background()
+	setupMiniredis(t)
+	changes, stop, err := SubscribeSensitiveWordsChanged()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := PublishSensitiveWordsChanged(ctx, "广告"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case category := <-changes:
+		if category != "广告" {
+			t.Fatalf("got change of %q, want 广告", category)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change notification received")
+	}
+
+	// 没有读取的通知合并成一个,不会阻塞订阅
+	for i := 0; i < 3; i++ {
+		if err := PublishSensitiveWordsChanged(ctx, "网址"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := stop(); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-changes:
+			if !ok {
+				return
+			}
+		case <-deadline:
+			t.Fatal("changes channel not closed after stop")
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package redis
+
+import (
+	"bluebell/models"
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+const (
+	suspensionCacheTTL  = 10 * time.Minute
+	suspensionNoneValue = "none" // 缓存"没有被封禁"的状态,避免每次请求都查询MySQL
+)
+
+// GetCachedSuspension 从缓存中查询用户的封禁状态
+// cached为false表示缓存未命中;cached为true且s为nil表示用户没有被封禁
+func GetCachedSuspension(ctx context.Context, userID int64) (s *models.UserSuspension, cached bool, err error) {
+	key := getRedisKey(KeyUserSuspensionPF + strconv.FormatInt(userID, 10))
+	val, err := rdb(ctx).Get(key).Result()
+	if err == Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if val == suspensionNoneValue {
+		return nil, true, nil
+	}
+	s = new(models.UserSuspension)
+	if err := json.Unmarshal([]byte(val), s); err != nil {
+		return nil, false, err
+	}
+	return s, true, nil
+}
+
+// SetCachedSuspension 缓存用户的封禁状态,s为nil表示用户没有被封禁
+// 有期限的封禁在到期时缓存也随之过期
+func SetCachedSuspension(ctx context.Context, userID int64, s *models.UserSuspension) error {
+	key := getRedisKey(KeyUserSuspensionPF + strconv.FormatInt(userID, 10))
+	if s == nil {
+		return rdb(ctx).Set(key, suspensionNoneValue, suspensionCacheTTL).Err()
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	ttl := suspensionCacheTTL
+	if s.ExpireTime != nil {
+		if left := time.Until(*s.ExpireTime); left < ttl {
+			ttl = left
+		}
+	}
+	if ttl <= 0 {
+		return nil
+	}
+	return rdb(ctx).Set(key, data, ttl).Err()
+}
+
+// DeleteCachedSuspension 封禁状态变化时删除缓存
+func DeleteCachedSuspension(ctx context.Context, userID int64) error {
+	key := getRedisKey(KeyUserSuspensionPF + strconv.FormatInt(userID, 10))
+	return rdb(ctx).Del(key).Err()
+}
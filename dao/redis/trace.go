@@ -0,0 +1,74 @@
+package redis
+
+import (
+	"bluebell/pkg/tracing"
+	"context"
+	"strings"
+
+	"github.com/go-redis/redis"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// rdb 返回绑定了ctx的客户端,每个命令或pipeline生成一个span
+// go-redis v6 的命令不带context,所以每次调用复制一个客户端并把ctx包在钩子里,连接池是共用的
+func rdb(ctx context.Context) *redis.Client {
+	c := client.WithContext(ctx)
+	if !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+		return c
+	}
+	c.WrapProcess(func(old func(redis.Cmder) error) func(redis.Cmder) error {
+		return func(cmd redis.Cmder) error {
+			_, span := startSpan(ctx, "redis."+cmd.Name(), cmdText(cmd))
+			err := old(cmd)
+			endSpan(span, err)
+			return err
+		}
+	})
+	c.WrapProcessPipeline(func(old func([]redis.Cmder) error) func([]redis.Cmder) error {
+		return func(cmds []redis.Cmder) error {
+			texts := make([]string, 0, len(cmds))
+			for _, cmd := range cmds {
+				texts = append(texts, cmdText(cmd))
+			}
+			_, span := startSpan(ctx, "redis.pipeline", strings.Join(texts, "\n"))
+			span.SetAttributes(attribute.Int("db.redis.pipeline_length", len(cmds)))
+			err := old(cmds)
+			endSpan(span, err)
+			return err
+		}
+	})
+	return c
+}
+
+func startSpan(ctx context.Context, name, text string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemRedis,
+			semconv.DBQueryText(text),
+		))
+}
+
+// endSpan 查询的key不存在(redis.Nil)不算错误
+func endSpan(span trace.Span, err error) {
+	if err != nil && err != Nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// cmdText 只记录命令名和key,不记录写入的值(如缓存的json)
+func cmdText(cmd redis.Cmder) string {
+	args := cmd.Args()
+	if len(args) < 2 {
+		return cmd.Name()
+	}
+	if key, ok := args[1].(string); ok {
+		return cmd.Name() + " " + key
+	}
+	return cmd.Name()
+}
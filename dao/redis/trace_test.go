@@ -0,0 +1,57 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestRedisSpans(t *testing.T) {
+	setupMiniredis(t)
+	sr := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	// 没有上层span时不生成span
+	if err := rdb(context.Background()).Set("k", "v", 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(sr.Ended()); n != 0 {
+		t.Fatalf("len(spans) = %d, want 0 without parent span", n)
+	}
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	if err := rdb(ctx).Get("missing").Err(); err != Nil {
+		t.Fatalf("err = %v, want redis.Nil", err)
+	}
+	pipe := rdb(ctx).Pipeline()
+	pipe.Incr("a")
+	pipe.Incr("b")
+	if _, err := pipe.Exec(); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	spans := sr.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("len(spans) = %d, want 3", len(spans))
+	}
+	want := []string{"redis.get", "redis.pipeline", "parent"}
+	for i, span := range spans {
+		if span.Name() != want[i] {
+			t.Fatalf("spans[%d] = %q, want %q", i, span.Name(), want[i])
+		}
+		if i < 2 && span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Fatalf("spans[%d] is not a child of parent", i)
+		}
+	}
+	// 查询的key不存在不算错误
+	if code := spans[0].Status().Code; code != 0 {
+		t.Fatalf("redis.get status = %v, want unset", code)
+	}
+}
@@ -1,8 +1,9 @@
 package redis
 
 import (
+	"bluebell/models"
+	"context"
 	"errors"
-	"math"
 	"strconv"
 	"time"
 
@@ -33,8 +34,8 @@ import (
 */
 
 const (
-	oneWeekInSeconds = 7 * 24 * 3600
-	scorePerVote     = 432 // 每一票值多少分
+	OneWeekInSeconds = 7 * 24 * 3600 // 发帖后允许投票的时间
+	ScorePerVote     = 432           // 每一票值多少分
 )
 
 var (
@@ -42,8 +43,8 @@ var (
 	ErrVoteRepeated   = errors.New("不允许重复投票")
 )
 
-func CreatePost(postID, communityID int64) error {
-	pipeline := client.TxPipeline() //创建一个事务流水线
+func CreatePost(ctx context.Context, postID, communityID int64) error {
+	pipeline := rdb(ctx).TxPipeline() //创建一个事务流水线
 	// 帖子时间
 	pipeline.ZAdd(getRedisKey(KeyPostTimeZSet), redis.Z{
 		Score:  float64(time.Now().Unix()),
@@ -62,34 +63,28 @@ func CreatePost(postID, communityID int64) error {
 	return err
 }
 
-func VoteForPost(userID, postID string, value float64) error {
-	// 1. 判断投票限制
-	// 去redis取帖子发布时间
-	postTime := client.ZScore(getRedisKey(KeyPostTimeZSet), postID).Val()
-	if float64(time.Now().Unix())-postTime > oneWeekInSeconds {
-		return ErrVoteTimeExpire
+// GetPostCreateTime 查询帖子在时间zset中的发帖时间,帖子不存在时返回0
+func GetPostCreateTime(ctx context.Context, postID string) (float64, error) {
+	t, err := rdb(ctx).ZScore(getRedisKey(KeyPostTimeZSet), postID).Result()
+	if err == Nil {
+		return 0, nil
 	}
-	// 2和3需要放到一个pipeline事务中操作
-
-	// 2. 更新贴子的分数
-	// 先查当前用户给当前帖子的投票记录
-	ov := client.ZScore(getRedisKey(KeyPostVotedZSetPF+postID), userID).Val()
+	return t, err
+}
 
-	// 更新：如果这一次投票的值和之前保存的值一致，就提示不允许重复投票
-	if value == ov {
-		return ErrVoteRepeated
-	}
-	var op float64
-	if value > ov {
-		op = 1
-	} else {
-		op = -1
+// GetUserVote 查询用户给帖子投的票,没有投过票时返回0
+func GetUserVote(ctx context.Context, userID, postID string) (float64, error) {
+	v, err := rdb(ctx).ZScore(getRedisKey(KeyPostVotedZSetPF+postID), userID).Result()
+	if err == Nil {
+		return 0, nil
 	}
-	diff := math.Abs(ov - value) // 计算两次投票的差值
-	pipeline := client.TxPipeline()
-	pipeline.ZIncrBy(getRedisKey(KeyPostScoreZSet), op*diff*scorePerVote, postID)
+	return v, err
+}
 
-	// 3. 记录用户为该贴子投票的数据
+// SaveVote 在一个事务中更新帖子的分数和用户的投票记录,value为0表示取消投票
+func SaveVote(ctx context.Context, userID, postID string, value, scoreDelta float64) error {
+	pipeline := rdb(ctx).TxPipeline()
+	pipeline.ZIncrBy(getRedisKey(KeyPostScoreZSet), scoreDelta, postID)
 	if value == 0 {
 		pipeline.ZRem(getRedisKey(KeyPostVotedZSetPF+postID), userID)
 	} else {
@@ -101,3 +96,46 @@ func VoteForPost(userID, postID string, value float64) error {
 	_, err := pipeline.Exec()
 	return err
 }
+
+// PostScore 根据发帖时间和投票数计算帖子的分数,与投票时累加的分数一致
+func PostScore(createTime time.Time, upVotes, downVotes int64) float64 {
+	return float64(createTime.Unix()) + float64(upVotes-downVotes)*ScorePerVote
+}
+
+// GetExpiredVotePostIDs 按发帖时间分批查询投票期已经结束的帖子id
+func GetExpiredVotePostIDs(ctx context.Context, offset, count int64) ([]string, error) {
+	max := strconv.FormatInt(time.Now().Unix()-OneWeekInSeconds, 10)
+	return rdb(ctx).ZRangeByScore(getRedisKey(KeyPostTimeZSet), redis.ZRangeBy{
+		Min:    "-inf",
+		Max:    max,
+		Offset: offset,
+		Count:  count,
+	}).Result()
+}
+
+// GetPostVoteCounts 批量统计帖子的赞成票数和反对票数,返回值与ids一一对应
+func GetPostVoteCounts(ctx context.Context, ids []string) ([]*models.VoteArchive, error) {
+	pipeline := rdb(ctx).Pipeline()
+	for _, id := range ids {
+		key := getRedisKey(KeyPostVotedZSetPF + id)
+		pipeline.ZCount(key, "1", "1")
+		pipeline.ZCount(key, "-1", "-1")
+	}
+	cmders, err := pipeline.Exec()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*models.VoteArchive, 0, len(ids))
+	for i, id := range ids {
+		postID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, &models.VoteArchive{
+			PostID:    postID,
+			UpVotes:   cmders[2*i].(*redis.IntCmd).Val(),
+			DownVotes: cmders[2*i+1].(*redis.IntCmd).Val(),
+		})
+	}
+	return res, nil
+}
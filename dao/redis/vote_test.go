@@ -0,0 +1,95 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSaveVote(t *testing.T) {
+	ctx := context.Background()
+	setupMiniredis(t)
+	createTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local)
+	if err := AddPostToIndex(ctx, 1, 1, createTime); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		userID     string
+		value      float64
+		scoreDelta float64
+		wantScore  float64 // 相对发帖时间的分数
+		wantUp     int64
+	}{
+		{userID: "7", value: 1, scoreDelta: ScorePerVote, wantScore: ScorePerVote, wantUp: 1},
+		{userID: "8", value: -1, scoreDelta: -ScorePerVote, wantScore: 0, wantUp: 1},
+		{userID: "7", value: 0, scoreDelta: -ScorePerVote, wantScore: -ScorePerVote, wantUp: 0},
+	}
+	for i, s := range steps {
+		if err := SaveVote(ctx, s.userID, "1", s.value, s.scoreDelta); err != nil {
+			t.Fatal(err)
+		}
+		score := client.ZScore(getRedisKey(KeyPostScoreZSet), "1").Val()
+		if got := score - float64(createTime.Unix()); got != s.wantScore {
+			t.Errorf("step %d: score = %v, want %v", i, got, s.wantScore)
+		}
+		v, err := GetUserVote(ctx, s.userID, "1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != s.value {
+			t.Errorf("step %d: user vote = %v, want %v", i, v, s.value)
+		}
+		up, err := GetPostVoteData(ctx, []string{"1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if up[0] != s.wantUp {
+			t.Errorf("step %d: up votes = %d, want %d", i, up[0], s.wantUp)
+		}
+	}
+}
+
+func TestGetPostCreateTime(t *testing.T) {
+	ctx := context.Background()
+	setupMiniredis(t)
+	createTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local)
+	if err := AddPostToIndex(ctx, 1, 1, createTime); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		postID string
+		want   float64
+	}{
+		{postID: "1", want: float64(createTime.Unix())},
+		{postID: "2", want: 0}, // 不存在的帖子
+	}
+	for _, tt := range tests {
+		got, err := GetPostCreateTime(ctx, tt.postID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("GetPostCreateTime(%s) = %v, want %v", tt.postID, got, tt.want)
+		}
+	}
+}
+
+func TestGetUserVotes(t *testing.T) {
+	ctx := context.Background()
+	setupMiniredis(t)
+	_ = SaveVote(ctx, "7", "1", 1, ScorePerVote)
+	_ = SaveVote(ctx, "7", "2", -1, -ScorePerVote)
+	_ = SaveVote(ctx, "8", "3", 1, ScorePerVote)
+
+	got, err := GetUserVotes(ctx, 7, []string{"1", "2", "3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int8{1, -1, 0}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("GetUserVotes() = %v, want %v", got, want)
+		}
+	}
+}
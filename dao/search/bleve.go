@@ -0,0 +1,155 @@
+package search
+
+import (
+	"bluebell/models"
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/v2/analysis/lang/cjk"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/highlight/format/html"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+// bleveSearcher 基于内嵌bleve索引的搜索,需要在帖子变化时维护索引
+type bleveSearcher struct {
+	index   bleve.Index
+	created bool // 索引是否是新建的
+}
+
+// bleveDoc 写入bleve索引的文档
+type bleveDoc struct {
+	Title       string    `json:"title"`
+	Content     string    `json:"content"`
+	AuthorName  string    `json:"author_name"`
+	CommunityID float64   `json:"community_id"`
+	CreateTime  time.Time `json:"create_time"`
+}
+
+func newBleveSearcher(path string) (*bleveSearcher, error) {
+	index, err := bleve.Open(path)
+	created := false
+	if err == bleve.ErrorIndexPathDoesNotExist {
+		index, err = bleve.New(path, buildIndexMapping())
+		created = true
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &bleveSearcher{index: index, created: created}, nil
+}
+
+func buildIndexMapping() mapping.IndexMapping {
+	// 标题和内容使用cjk分词器(二元分词),支持中文搜索
+	textField := bleve.NewTextFieldMapping()
+	textField.Analyzer = cjk.AnalyzerName
+	textField.Store = true
+	textField.IncludeTermVectors = true // 高亮需要
+
+	authorField := bleve.NewTextFieldMapping()
+	authorField.Analyzer = keyword.Name
+
+	doc := bleve.NewDocumentMapping()
+	doc.AddFieldMappingsAt("title", textField)
+	doc.AddFieldMappingsAt("content", textField)
+	doc.AddFieldMappingsAt("author_name", authorField)
+	doc.AddFieldMappingsAt("community_id", bleve.NewNumericFieldMapping())
+	doc.AddFieldMappingsAt("create_time", bleve.NewDateTimeFieldMapping())
+
+	m := bleve.NewIndexMapping()
+	m.DefaultMapping = doc
+	return m
+}
+
+func (s *bleveSearcher) Search(_ context.Context, q *Query) (*Result, error) {
+	title := bleve.NewMatchQuery(q.Keyword)
+	title.SetField("title")
+	content := bleve.NewMatchQuery(q.Keyword)
+	content.SetField("content")
+	conjuncts := []query.Query{bleve.NewDisjunctionQuery(title, content)}
+
+	if q.CommunityID != 0 {
+		cid := float64(q.CommunityID)
+		inclusive := true
+		cq := bleve.NewNumericRangeInclusiveQuery(&cid, &cid, &inclusive, &inclusive)
+		cq.SetField("community_id")
+		conjuncts = append(conjuncts, cq)
+	}
+	if q.AuthorName != "" {
+		aq := bleve.NewTermQuery(q.AuthorName)
+		aq.SetField("author_name")
+		conjuncts = append(conjuncts, aq)
+	}
+	if !q.Since.IsZero() {
+		tq := bleve.NewDateRangeQuery(q.Since, time.Time{})
+		tq.SetField("create_time")
+		conjuncts = append(conjuncts, tq)
+	}
+
+	req := bleve.NewSearchRequestOptions(bleve.NewConjunctionQuery(conjuncts...),
+		int(q.Size), int(q.Offset), false)
+	req.Highlight = bleve.NewHighlightWithStyle(html.Name)
+	req.Highlight.AddField("title")
+	req.Highlight.AddField("content")
+
+	sr, err := s.index.Search(req)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{
+		Total: int64(sr.Total),
+		Hits:  make([]*Hit, 0, len(sr.Hits)),
+	}
+	for _, h := range sr.Hits {
+		postID, err := strconv.ParseInt(h.ID, 10, 64)
+		if err != nil {
+			continue
+		}
+		res.Hits = append(res.Hits, &Hit{
+			PostID:  postID,
+			Title:   strings.Join(h.Fragments["title"], "..."),
+			Snippet: strings.Join(h.Fragments["content"], "..."),
+		})
+	}
+	return res, nil
+}
+
+func (s *bleveSearcher) Index(_ context.Context, post *models.Post, authorName string) error {
+	return s.index.Index(strconv.FormatInt(post.ID, 10), newBleveDoc(post, authorName))
+}
+
+func (s *bleveSearcher) IndexBatch(_ context.Context, posts []*models.Post, authorNames map[int64]string) error {
+	b := s.index.NewBatch()
+	for _, post := range posts {
+		if err := b.Index(strconv.FormatInt(post.ID, 10), newBleveDoc(post, authorNames[post.AuthorID])); err != nil {
+			return err
+		}
+	}
+	return s.index.Batch(b)
+}
+
+func newBleveDoc(post *models.Post, authorName string) *bleveDoc {
+	createTime := post.CreateTime
+	if createTime.IsZero() {
+		createTime = time.Now()
+	}
+	return &bleveDoc{
+		Title:       post.Title,
+		Content:     post.Content,
+		AuthorName:  authorName,
+		CommunityID: float64(post.CommunityID),
+		CreateTime:  createTime,
+	}
+}
+
+func (s *bleveSearcher) Delete(_ context.Context, postID int64) error {
+	return s.index.Delete(strconv.FormatInt(postID, 10))
+}
+
+func (s *bleveSearcher) Close() error {
+	return s.index.Close()
+}
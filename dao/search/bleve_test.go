@@ -0,0 +1,56 @@
+package search
+
+import (
+	"bluebell/models"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBleveIndexBatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "post.bleve")
+	s, err := newBleveSearcher(path)
+	if err != nil {
+		t.Fatalf("newBleveSearcher() error = %v", err)
+	}
+	if !s.created {
+		t.Error("new index should be marked as created")
+	}
+
+	ctx := context.Background()
+	posts := []*models.Post{
+		{ID: 1, AuthorID: 10, CommunityID: 1, Title: "学习Go语言", Content: "并发编程", CreateTime: time.Now()},
+		{ID: 2, AuthorID: 20, CommunityID: 2, Title: "周末去爬山", Content: "天气很好", CreateTime: time.Now()},
+	}
+	if err := s.IndexBatch(ctx, posts, map[int64]string{10: "alice", 20: "bob"}); err != nil {
+		t.Fatalf("IndexBatch() error = %v", err)
+	}
+	res, err := s.Search(ctx, &Query{Keyword: "爬山", Size: 10})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if res.Total != 1 || res.Hits[0].PostID != 2 {
+		t.Errorf("Search() = %+v, want post 2", res)
+	}
+	res, err = s.Search(ctx, &Query{Keyword: "Go", AuthorName: "alice", Size: 10})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if res.Total != 1 || res.Hits[0].PostID != 1 {
+		t.Errorf("Search() by author = %+v, want post 1", res)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// 重新打开已有的索引不需要回填
+	s, err = newBleveSearcher(path)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	defer s.Close()
+	if s.created {
+		t.Error("existing index should not be marked as created")
+	}
+}
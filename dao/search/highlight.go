@@ -0,0 +1,101 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	highlightBefore = "<mark>"
+	highlightAfter  = "</mark>"
+)
+
+// Highlight 把text中出现的关键词用<mark>标签包起来,其余部分做HTML转义
+// maxRunes大于0时只截取第一个命中位置附近maxRunes个字符的片段
+func Highlight(text string, terms []string, maxRunes int) string {
+	lower := asciiLower(text)
+	// 找出所有命中的区间(字节下标),重叠的区间合并
+	type span struct{ start, end int }
+	var spans []span
+	for i := 0; i < len(lower); {
+		matched := 0
+		for _, term := range terms {
+			t := asciiLower(term)
+			if t != "" && strings.HasPrefix(lower[i:], t) && len(t) > matched {
+				matched = len(t)
+			}
+		}
+		if matched == 0 {
+			_, size := utf8.DecodeRuneInString(lower[i:])
+			i += size
+			continue
+		}
+		if n := len(spans); n > 0 && spans[n-1].end >= i {
+			spans[n-1].end = i + matched
+		} else {
+			spans = append(spans, span{i, i + matched})
+		}
+		i += matched
+	}
+
+	// 截取片段
+	from, to := 0, len(text)
+	if maxRunes > 0 && utf8.RuneCountInString(text) > maxRunes {
+		if len(spans) > 0 {
+			from = backRunes(text, spans[0].start, maxRunes/4)
+		}
+		to = forwardRunes(text, from, maxRunes)
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("...")
+	}
+	pos := from
+	for _, sp := range spans {
+		if sp.end <= from || sp.start >= to {
+			continue
+		}
+		start, end := max(sp.start, from), min(sp.end, to)
+		b.WriteString(html.EscapeString(text[pos:start]))
+		b.WriteString(highlightBefore)
+		b.WriteString(html.EscapeString(text[start:end]))
+		b.WriteString(highlightAfter)
+		pos = end
+	}
+	b.WriteString(html.EscapeString(text[pos:to]))
+	if to < len(text) {
+		b.WriteString("...")
+	}
+	return b.String()
+}
+
+// asciiLower 只转换ASCII字母的大小写,保证转换前后字节下标一致
+func asciiLower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
+
+// backRunes 返回从下标i往前n个字符的字节下标
+func backRunes(s string, i, n int) int {
+	for ; n > 0 && i > 0; n-- {
+		_, size := utf8.DecodeLastRuneInString(s[:i])
+		i -= size
+	}
+	return i
+}
+
+// forwardRunes 返回从下标i往后n个字符的字节下标
+func forwardRunes(s string, i, n int) int {
+	for ; n > 0 && i < len(s); n-- {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	return i
+}
@@ -0,0 +1,27 @@
+package search
+
+import "testing"
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		terms    []string
+		maxRunes int
+		want     string
+	}{
+		{"single term", "学习使我快乐", []string{"快乐"}, 0, "学习使我<mark>快乐</mark>"},
+		{"case insensitive", "Learn Go today", []string{"go"}, 0, "Learn <mark>Go</mark> today"},
+		{"overlapping terms", "golang", []string{"go", "golang"}, 0, "<mark>golang</mark>"},
+		{"escape html", "<b>go</b>", []string{"go"}, 0, "&lt;b&gt;<mark>go</mark>&lt;/b&gt;"},
+		{"no match", "hello", []string{"go"}, 0, "hello"},
+		{"snippet", "一二三四五六七八九十关键词一二三四五六七八九十", []string{"关键词"}, 8, "...九十<mark>关键词</mark>一二三..."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Highlight(tt.text, tt.terms, tt.maxRunes); got != tt.want {
+				t.Errorf("Highlight() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package search
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/models"
+	"context"
+	"strings"
+)
+
+const snippetLength = 80 // 内容片段的最大长度(字符数)
+
+// mysqlSearcher 基于MySQL FULLTEXT索引的搜索,索引由MySQL自己维护
+type mysqlSearcher struct{}
+
+func (s *mysqlSearcher) Search(ctx context.Context, q *Query) (*Result, error) {
+	posts, total, err := mysql.SearchPosts(ctx, &mysql.SearchPostsParam{
+		Keyword:     q.Keyword,
+		CommunityID: q.CommunityID,
+		AuthorName:  q.AuthorName,
+		Since:       q.Since,
+		Offset:      q.Offset,
+		Limit:       q.Size,
+	})
+	if err != nil {
+		return nil, err
+	}
+	terms := strings.Fields(q.Keyword)
+	res := &Result{
+		Total: total,
+		Hits:  make([]*Hit, 0, len(posts)),
+	}
+	for _, post := range posts {
+		res.Hits = append(res.Hits, &Hit{
+			PostID:  post.ID,
+			Title:   Highlight(post.Title, terms, 0),
+			Snippet: Highlight(post.Content, terms, snippetLength),
+		})
+	}
+	return res, nil
+}
+
+func (s *mysqlSearcher) Index(context.Context, *models.Post, string) error { return nil }
+
+func (s *mysqlSearcher) IndexBatch(context.Context, []*models.Post, map[int64]string) error {
+	return nil
+}
+
+func (s *mysqlSearcher) Delete(context.Context, int64) error { return nil }
+
+func (s *mysqlSearcher) Close() error { return nil }
@@ -0,0 +1,130 @@
+package search
+
+import (
+	"bluebell/models"
+	"bluebell/pkg/tracing"
+	"bluebell/setting"
+	"context"
+	"fmt"
+	"time"
+)
+
+// 帖子全文搜索
+// 默认使用MySQL的FULLTEXT索引(ngram分词器支持中文),也可以配置成使用内嵌的bleve索引
+// bleve索引保存在本机磁盘上,只有写帖子的实例能更新自己的索引,所以只能在单实例部署时使用,多实例部署必须使用mysql
+// bleve索引是新建的(第一次启用或删除了索引目录)时 NeedsBackfill 返回true,需要从MySQL回填已有的帖子
+
+const (
+	EngineMySQL = "mysql"
+	EngineBleve = "bleve"
+)
+
+// Query 搜索条件
+type Query struct {
+	Keyword     string
+	CommunityID int64     // 为0表示不限制社区
+	AuthorName  string    // 为空表示不限制作者
+	Since       time.Time // 为零值表示不限制发帖时间
+	Offset      int64
+	Size        int64
+}
+
+// Hit 搜索命中的帖子及高亮后的片段
+type Hit struct {
+	PostID  int64
+	Title   string // 高亮后的标题
+	Snippet string // 高亮后的内容片段
+}
+
+// Result 搜索结果
+type Result struct {
+	Total int64
+	Hits  []*Hit
+}
+
+// Searcher 搜索引擎需要实现的接口
+type Searcher interface {
+	// Search 按相关度从高到低返回一页搜索结果
+	Search(ctx context.Context, q *Query) (*Result, error)
+	// Index 新建或更新帖子的索引
+	Index(ctx context.Context, post *models.Post, authorName string) error
+	// IndexBatch 批量新建或更新帖子的索引,authorNames是作者id到用户名的映射
+	IndexBatch(ctx context.Context, posts []*models.Post, authorNames map[int64]string) error
+	// Delete 删除帖子的索引
+	Delete(ctx context.Context, postID int64) error
+	Close() error
+}
+
+var (
+	searcher      Searcher
+	engine        string
+	needsBackfill bool
+)
+
+// Init 根据配置初始化搜索引擎
+func Init(cfg *setting.SearchConfig) (err error) {
+	engine = EngineMySQL
+	if cfg != nil && cfg.Engine != "" {
+		engine = cfg.Engine
+	}
+	switch engine {
+	case EngineMySQL:
+		searcher = new(mysqlSearcher)
+	case EngineBleve:
+		var s *bleveSearcher
+		if s, err = newBleveSearcher(cfg.IndexPath); err == nil {
+			searcher, needsBackfill = s, s.created
+		}
+	default:
+		err = fmt.Errorf("unknown search engine %q", engine)
+	}
+	return
+}
+
+// Engine 返回使用的搜索引擎,还没有初始化时返回空字符串
+func Engine() string {
+	if searcher == nil {
+		return ""
+	}
+	return engine
+}
+
+// NeedsBackfill 索引是否是本次启动时新建的,新建的索引中没有已有的帖子
+func NeedsBackfill() bool {
+	return needsBackfill
+}
+
+// Close 关闭搜索引擎
+func Close() {
+	if searcher != nil {
+		_ = searcher.Close()
+	}
+}
+
+// Search 搜索帖子
+func Search(ctx context.Context, q *Query) (res *Result, err error) {
+	ctx, span := tracing.Start(ctx, "search."+engine)
+	defer func() { tracing.End(span, err) }()
+	return searcher.Search(ctx, q)
+}
+
+// IndexPost 新建或更新帖子的索引
+func IndexPost(ctx context.Context, post *models.Post, authorName string) (err error) {
+	ctx, span := tracing.Start(ctx, "search."+engine+".index")
+	defer func() { tracing.End(span, err) }()
+	return searcher.Index(ctx, post, authorName)
+}
+
+// IndexPosts 批量新建或更新帖子的索引
+func IndexPosts(ctx context.Context, posts []*models.Post, authorNames map[int64]string) (err error) {
+	ctx, span := tracing.Start(ctx, "search."+engine+".index_batch")
+	defer func() { tracing.End(span, err) }()
+	return searcher.IndexBatch(ctx, posts, authorNames)
+}
+
+// DeletePost 删除帖子的索引
+func DeletePost(ctx context.Context, postID int64) (err error) {
+	ctx, span := tracing.Start(ctx, "search."+engine+".delete")
+	defer func() { tracing.End(span, err) }()
+	return searcher.Delete(ctx, postID)
+}
@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// RequestIDHeader 请求头中携带的请求id,响应中也会返回
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen 上游传入的请求id超过这个长度或包含不可见字符时重新生成
+const maxRequestIDLen = 128
+
+type loggerKey struct{}
+
+type requestIDKey struct{}
+
+// FromContext 返回ctx中保存的带请求信息的logger,没有时返回全局logger
+func FromContext(ctx context.Context) *zap.Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(loggerKey{}).(*zap.Logger); ok {
+			return l
+		}
+	}
+	return zap.L()
+}
+
+// NewContext 把logger保存到ctx中
+func NewContext(ctx context.Context, l *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// With 给ctx中的logger加上字段,之后用 FromContext 取到的logger都会带上这些字段
+func With(ctx context.Context, fields ...zap.Field) context.Context {
+	return NewContext(ctx, FromContext(ctx).With(fields...))
+}
+
+// RequestID 返回ctx所属请求的id,不在请求中时返回空字符串
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// GinRequestID 使用请求头中的 X-Request-ID,没有时生成一个,并写入响应头
+// 请求的context中保存带有request_id的logger,开启链路追踪时还带有trace_id,
+// 需要放在 GinLogger 和 GinRecovery 之前
+func GinRequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Header(RequestIDHeader, id)
+
+		ctx := context.WithValue(c.Request.Context(), requestIDKey{}, id)
+		fields := []zap.Field{zap.String("request_id", id)}
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			fields = append(fields, zap.String("trace_id", sc.TraceID().String()))
+		}
+		c.Request = c.Request.WithContext(With(ctx, fields...))
+		c.Next()
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,78 @@
+package logger
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestGinRequestID(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	defer zap.ReplaceGlobals(zap.New(core))()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinRequestID(), GinLogger())
+	r.GET("/", func(c *gin.Context) {
+		ctx := With(c.Request.Context(), zap.Int64("user_id", 7))
+		c.Request = c.Request.WithContext(ctx)
+		FromContext(ctx).Info("handler")
+		c.String(http.StatusOK, RequestID(ctx))
+	})
+
+	tests := []struct {
+		name   string
+		header string
+		want   string // 为空时表示需要生成新的id
+	}{
+		{name: "from header", header: "abc-123", want: "abc-123"},
+		{name: "missing", header: ""},
+		{name: "invalid", header: "has space"},
+		{name: "too long", header: strings.Repeat("a", maxRequestIDLen+1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.TakeAll()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			id := w.Header().Get(RequestIDHeader)
+			if tt.want != "" && id != tt.want {
+				t.Fatalf("request id = %q, want %q", id, tt.want)
+			}
+			if tt.want == "" && (len(id) != 32 || id == tt.header) {
+				t.Fatalf("request id = %q, want a generated one", id)
+			}
+			if w.Body.String() != id {
+				t.Fatalf("RequestID(ctx) = %q, want %q", w.Body.String(), id)
+			}
+			// 处理函数和访问日志都带有request_id,访问日志还能取到处理过程中加上的user_id
+			entries := logs.TakeAll()
+			if len(entries) != 2 {
+				t.Fatalf("len(entries) = %d, want 2", len(entries))
+			}
+			for _, e := range entries {
+				fields := e.ContextMap()
+				if fields["request_id"] != id || fields["user_id"] != int64(7) {
+					t.Fatalf("entry %q fields = %v", e.Message, fields)
+				}
+			}
+		})
+	}
+}
+
+func TestFromContextDefault(t *testing.T) {
+	if FromContext(context.Background()) != zap.L() {
+		t.Fatal("FromContext should return the global logger outside of requests")
+	}
+}
@@ -0,0 +1,97 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/logger"
+	"bluebell/models"
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RestrictedError 用户被社区禁言或被全站封禁时返回的错误
+type RestrictedError struct {
+	Suspended  bool       // true:全站封禁 false:社区内禁言
+	Reason     string     // 原因
+	ExpireTime *time.Time // 到期时间,为空表示永久
+}
+
+func (e *RestrictedError) Error() string {
+	if e.Suspended {
+		return "账号已被封禁"
+	}
+	return "已被禁言"
+}
+
+// CheckUserRestriction 检查用户是否可以在社区内发帖、投票和评论
+// 被限制时返回*RestrictedError
+func CheckUserRestriction(ctx context.Context, userID, communityID int64) error {
+	now := time.Now()
+	// 1. 全站封禁
+	s, err := getUserSuspension(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if s != nil && s.Active(now) {
+		return &RestrictedError{Suspended: true, Reason: s.Reason, ExpireTime: s.ExpireTime}
+	}
+	// 2. 社区内禁言
+	ban, err := mysql.GetCommunityBan(ctx, communityID, userID)
+	if err != nil {
+		return err
+	}
+	if ban != nil && ban.Active(now) {
+		return &RestrictedError{Reason: ban.Reason, ExpireTime: ban.ExpireTime}
+	}
+	return nil
+}
+
+// getUserSuspension 先查redis缓存,未命中再查MySQL并回填缓存
+func getUserSuspension(ctx context.Context, userID int64) (*models.UserSuspension, error) {
+	s, cached, err := redis.GetCachedSuspension(ctx, userID)
+	if err != nil {
+		// 缓存不可用时降级查询MySQL
+		logger.FromContext(ctx).Warn("redis.GetCachedSuspension failed", zap.Int64("user_id", userID), zap.Error(err))
+	}
+	if cached {
+		return s, nil
+	}
+	s, err = mysql.GetUserSuspension(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if s != nil && !s.Active(time.Now()) {
+		s = nil
+	}
+	if err := redis.SetCachedSuspension(ctx, userID, s); err != nil {
+		logger.FromContext(ctx).Warn("redis.SetCachedSuspension failed", zap.Int64("user_id", userID), zap.Error(err))
+	}
+	return s, nil
+}
+
+// SuspendUser 全站封禁用户
+func SuspendUser(ctx context.Context, operatorID int64, p *models.ParamSuspendUser) error {
+	s := &models.UserSuspension{
+		UserID:     p.UserID,
+		OperatorID: operatorID,
+		Reason:     p.Reason,
+	}
+	if p.Duration > 0 {
+		expire := time.Now().Add(time.Duration(p.Duration) * time.Second)
+		s.ExpireTime = &expire
+	}
+	if err := mysql.SuspendUser(ctx, s); err != nil {
+		return err
+	}
+	return redis.DeleteCachedSuspension(ctx, p.UserID)
+}
+
+// UnsuspendUser 解除全站封禁
+func UnsuspendUser(ctx context.Context, userID int64) error {
+	if err := mysql.UnsuspendUser(ctx, userID); err != nil {
+		return err
+	}
+	return redis.DeleteCachedSuspension(ctx, userID)
+}
@@ -3,30 +3,103 @@ package logic
 import (
 	"bluebell/dao/mysql"
 	"bluebell/dao/redis"
+	"bluebell/logger"
 	"bluebell/models"
+	"bluebell/pkg/cursor"
+	"bluebell/pkg/slug"
+	"context"
+	"errors"
 
 	"go.uber.org/zap"
 )
 
-func GetCommunityList() ([]*models.Community, error) {
+func GetCommunityList(ctx context.Context) ([]*models.Community, error) {
 	// 查数据库 查找到所有的community 并返回
-	return mysql.GetCommunityList()
+	return mysql.GetCommunityList(ctx)
 }
 
-func GetCommunityDetail(id int64) (*models.CommunityDetail, error) {
-	return mysql.GetCommunityDetailByID(id)
+func GetCommunityDetail(ctx context.Context, id int64) (*models.CommunityDetail, error) {
+	return mysql.GetCommunityDetailByID(ctx, id)
 }
 
-func CommunityByName(communityName string) ([]*models.Post, error) {
-	id := mysql.GetCommunityIDByName(communityName)
-	post, err := mysql.GetPostListByCommunityIDs(id)
+// CreateCommunity 创建社区,slug由社区名称生成,名称已存在时返回 mysql.ErrorCommunityExist
+func CreateCommunity(ctx context.Context, p *models.ParamCreateCommunity) (*models.CommunityDetail, error) {
+	if err := mysql.CheckCommunityExist(ctx, p.Name); err != nil {
+		return nil, err
+	}
+	id, err := mysql.NextCommunityID(ctx)
 	if err != nil {
-		zap.L().Error("CommunityByName mysql.GetPostListByCommunityIDs(id) ", zap.Error(err))
 		return nil, err
 	}
-	return post, err
+	s, err := slug.ForCommunity(p.Name, id, func(s string) (bool, error) {
+		return mysql.CommunitySlugExists(ctx, s)
+	})
+	if err != nil {
+		return nil, err
+	}
+	community := &models.CommunityDetail{
+		ID:           id,
+		Name:         p.Name,
+		Slug:         s,
+		Introduction: p.Introduction,
+	}
+	if err := mysql.InsertCommunity(ctx, community); err != nil {
+		return nil, err
+	}
+	// 社区id按最大值加一分配,可能重新使用已经被删除的社区的id,删除旧社区的缓存
+	InvalidateCommunityCache(ctx, id)
+	return mysql.GetCommunityDetailByID(ctx, id)
 }
 
-func GetComments(postID int64) ([]*models.Comment, error) {
-	return redis.GetComments(postID)
+// CommunityByName 根据slug(或社区名称)查询社区详情及按排序依据分页的帖子列表
+func CommunityByName(ctx context.Context, name string, p *models.ParamPostList, viewerID int64) (*models.ApiCommunityPosts, string, error) {
+	community, err := getCommunityBySlugOrName(ctx, name)
+	if err != nil {
+		return nil, "", err
+	}
+	posts, next, err := std.postListQuery(SourceCommunity(community.ID), p, viewerID).Run(ctx)
+	if err != nil {
+		logger.FromContext(ctx).Error("CommunityByName postListQuery failed",
+			zap.Int64("community_id", community.ID),
+			zap.Error(err))
+		return nil, "", err
+	}
+	if posts == nil {
+		posts = make([]*models.ApiPostDetail, 0)
+	}
+	return &models.ApiCommunityPosts{
+		Community: community,
+		Posts:     posts,
+	}, next, nil
+}
+
+// getCommunityBySlugOrName 先按slug查询社区,查不到时再按社区名称查询(兼容旧的链接)
+func getCommunityBySlugOrName(ctx context.Context, name string) (*models.CommunityDetail, error) {
+	if s := slug.Make(name); s != "" {
+		community, err := mysql.GetCommunityDetailBySlug(ctx, s)
+		if err == nil {
+			return community, nil
+		}
+		if !errors.Is(err, mysql.ErrorInvalidID) {
+			return nil, err
+		}
+	}
+	id, err := mysql.GetCommunityIDByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return mysql.GetCommunityDetailByID(ctx, id)
+}
+
+// GetComments 按评论时间从早到晚分页查询帖子的评论,返回下一页的游标
+func GetComments(ctx context.Context, postID int64, cursorStr string, size int64) ([]*models.Comment, string, error) {
+	c, err := cursor.Decode(cursorStr)
+	if err != nil {
+		return nil, "", err
+	}
+	comments, next, err := redis.GetCommentsByCursor(ctx, postID, c, size)
+	if err != nil {
+		return nil, "", err
+	}
+	return comments, next.Encode(), nil
 }
@@ -2,15 +2,18 @@ package logic
 
 import (
 	"bluebell/dao/mysql"
+	"bluebell/logger"
+	"context"
 
 	"go.uber.org/zap"
 )
 
-func DeletePost(postID int64) {
+func DeletePost(ctx context.Context, postID int64) {
 	// 把参数传递到dao层进行处理
-	err := mysql.DeletePost(postID)
+	err := mysql.DeletePostWithOutbox(ctx, postID)
 	if err != nil {
-		zap.L().Error("mysql.DeletePost error", zap.Error(err))
+		logger.FromContext(ctx).Error("mysql.DeletePostWithOutbox error", zap.Error(err))
 		return
 	}
+	notifyOutbox()
 }
@@ -0,0 +1,20 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"context"
+)
+
+// FollowCommunity 关注社区,社区必须存在
+func FollowCommunity(ctx context.Context, userID, communityID int64) error {
+	if _, err := mysql.GetCommunityDetailByID(ctx, communityID); err != nil {
+		return err
+	}
+	return redis.FollowCommunity(ctx, userID, communityID)
+}
+
+// UnfollowCommunity 取消关注社区
+func UnfollowCommunity(ctx context.Context, userID, communityID int64) error {
+	return redis.UnfollowCommunity(ctx, userID, communityID)
+}
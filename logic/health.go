@@ -0,0 +1,26 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/pkg/health"
+	"context"
+)
+
+// readiness 就绪检查依赖的MySQL和redis
+var readiness = health.NewChecker()
+
+func init() {
+	readiness.Add("mysql", mysql.Ping)
+	readiness.Add("redis", redis.Ping)
+}
+
+// CheckReadiness 检查MySQL和redis是否可用,超时时间由ctx控制
+func CheckReadiness(ctx context.Context) *health.Report {
+	return readiness.Check(ctx)
+}
+
+// SetShuttingDown 服务开始退出,之后的就绪检查都返回未就绪
+func SetShuttingDown() {
+	readiness.SetShuttingDown()
+}
@@ -0,0 +1,159 @@
+package logic
+
+import (
+	"bluebell/dao/redis"
+	"bluebell/logger"
+	"bluebell/pkg/lru"
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 帖子列表需要展示作者的用户名和社区详情,这两类数据很少变化
+// 查询顺序: 进程内LRU -> redis -> MySQL(批量),下层查到的数据回填到上层
+// 数据变化后删除redis中的缓存,再通过redis通知所有实例删除进程内缓存
+// 连接断开期间的通知会丢失,所以进程内缓存的过期时间较短,其他实例最多读到这么久的旧数据
+// 每个 Service 有自己的缓存,见 NewService
+
+const (
+	localUsernameCacheSize  = 4096
+	localCommunityCacheSize = 256
+	localCacheTTL           = time.Minute
+)
+
+// InvalidateUserCache 用户信息(用户名、头像、角色)变化后删除缓存,目前只缓存了用户名
+func InvalidateUserCache(ctx context.Context, userID int64) {
+	std.usernameCache.invalidate(ctx, userID)
+}
+
+// InvalidateCommunityCache 社区信息变化后删除缓存
+func InvalidateCommunityCache(ctx context.Context, communityID int64) {
+	std.communityCache.invalidate(ctx, communityID)
+}
+
+// stopCacheInvalidationSub 取消订阅删除缓存的通知,为nil时还没有订阅
+var stopCacheInvalidationSub func() error
+
+// StartCacheInvalidation 订阅其他实例发出的删除缓存的通知,删除本实例进程内缓存中对应的数据
+func StartCacheInvalidation() error {
+	invalidations, stop, err := redis.SubscribeCacheInvalidated()
+	if err != nil {
+		return err
+	}
+	stopCacheInvalidationSub = stop
+	caches := map[string]interface{ removeLocal(id int64) }{
+		std.usernameCache.name:  std.usernameCache,
+		std.communityCache.name: std.communityCache,
+	}
+	go func() {
+		for inv := range invalidations {
+			if c, ok := caches[inv.Name]; ok {
+				c.removeLocal(inv.ID)
+			}
+		}
+	}()
+	return nil
+}
+
+// StopCacheInvalidation 取消订阅删除缓存的通知
+func StopCacheInvalidation() {
+	if stopCacheInvalidationSub == nil {
+		return
+	}
+	if err := stopCacheInvalidationSub(); err != nil {
+		zap.L().Warn("unsubscribe cache invalidations failed", zap.Error(err))
+	}
+}
+
+// tieredCache 多级缓存,getCached/setCached/delCached为nil时跳过redis这一级
+// publish为nil时只删除本实例的进程内缓存
+type tieredCache[V any] struct {
+	name      string
+	local     *lru.Cache[int64, V]
+	getCached func(ctx context.Context, ids []int64) (map[int64]V, error)
+	setCached func(context.Context, map[int64]V) error
+	delCached func(ctx context.Context, id int64) error
+	publish   func(ctx context.Context, name string, id int64) error
+	fetch     func(ctx context.Context, ids []int64) (map[int64]V, error)
+}
+
+// loadMany 批量查询,不存在的id不会出现在结果中
+// redis出错时只记录日志并继续查询MySQL
+func (t *tieredCache[V]) loadMany(ctx context.Context, ids []int64) (map[int64]V, error) {
+	res := make(map[int64]V, len(ids))
+	missing := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if v, ok := t.local.Get(id); ok {
+			res[id] = v
+			continue
+		}
+		missing = append(missing, id)
+	}
+	if len(missing) == 0 {
+		return res, nil
+	}
+
+	if t.getCached != nil {
+		cached, err := t.getCached(ctx, missing)
+		if err != nil {
+			logger.FromContext(ctx).Warn("tieredCache get cached failed", zap.String("name", t.name), zap.Error(err))
+		}
+		missing = t.fill(res, missing, cached)
+		if len(missing) == 0 {
+			return res, nil
+		}
+	}
+
+	fetched, err := t.fetch(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	t.fill(res, missing, fetched)
+	if t.setCached != nil && len(fetched) > 0 {
+		if err := t.setCached(ctx, fetched); err != nil {
+			logger.FromContext(ctx).Warn("tieredCache set cached failed", zap.String("name", t.name), zap.Error(err))
+		}
+	}
+	return res, nil
+}
+
+// fill 把查到的数据写入结果和进程内缓存,返回仍未查到的id
+func (t *tieredCache[V]) fill(res map[int64]V, ids []int64, found map[int64]V) (missing []int64) {
+	for _, id := range ids {
+		v, ok := found[id]
+		if !ok {
+			missing = append(missing, id)
+			continue
+		}
+		res[id] = v
+		t.local.Add(id, v)
+	}
+	return missing
+}
+
+// invalidate 删除缓存,先删除redis中的再通知所有实例删除进程内的,避免其他实例从redis中读回旧数据
+func (t *tieredCache[V]) invalidate(ctx context.Context, id int64) {
+	t.local.Remove(id)
+	if t.delCached != nil {
+		if err := t.delCached(ctx, id); err != nil {
+			logger.FromContext(ctx).Warn("tieredCache delete cached failed",
+				zap.String("name", t.name),
+				zap.Int64("id", id),
+				zap.Error(err))
+		}
+	}
+	if t.publish != nil {
+		if err := t.publish(ctx, t.name, id); err != nil {
+			logger.FromContext(ctx).Warn("tieredCache publish invalidation failed",
+				zap.String("name", t.name),
+				zap.Int64("id", id),
+				zap.Error(err))
+		}
+	}
+}
+
+// removeLocal 只删除本实例进程内缓存中的数据,收到其他实例的通知时调用
+func (t *tieredCache[V]) removeLocal(id int64) {
+	t.local.Remove(id)
+}
@@ -0,0 +1,174 @@
+package logic
+
+import (
+	"bluebell/models"
+	"bluebell/pkg/lru"
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// countingSource 模拟MySQL,记录查询次数
+type countingSource struct {
+	queries int
+}
+
+func (s *countingSource) GetUsersByIDs(_ context.Context, ids []int64) ([]*models.User, error) {
+	s.queries++
+	users := make([]*models.User, 0, len(ids))
+	for _, id := range ids {
+		users = append(users, &models.User{UserID: id, Username: "user"})
+	}
+	return users, nil
+}
+
+func (s *countingSource) GetCommunitiesByIDs(_ context.Context, ids []int64) ([]*models.CommunityDetail, error) {
+	s.queries++
+	communities := make([]*models.CommunityDetail, 0, len(ids))
+	for _, id := range ids {
+		communities = append(communities, &models.CommunityDetail{ID: id})
+	}
+	return communities, nil
+}
+
+// newCountingService 作者和社区信息从计数的数据源查询,进程内缓存最多保存cacheSize个元素
+func newCountingService(src *countingSource, cacheSize int) *Service {
+	s := NewService(nil, src, src, nil, nil)
+	s.usernameCache.local = lru.New[int64, string](cacheSize, time.Minute)
+	s.communityCache.local = lru.New[int64, *models.CommunityDetail](cacheSize, time.Minute)
+	return s
+}
+
+// loadPage 补充一页帖子的作者和社区信息
+func loadPage(s *Service, posts []*models.Post) ([]*models.ApiPostDetail, error) {
+	list := make([]*models.ApiPostDetail, 0, len(posts))
+	for _, post := range posts {
+		list = append(list, &models.ApiPostDetail{Post: post})
+	}
+	q := s.NewPostQuery(nil)
+	list, err := EnrichAuthor.enrich(context.Background(), q, list)
+	if err != nil {
+		return nil, err
+	}
+	return EnrichCommunity.enrich(context.Background(), q, list)
+}
+
+// testPosts 生成一页帖子,10个作者分布在4个社区
+func testPosts(n int) []*models.Post {
+	posts := make([]*models.Post, 0, n)
+	for i := 0; i < n; i++ {
+		posts = append(posts, &models.Post{
+			ID:          int64(i + 1),
+			AuthorID:    int64(i%10 + 1),
+			CommunityID: int64(i%4 + 1),
+		})
+	}
+	return posts
+}
+
+func TestEnrichAuthorAndCommunity(t *testing.T) {
+	src := new(countingSource)
+	s := newCountingService(src, 100)
+	posts := testPosts(20)
+	list, err := loadPage(s, posts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src.queries != 2 {
+		t.Fatalf("queries = %d, want 2", src.queries)
+	}
+	if len(list) != len(posts) {
+		t.Fatalf("len(list) = %d, want %d", len(list), len(posts))
+	}
+	for _, d := range list {
+		if d.AuthorName != "user" || d.CommunityDetail.ID != d.Post.CommunityID {
+			t.Fatalf("post %d = %+v", d.Post.ID, d)
+		}
+	}
+	// 第二次请求全部命中进程内缓存
+	if _, err := loadPage(s, posts); err != nil {
+		t.Fatal(err)
+	}
+	if src.queries != 2 {
+		t.Fatalf("queries = %d, want 2", src.queries)
+	}
+	// 删除缓存后只重新查询被删除的部分
+	s.usernameCache.invalidate(context.Background(), 1)
+	if _, err := loadPage(s, posts); err != nil {
+		t.Fatal(err)
+	}
+	if src.queries != 3 {
+		t.Fatalf("queries = %d, want 3", src.queries)
+	}
+}
+
+func TestInvalidatePublishes(t *testing.T) {
+	src := new(countingSource)
+	s := newCountingService(src, 100)
+	var published []string
+	s.communityCache.publish = func(_ context.Context, name string, id int64) error {
+		published = append(published, fmt.Sprintf("%s:%d", name, id))
+		return nil
+	}
+	posts := testPosts(4)
+	if _, err := loadPage(s, posts); err != nil {
+		t.Fatal(err)
+	}
+	s.communityCache.invalidate(context.Background(), 2)
+	if want := []string{"community:2"}; !reflect.DeepEqual(published, want) {
+		t.Fatalf("published = %v, want %v", published, want)
+	}
+	// 其他实例收到通知后只删除进程内缓存
+	s.usernameCache.removeLocal(1)
+	queries := src.queries
+	if _, err := loadPage(s, posts); err != nil {
+		t.Fatal(err)
+	}
+	if src.queries != queries+2 {
+		t.Fatalf("queries = %d, want %d", src.queries, queries+2)
+	}
+}
+
+// BenchmarkPostListNPlusOne 逐个帖子补充作者和社区,容量为1的缓存放不下一页的数据,每个帖子都要查询数据源
+// 作为对比的基准,与 BenchmarkPostListBatch 使用相同的缓存和加载路径,只是不批量
+func BenchmarkPostListNPlusOne(b *testing.B) {
+	src := new(countingSource)
+	s := newCountingService(src, 1)
+	posts := testPosts(10)
+	for i := 0; i < b.N; i++ {
+		for _, post := range posts {
+			if _, err := loadPage(s, []*models.Post{post}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.ReportMetric(float64(src.queries)/float64(b.N), "queries/op")
+}
+
+// BenchmarkPostListBatch 批量查询,容量为1的缓存放不下一页的数据,每次都要查询数据源
+func BenchmarkPostListBatch(b *testing.B) {
+	src := new(countingSource)
+	s := newCountingService(src, 1)
+	posts := testPosts(10)
+	for i := 0; i < b.N; i++ {
+		if _, err := loadPage(s, posts); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(src.queries)/float64(b.N), "queries/op")
+}
+
+// BenchmarkPostListCached 批量查询,并使用进程内缓存
+func BenchmarkPostListCached(b *testing.B) {
+	src := new(countingSource)
+	s := newCountingService(src, 100)
+	posts := testPosts(10)
+	for i := 0; i < b.N; i++ {
+		if _, err := loadPage(s, posts); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(src.queries)/float64(b.N), "queries/op")
+}
@@ -0,0 +1,216 @@
+package logic
+
+import (
+	"bluebell/dao/redis"
+	"bluebell/logger"
+	"bluebell/models"
+	"bluebell/pkg/badword"
+	"bluebell/pkg/metrics"
+	"bluebell/setting"
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// 用户提交的标题、正文、评论和用户名都要经过敏感词过滤
+// 过滤器在服务启动时从MySQL加载,所有请求共用,词库被管理员修改后所有实例自动重新加载,见 sensitiveword.go
+// 每个词库是一个分类,命中后按分类的处理方式拒绝、屏蔽或交给版主审核
+// 评论和用户名没有审核队列,用户名也不能屏蔽,命中 PolicyReview 的评论按拒绝处理,用户名命中任何敏感词都拒绝
+
+var (
+	moderation            *badword.ModerationFilter
+	stopSensitiveWordsSub func() error // 取消订阅词库变化的通知
+)
+
+// SensitiveWordError 提交的内容包含需要拒绝的敏感词
+type SensitiveWordError struct {
+	Field    string // 包含敏感词的字段,如 title、content
+	Category string // 敏感词的分类
+	Word     string // 命中的敏感词,只记录在日志中
+}
+
+func (e *SensitiveWordError) Error() string {
+	return fmt.Sprintf("%s包含%s敏感词: %s", e.Field, e.Category, e.Word)
+}
+
+// InitModeration 从MySQL加载敏感词库,词库为空时先导入配置目录中的词库文件
+// 词库变化时通过redis的pub/sub通知所有实例重新加载,同时定期全量加载,弥补连接断开期间丢失的通知
+func InitModeration(cfg *setting.ModerationConfig) error {
+	policies, err := parsePolicies(cfg.DefaultPolicy, cfg.Policies)
+	if err != nil {
+		return err
+	}
+	normalizer, err := newNormalizer(cfg.NormalizeConfig)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if err := seedSensitiveWords(ctx, cfg.WordsDir); err != nil {
+		return err
+	}
+	// 先订阅再加载,加载期间发生的变化也会触发重新加载
+	changes, stop, err := redis.SubscribeSensitiveWordsChanged()
+	if err != nil {
+		return err
+	}
+	f, err := badword.NewModerationFilter(ctx, loadSensitiveWords, policies, normalizer)
+	if err != nil {
+		_ = stop()
+		return err
+	}
+	f.Watch(changes, cfg.ReloadInterval)
+	moderation, stopSensitiveWordsSub = f, stop
+	return nil
+}
+
+// parsePolicies policies 是分类到处理方式的映射,没有配置的分类使用 defaultPolicy
+func parsePolicies(defaultPolicy string, policies map[string]string) (badword.Policies, error) {
+	p := badword.Policies{Categories: make(map[string]badword.Policy, len(policies))}
+	var err error
+	if p.Default, err = badword.ParsePolicy(defaultPolicy); err != nil {
+		return p, err
+	}
+	for category, s := range policies {
+		if p.Categories[category], err = badword.ParsePolicy(s); err != nil {
+			return p, fmt.Errorf("category %s: %w", category, err)
+		}
+	}
+	return p, nil
+}
+
+// newNormalizer 匹配前转换文本的方式,没有配置时不转换
+func newNormalizer(cfg *setting.NormalizeConfig) (*badword.Normalizer, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	opts := badword.NormalizeOptions{
+		FoldWidth:        cfg.FoldWidth,
+		FoldCase:         cfg.FoldCase,
+		RemoveSeparators: cfg.RemoveSeparators,
+		Stopwords:        cfg.Stopwords,
+		Simplified:       cfg.Simplified,
+		Homoglyphs:       make(map[rune]rune),
+	}
+	for _, file := range cfg.HomoglyphFiles {
+		m, err := badword.LoadHomoglyphs(file)
+		if err != nil {
+			return nil, err
+		}
+		for from, to := range m {
+			opts.Homoglyphs[from] = to
+		}
+	}
+	return badword.NewNormalizer(opts), nil
+}
+
+// StopModeration 取消订阅词库变化的通知,停止后台加载
+func StopModeration() {
+	if moderation == nil {
+		return
+	}
+	if err := stopSensitiveWordsSub(); err != nil {
+		zap.L().Warn("unsubscribe sensitive word changes failed", zap.Error(err))
+	}
+	if err := moderation.Close(); err != nil {
+		zap.L().Warn("close moderation filter failed", zap.Error(err))
+	}
+}
+
+// moderateText 按分类的处理方式处理文本,没有初始化过滤器时不检查
+func moderateText(text string) badword.Verdict {
+	if moderation == nil {
+		return badword.Verdict{Text: text}
+	}
+	return moderation.Moderate(text)
+}
+
+// textField 需要检查的字段,屏蔽敏感词后的文本写回text
+type textField struct {
+	name string
+	text *string
+}
+
+// moderateFields 处理各个字段中的敏感词,target 用于按业务统计,如 post、comment
+// 任何字段命中需要拒绝的敏感词时返回 *SensitiveWordError,字段保持不变;
+// 否则在字段中屏蔽敏感词,并返回是否需要交给版主审核
+func moderateFields(moderate func(string) badword.Verdict, target string, fields ...textField) (review bool, err error) {
+	verdicts := make([]badword.Verdict, len(fields))
+	policy := badword.Policy("")
+	for i, f := range fields {
+		v := moderate(*f.text)
+		if v.Policy == badword.PolicyReject {
+			metrics.SensitiveWordRejections.WithLabelValues(target).Inc()
+			return false, &SensitiveWordError{Field: f.name, Category: v.Match.Category, Word: v.Match.Word}
+		}
+		if v.Policy == badword.PolicyReview || policy == "" {
+			policy = v.Policy
+		}
+		verdicts[i] = v
+	}
+	for i, f := range fields {
+		*f.text = verdicts[i].Text
+	}
+	if policy != "" {
+		metrics.SensitiveWordActions.WithLabelValues(target, string(policy)).Inc()
+	}
+	return policy == badword.PolicyReview, nil
+}
+
+// rejectReview 没有审核队列的内容,需要审核的敏感词按拒绝处理
+func rejectReview(moderate func(string) badword.Verdict) func(string) badword.Verdict {
+	return func(text string) badword.Verdict {
+		v := moderate(text)
+		if v.Policy == badword.PolicyReview {
+			v.Policy = badword.PolicyReject
+		}
+		return v
+	}
+}
+
+// checkUsername 用户名命中任何敏感词都拒绝
+func checkUsername(username string) error {
+	v := moderateText(username)
+	if len(v.Matches) == 0 {
+		return nil
+	}
+	metrics.SensitiveWordRejections.WithLabelValues("username").Inc()
+	m := v.Matches[0]
+	return &SensitiveWordError{Field: "username", Category: m.Category, Word: m.Word}
+}
+
+// queuePostReview 把帖子加入所在社区的审核队列,失败时只记录日志,帖子已经发布成功
+func queuePostReview(ctx context.Context, p *models.Post) {
+	if err := redis.AddPostReview(ctx, p.CommunityID, p.ID); err != nil {
+		logger.FromContext(ctx).Error("redis.AddPostReview failed",
+			zap.Int64("community_id", p.CommunityID),
+			zap.Int64("post_id", p.ID),
+			zap.Error(err))
+	}
+}
+
+// GetReviewPosts 查询社区审核队列中的帖子,先加入队列的在前
+func GetReviewPosts(ctx context.Context, communityID int64) ([]*models.ApiPostDetail, error) {
+	ids, err := redis.GetReviewPostIDs(ctx, communityID)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return []*models.ApiPostDetail{}, nil
+	}
+	data, _, err := NewPostQuery(SourceIDs(ids...)).Run(ctx)
+	return data, err
+}
+
+// ApprovePost 版主审核通过,把帖子移出审核队列,帖子不在队列中时返回 ErrorPostNotExist
+// 审核不通过时使用 ModeratePost 删除帖子,删除事件会把帖子移出队列
+func ApprovePost(ctx context.Context, communityID, postID int64) error {
+	found, err := redis.RemovePostReview(ctx, communityID, postID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrorPostNotExist
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/models"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	ErrorCommentNotExist   = errors.New("评论不存在")
+	ErrorModeratorNotExist = errors.New("版主不存在")
+)
+
+// GetModeratorRole 查询用户在社区中的版主角色
+func GetModeratorRole(ctx context.Context, communityID, userID int64) (int8, error) {
+	return mysql.GetModeratorRole(ctx, communityID, userID)
+}
+
+// GetPostCommunityID 查询帖子所属的社区id
+func GetPostCommunityID(ctx context.Context, postID int64) (int64, error) {
+	post, err := mysql.GetPostById(ctx, postID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, mysql.ErrorInvalidID
+		}
+		return 0, err
+	}
+	return post.CommunityID, nil
+}
+
+// GetModerators 查询社区的版主列表
+func GetModerators(ctx context.Context, communityID int64) ([]*models.CommunityModerator, error) {
+	return mysql.GetCommunityModerators(ctx, communityID)
+}
+
+// AddModerator 为社区添加版主
+func AddModerator(ctx context.Context, communityID int64, p *models.ParamModerator) error {
+	// 社区和用户都必须存在
+	if _, err := mysql.GetCommunityDetailByID(ctx, communityID); err != nil {
+		return err
+	}
+	if _, err := mysql.GetUserById(ctx, p.UserID); err != nil {
+		if err == sql.ErrNoRows {
+			return mysql.ErrorUserNotExist
+		}
+		return err
+	}
+	role := p.Role
+	if role == models.ModeratorRoleNone {
+		role = models.ModeratorRoleModerator
+	}
+	return mysql.AddCommunityModerator(ctx, &models.CommunityModerator{
+		CommunityID: communityID,
+		UserID:      p.UserID,
+		Role:        role,
+	})
+}
+
+// RemoveModerator 移除社区版主,用户不是版主时返回 ErrorModeratorNotExist
+func RemoveModerator(ctx context.Context, communityID, userID int64) error {
+	found, err := mysql.RemoveCommunityModerator(ctx, communityID, userID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrorModeratorNotExist
+	}
+	return nil
+}
+
+// ModeratePost 版主删除社区内的帖子
+// 置顶记录和其他索引一起由后台任务根据删除事件清理
+func ModeratePost(ctx context.Context, communityID, postID int64) error {
+	if err := mysql.DeletePostWithOutbox(ctx, postID); err != nil {
+		return err
+	}
+	notifyOutbox()
+	return nil
+}
+
+// RemoveComment 版主删除帖子下的评论
+func RemoveComment(ctx context.Context, postID, commentID int64) error {
+	found, err := redis.RemoveComment(ctx, postID, commentID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrorCommentNotExist
+	}
+	invalidatePost(ctx, postID)
+	return nil
+}
+
+// PinPost 在社区内置顶帖子
+func PinPost(ctx context.Context, communityID, postID int64) error {
+	if err := redis.PinPost(ctx, communityID, postID); err != nil {
+		return err
+	}
+	// 帖子详情中包含置顶状态
+	invalidatePost(ctx, postID)
+	return nil
+}
+
+// UnpinPost 取消社区内帖子的置顶
+func UnpinPost(ctx context.Context, communityID, postID int64) error {
+	if err := redis.UnpinPost(ctx, communityID, postID); err != nil {
+		return err
+	}
+	invalidatePost(ctx, postID)
+	return nil
+}
+
+// BanUser 在社区内禁言用户
+func BanUser(ctx context.Context, communityID, operatorID int64, p *models.ParamBanUser) error {
+	ban := &models.CommunityBan{
+		CommunityID: communityID,
+		UserID:      p.UserID,
+		OperatorID:  operatorID,
+		Reason:      p.Reason,
+	}
+	if p.Duration > 0 {
+		expire := time.Now().Add(time.Duration(p.Duration) * time.Second)
+		ban.ExpireTime = &expire
+	}
+	return mysql.BanUserInCommunity(ctx, ban)
+}
+
+// UnbanUser 解除社区内的禁言
+func UnbanUser(ctx context.Context, communityID, userID int64) error {
+	return mysql.UnbanUserInCommunity(ctx, communityID, userID)
+}
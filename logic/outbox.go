@@ -0,0 +1,246 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/logger"
+	"bluebell/models"
+	"bluebell/pkg/metrics"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 帖子的写操作只写MySQL(帖子和post_outbox中的事件在同一个事务中),
+// 由OutboxRelay把事件同步到redis的索引、搜索索引和帖子缓存
+// 处理失败的事件按指数退避重试,事件的处理都是幂等的,重复处理不会有副作用
+// 多实例部署时每个实例都运行OutboxRelay,先领取一批事件再处理,同一个事件同一时间只由一个实例处理
+// 同一个帖子的事件可能由不同的实例同时处理,创建和修改事件在处理期间给帖子加共享锁,
+// 删除帖子需要等它处理完成,之后写入的删除事件一定在它之后生效,不会把已经删除的帖子重新加回索引
+
+const (
+	outboxInterval    = time.Second     // 没有新事件时的轮询间隔
+	outboxBatchSize   = 100             // 每次查询的事件数量
+	outboxMaxAttempts = 20              // 超过这个次数不再重试,需要管理员排查后重新加入队列
+	outboxMaxBackoff  = 5 * time.Minute // 最长的重试间隔
+	outboxLease       = 2 * time.Minute // 领取的事件超过这个时间没有处理完,其他实例可以重新领取
+)
+
+var outboxRelay = &OutboxRelay{
+	interval:    outboxInterval,
+	batchSize:   outboxBatchSize,
+	maxAttempts: outboxMaxAttempts,
+	wake:        make(chan struct{}, 1),
+}
+
+// OutboxRelay 处理post_outbox中事件的后台任务
+type OutboxRelay struct {
+	interval    time.Duration
+	batchSize   int
+	maxAttempts int
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// StartOutboxRelay 启动处理帖子变更事件的后台任务
+func StartOutboxRelay() {
+	outboxRelay.Start()
+}
+
+// StopOutboxRelay 停止后台任务,等待正在处理的事件完成
+func StopOutboxRelay() {
+	outboxRelay.Stop()
+}
+
+// Start 启动后台任务
+func (r *OutboxRelay) Start() {
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+	go r.loop()
+}
+
+// Stop 停止后台任务,可以重复调用
+func (r *OutboxRelay) Stop() {
+	if r.stop == nil {
+		return
+	}
+	r.once.Do(func() { close(r.stop) })
+	<-r.done
+}
+
+// Notify 有新事件写入时唤醒后台任务,不用等到下一次轮询
+func (r *OutboxRelay) Notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (r *OutboxRelay) loop() {
+	defer close(r.done)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		r.drain()
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+	}
+}
+
+// drain 处理所有到了处理时间的事件
+func (r *OutboxRelay) drain() {
+	ctx := context.Background()
+	for {
+		n, err := r.runOnce(ctx)
+		if err != nil {
+			zap.L().Error("OutboxRelay query events failed", zap.Error(err))
+			return
+		}
+		if n < r.batchSize {
+			return
+		}
+	}
+}
+
+// runOnce 处理一批事件,返回查询到的事件数量
+func (r *OutboxRelay) runOnce(ctx context.Context) (int, error) {
+	claimer, err := newOutboxClaimer()
+	if err != nil {
+		return 0, err
+	}
+	events, err := mysql.ClaimOutboxEvents(ctx, claimer, r.batchSize, r.maxAttempts, int64(outboxLease/time.Second))
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range events {
+		if err := applyOutboxEvent(ctx, e); err != nil {
+			r.fail(ctx, e, err)
+			continue
+		}
+		if err := mysql.MarkOutboxEventProcessed(ctx, e.ID); err != nil {
+			// 下次还会再处理一次,事件的处理是幂等的
+			logger.FromContext(ctx).Error("mysql.MarkOutboxEventProcessed failed", zap.Int64("id", e.ID), zap.Error(err))
+		}
+	}
+	return len(events), nil
+}
+
+func (r *OutboxRelay) fail(ctx context.Context, e *models.OutboxEvent, err error) {
+	attempts := e.Attempts + 1
+	fields := []zap.Field{
+		zap.Int64("id", e.ID),
+		zap.String("event_type", e.EventType),
+		zap.Int64("post_id", e.PostID),
+		zap.Int("attempts", attempts),
+		zap.Error(err),
+	}
+	if attempts >= r.maxAttempts {
+		metrics.OutboxEventsDead.WithLabelValues(e.EventType).Inc()
+		logger.FromContext(ctx).Error("outbox event gave up after max attempts", fields...)
+	} else {
+		logger.FromContext(ctx).Warn("apply outbox event failed", fields...)
+	}
+	retryAfter := outboxBackoff(attempts)
+	if err := mysql.MarkOutboxEventFailed(ctx, e.ID, err.Error(), int64(retryAfter/time.Second)); err != nil {
+		logger.FromContext(ctx).Error("mysql.MarkOutboxEventFailed failed", zap.Int64("id", e.ID), zap.Error(err))
+	}
+}
+
+// GetDeadOutboxEvents 分页查询不再自动重试的事件,管理员排查原因后用 RequeueOutboxEvents 重新加入队列
+func GetDeadOutboxEvents(ctx context.Context, page, size int64) ([]*models.OutboxEvent, error) {
+	return mysql.GetDeadOutboxEvents(ctx, outboxRelay.maxAttempts, page, size)
+}
+
+// RequeueOutboxEvents 把不再自动重试的事件重新加入处理队列,ids为空时重新加入所有这样的事件
+func RequeueOutboxEvents(ctx context.Context, ids []int64) (int64, error) {
+	n, err := mysql.RequeueDeadOutboxEvents(ctx, outboxRelay.maxAttempts, ids)
+	if err != nil {
+		return 0, err
+	}
+	if n > 0 {
+		notifyOutbox()
+	}
+	return n, nil
+}
+
+// outboxBackoff 第n次失败后的重试间隔: 1s, 2s, 4s ... 最长 outboxMaxBackoff
+func outboxBackoff(attempts int) time.Duration {
+	if attempts > 16 {
+		return outboxMaxBackoff
+	}
+	d := time.Second << (attempts - 1)
+	if d > outboxMaxBackoff {
+		d = outboxMaxBackoff
+	}
+	return d
+}
+
+// newOutboxClaimer 每次领取事件使用不同的随机标识
+func newOutboxClaimer() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// notifyOutbox 唤醒后台任务处理刚写入的事件
+func notifyOutbox() {
+	outboxRelay.Notify()
+}
+
+// applyOutboxEvent 把事件同步到redis、搜索索引和帖子缓存
+// 创建和修改事件按帖子当前的数据处理,帖子已经被删除时由之后的删除事件负责清理
+// 更新搜索索引失败时也返回错误,和redis一样按退避时间重试
+// 持有帖子的共享锁期间只写redis和搜索索引,保证删除事件一定在这之后处理;帖子的作者不会变化,在加锁之前查询
+func applyOutboxEvent(ctx context.Context, e *models.OutboxEvent) error {
+	switch e.EventType {
+	case models.OutboxPostCreated, models.OutboxPostUpdated:
+		authorName, err := postAuthorName(ctx, e.PostID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		err = mysql.WithPostShareLocked(ctx, e.PostID, func(post *models.Post) error {
+			if e.EventType == models.OutboxPostCreated {
+				if err := redis.AddPostToIndex(ctx, post.ID, post.CommunityID, post.CreateTime); err != nil {
+					return err
+				}
+			}
+			return indexPost(ctx, post, authorName)
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	case models.OutboxPostDeleted:
+		if err := redis.RemovePostFromIndex(ctx, e.PostID, e.CommunityID); err != nil {
+			return err
+		}
+		if err := unindexPost(ctx, e.PostID); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown outbox event type %q", e.EventType)
+	}
+	invalidatePost(ctx, e.PostID)
+	return nil
+}
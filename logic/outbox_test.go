@@ -0,0 +1,37 @@
+package logic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOutboxBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{5, 16 * time.Second},
+		{9, 256 * time.Second},
+		{10, outboxMaxBackoff},
+		{100, outboxMaxBackoff},
+	}
+	for _, tt := range tests {
+		if got := outboxBackoff(tt.attempts); got != tt.want {
+			t.Errorf("outboxBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestOutboxRelayNotify(t *testing.T) {
+	r := &OutboxRelay{wake: make(chan struct{}, 1)}
+	// 多次唤醒只保留一个信号,不会阻塞
+	r.Notify()
+	r.Notify()
+	if len(r.wake) != 1 {
+		t.Fatalf("len(wake) = %d, want 1", len(r.wake))
+	}
+	// 没有启动时停止不会阻塞
+	r.Stop()
+}
@@ -3,9 +3,13 @@ package logic
 import (
 	"bluebell/dao/mysql"
 	"bluebell/dao/redis"
+	"bluebell/logger"
 	"bluebell/models"
+	"bluebell/pkg/metrics"
 	"bluebell/pkg/snowflake"
-	"fmt"
+	"context"
+	"database/sql"
+	"errors"
 	"mime/multipart"
 	"strconv"
 	"time"
@@ -15,241 +19,174 @@ import (
 	"go.uber.org/zap"
 )
 
-func CreatePost(p *models.Post) (err error) {
-	// 1. 生成post id
-	p.ID = snowflake.GenID()
-	// 2. 保存到数据库
-	err = mysql.CreatePost(p)
-	if err != nil {
-		return err
-	}
-	err = redis.CreatePost(p.ID, p.CommunityID)
-	return
-	// 3. 返回
+var ErrorNotPostAuthor = errors.New("不是帖子的作者")
+
+func CreatePost(ctx context.Context, p *models.Post) (err error) {
+	return std.CreatePost(ctx, p)
 }
 
-// GetPostById 根据帖子id查询帖子详情数据
-func GetPostById(pid int64) (data *models.ApiPostDetail, err error) {
-	// 查询并组合我们接口想用的数据
-	post, err := mysql.GetPostById(pid)
-	if err != nil {
-		zap.L().Error("mysql.GetPostById(pid) failed",
-			zap.Int64("pid", pid),
-			zap.Error(err))
-		return
-	}
-	// 根据作者id查询作者信息
-	user, err := mysql.GetUserById(post.AuthorID)
+// CreatePost 发布帖子
+func (s *Service) CreatePost(ctx context.Context, p *models.Post) (err error) {
+	review, err := moderateFields(s.moderate, "post",
+		textField{"title", &p.Title},
+		textField{"content", &p.Content})
 	if err != nil {
-		zap.L().Error("mysql.GetUserById(post.AuthorID) failed",
-			zap.Int64("author_id", post.AuthorID),
-			zap.Error(err))
-		return
+		return err
 	}
-	// 根据社区id查询社区详细信息
-	community, err := mysql.GetCommunityDetailByID(post.CommunityID)
+	// 1. 生成post id
+	p.ID = s.genID()
+	// 2. 保存到数据库,redis中的索引和搜索索引由后台任务根据事件更新
+	err = s.posts.CreatePostWithOutbox(ctx, p)
 	if err != nil {
-		zap.L().Error("mysql.GetUserById(post.AuthorID) failed",
-			zap.Int64("community_id", post.CommunityID),
-			zap.Error(err))
-		return
+		return err
 	}
-	// 接口数据拼接
-	data = &models.ApiPostDetail{
-		AuthorName:      user.Username,
-		Post:            post,
-		CommunityDetail: community,
+	metrics.PostsCreated.Inc()
+	s.notify()
+	if review {
+		s.queueReview(ctx, p)
 	}
 	return
 }
 
-// GetPostList 获取帖子列表
-func GetPostList(page, size int64) (data []*models.ApiPostDetail, err error) {
-	posts, err := mysql.GetPostList(page, size)
+// GetPostById 根据帖子id查询帖子详情数据,帖子不存在时返回 ErrorPostNotExist
+// 详情数据经过缓存,只有当前用户的投票是每次单独查询的
+func GetPostById(ctx context.Context, pid, viewerID int64) (data *models.ApiPostDetail, err error) {
+	cached, err := postCache.Get(ctx, pid)
 	if err != nil {
 		return nil, err
 	}
-	data = make([]*models.ApiPostDetail, 0, len(posts))
-
-	for _, post := range posts {
-		// 根据作者id查询作者信息
-		user, err := mysql.GetUserById(post.AuthorID)
-		if err != nil {
-			zap.L().Error("mysql.GetUserById(post.AuthorID) failed",
-				zap.Int64("author_id", post.AuthorID),
-				zap.Error(err))
-			continue
-		}
-		// 根据社区id查询社区详细信息
-		community, err := mysql.GetCommunityDetailByID(post.CommunityID)
-		if err != nil {
-			zap.L().Error("mysql.GetUserById(post.AuthorID) failed",
-				zap.Int64("community_id", post.CommunityID),
-				zap.Error(err))
-			continue
-		}
-		postDetail := &models.ApiPostDetail{
-			AuthorName:      user.Username,
-			Post:            post,
-			CommunityDetail: community,
-		}
-		data = append(data, postDetail)
+	// 缓存中的数据被多个请求共享,复制一份再填充当前用户的投票
+	detail := *cached
+	q := NewPostQuery(nil).Viewer(viewerID)
+	list, err := EnrichMyVote.enrich(ctx, q, []*models.ApiPostDetail{&detail})
+	if err != nil {
+		return nil, err
 	}
-	return
+	return list[0], nil
 }
 
-func GetPostList2(p *models.ParamPostList) (data []*models.ApiPostDetail, err error) {
-	// 2. 去redis查询id列表
-	ids, err := redis.GetPostIDsInOrder(p)
+// EditPost 作者修改自己的帖子
+func EditPost(ctx context.Context, userID, postID int64, p *models.ParamEditPost) error {
+	review, err := moderateFields(std.moderate, "post",
+		textField{"title", &p.Title},
+		textField{"content", &p.Content})
 	if err != nil {
-		return
-	}
-	if len(ids) == 0 {
-		zap.L().Warn("redis.GetPostIDsInOrder(p) return 0 data")
-		return
+		return err
 	}
-	zap.L().Debug("GetPostList2", zap.Any("ids", ids))
-	// 3. 根据id去MySQL数据库查询帖子详细信息
-	// 返回的数据还要按照我给定的id的顺序返回
-	posts, err := mysql.GetPostListByIDs(ids)
+	post, err := mysql.GetPostById(ctx, postID)
 	if err != nil {
-		return
+		if err == sql.ErrNoRows {
+			return ErrorPostNotExist
+		}
+		return err
 	}
-	//zap.L().Debug("GetPostList2", zap.Any("posts", posts))
-	// 提前查询好每篇帖子的投票数
-	voteData, err := redis.GetPostVoteData(ids)
-	if err != nil {
-		return
+	if post.AuthorID != userID {
+		return ErrorNotPostAuthor
 	}
-
-	// 将帖子的作者及分区信息查询出来填充到帖子中
-	for idx, post := range posts {
-		// 根据作者id查询作者信息
-		user, err := mysql.GetUserById(post.AuthorID)
-		if err != nil {
-			zap.L().Error("mysql.GetUserById(post.AuthorID) failed",
-				zap.Int64("author_id", post.AuthorID),
-				zap.Error(err))
-			continue
-		}
-		// 根据社区id查询社区详细信息
-		community, err := mysql.GetCommunityDetailByID(post.CommunityID)
-		if err != nil {
-			zap.L().Error("mysql.GetUserById(post.AuthorID) failed",
-				zap.Int64("community_id", post.CommunityID),
-				zap.Error(err))
-			continue
-		}
-		postDetail := &models.ApiPostDetail{
-			AuthorName:      user.Username,
-			VoteNum:         voteData[idx],
-			Post:            post,
-			CommunityDetail: community,
-		}
-		data = append(data, postDetail)
+	post.Title, post.Content = p.Title, p.Content
+	if err := mysql.UpdatePostWithOutbox(ctx, post); err != nil {
+		return err
 	}
-	return
+	notifyOutbox()
+	if review {
+		std.queueReview(ctx, post)
+	}
+	return nil
+}
 
+// GetPostList 获取全站最新的帖子列表
+// 带游标或请求第一页时按游标分页并返回下一页的游标,否则按页码分页(兼容旧的客户端)
+func GetPostList(ctx context.Context, page, size int64, cursorStr string, viewerID int64) (data []*models.ApiPostDetail, next string, err error) {
+	return std.GetPostList(ctx, page, size, cursorStr, viewerID)
 }
 
-func GetCommunityPostList(p *models.ParamPostList) (data []*models.ApiPostDetail, err error) {
-	// 2. 去redis查询id列表
-	ids, err := redis.GetCommunityPostIDsInOrder(p)
-	if err != nil {
-		return
-	}
-	if len(ids) == 0 {
-		zap.L().Warn("redis.GetPostIDsInOrder(p) return 0 data")
-		return
-	}
-	zap.L().Debug("GetCommunityPostIDsInOrder", zap.Any("ids", ids))
-	// 3. 根据id去MySQL数据库查询帖子详细信息
-	// 返回的数据还要按照我给定的id的顺序返回
-	posts, err := mysql.GetPostListByIDs(ids)
-	if err != nil {
-		return
-	}
-	zap.L().Debug("GetPostList2", zap.Any("posts", posts))
-	// 提前查询好每篇帖子的投票数
-	voteData, err := redis.GetPostVoteData(ids)
-	if err != nil {
-		return
-	}
+// GetPostList 获取全站最新的帖子列表
+func (s *Service) GetPostList(ctx context.Context, page, size int64, cursorStr string, viewerID int64) (data []*models.ApiPostDetail, next string, err error) {
+	return s.NewPostQuery(SourceRecent()).
+		Paginate(cursorStr, page, size).
+		Viewer(viewerID).
+		Run(ctx)
+}
 
-	// 将帖子的作者及分区信息查询出来填充到帖子中
-	for idx, post := range posts {
-		// 根据作者id查询作者信息
-		user, err := mysql.GetUserById(post.AuthorID)
-		if err != nil {
-			zap.L().Error("mysql.GetUserById(post.AuthorID) failed",
-				zap.Int64("author_id", post.AuthorID),
-				zap.Error(err))
-			continue
-		}
-		// 根据社区id查询社区详细信息
-		community, err := mysql.GetCommunityDetailByID(post.CommunityID)
-		if err != nil {
-			zap.L().Error("mysql.GetUserById(post.AuthorID) failed",
-				zap.Int64("community_id", post.CommunityID),
-				zap.Error(err))
-			continue
-		}
-		postDetail := &models.ApiPostDetail{
-			AuthorName:      user.Username,
-			VoteNum:         voteData[idx],
-			Post:            post,
-			CommunityDetail: community,
-		}
-		data = append(data, postDetail)
-	}
-	return
+// GetPostListNew 按作者、社区或全站查询帖子列表
+func GetPostListNew(ctx context.Context, p *models.ParamPostList, viewerID int64) (data []*models.ApiPostDetail, next string, err error) {
+	return std.GetPostListNew(ctx, p, viewerID)
 }
 
-// GetPostListNew  将两个查询帖子列表逻辑合二为一的函数
-func GetPostListNew(p *models.ParamPostList) (data []*models.ApiPostDetail, err error) {
-	// 根据请求参数的不同，执行不同的逻辑。
-	if p.CommunityID == 0 {
-		// 查所有
-		data, err = GetPostList2(p)
-		fmt.Println(data)
-	} else {
-		// 根据社区id查询
-		data, err = GetCommunityPostList(p)
-	}
+// GetPostListNew 按作者、社区或全站查询帖子列表
+func (s *Service) GetPostListNew(ctx context.Context, p *models.ParamPostList, viewerID int64) (data []*models.ApiPostDetail, next string, err error) {
+	// 根据请求参数的不同，选择不同的帖子来源
+	var source PostSource
+	switch {
+	case p.AuthorID != 0:
+		source = SourceAuthor(p.AuthorID)
+	case p.CommunityID != 0:
+		source = SourceCommunity(p.CommunityID)
+	default:
+		source = SourceGlobal()
+	}
+	data, next, err = s.postListQuery(source, p, viewerID).Run(ctx)
 	if err != nil {
-		zap.L().Error("GetPostListNew failed", zap.Error(err))
-		return nil, err
+		logger.FromContext(ctx).Error("GetPostListNew failed", zap.Error(err))
+		return nil, "", err
 	}
 	return
 }
 
-func PostAvatar(c *gin.Context, id string, file *multipart.FileHeader) {
+// GetFeed 查询用户关注的社区内的帖子列表
+func GetFeed(ctx context.Context, userID int64, p *models.ParamPostList) (data []*models.ApiPostDetail, next string, err error) {
+	return std.GetFeed(ctx, userID, p)
+}
+
+// GetFeed 查询用户关注的社区内的帖子列表
+func (s *Service) GetFeed(ctx context.Context, userID int64, p *models.ParamPostList) (data []*models.ApiPostDetail, next string, err error) {
+	return s.postListQuery(SourceFollowed(userID), p, userID).Run(ctx)
+}
+
+func (s *Service) postListQuery(source PostSource, p *models.ParamPostList, viewerID int64) *PostQuery {
+	return s.NewPostQuery(source).
+		Order(p.Order).
+		Paginate(p.Cursor, p.Page, p.Size).
+		Viewer(viewerID)
+}
+
+func PostAvatar(c *gin.Context, id int64, file *multipart.FileHeader) error {
 	// 将file保存到本地
 	fileName := "./uploadfile/" + strconv.FormatInt(time.Now().Unix(), 10) + file.Filename
-	err := c.SaveUploadedFile(file, fileName)
-	if err != nil {
-		zap.Error(err)
-		return
+	if err := c.SaveUploadedFile(file, fileName); err != nil {
+		logger.FromContext(c.Request.Context()).Error("c.SaveUploadedFile failed", zap.Int64("user_id", id), zap.Error(err))
+		return err
 	}
 	// 将保存后的文件本地路径保存到用户表的头像字段
-	mysql.UploadAvatar(id, fileName[1:])
-	//返回响应
-	return
+	if err := mysql.UploadAvatar(c.Request.Context(), id, fileName[1:]); err != nil {
+		logger.FromContext(c.Request.Context()).Error("mysql.UploadAvatar failed", zap.Int64("user_id", id), zap.Error(err))
+		return err
+	}
+	InvalidateUserCache(c.Request.Context(), id)
+	return nil
 }
 
-func GetPostByTitle(title string) (post []*models.Post, err error) {
-	post, err = mysql.GetPostsByTitle(title)
+func GetPostByTitle(ctx context.Context, title string) (post []*models.Post, err error) {
+	post, err = mysql.GetPostsByTitle(ctx, title)
 	if err != nil {
-		zap.L().Error("mysql.GetPostByTitle(title) invalied params", zap.Error(err))
+		logger.FromContext(ctx).Error("mysql.GetPostByTitle(title) invalied params", zap.Error(err))
 		return
 	}
 	return
 }
 
-func PostComment(comment *models.Comment) error {
-	err := redis.AddComment(comment)
+func PostComment(ctx context.Context, comment *models.Comment) error {
+	if _, err := moderateFields(rejectReview(moderateText), "comment", textField{"content", &comment.Content}); err != nil {
+		return err
+	}
+	// 生成评论id,版主删除评论时使用
+	comment.CommentID = snowflake.GenID()
+	err := redis.AddComment(ctx, comment)
 	if err != nil {
 		return err
 	}
-	return err
+	metrics.CommentsCreated.Inc()
+	// 帖子详情中包含评论数
+	invalidatePost(ctx, comment.ID)
+	return nil
 }
@@ -0,0 +1,201 @@
+package logic
+
+import (
+	"bluebell/dao/redis"
+	"bluebell/logger"
+	"bluebell/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+// 帖子详情的旁路缓存:
+// 	1. 先查redis,命中直接返回
+// 	2. 未命中时用singleflight合并同一个帖子的并发请求,只有一个请求去MySQL组装数据
+// 	3. 过期时间加上随机抖动,避免同一时间写入的缓存同时过期
+// 	4. 不存在的帖子也缓存一小段时间,避免不断用无效的id打到MySQL
+// 帖子被修改、删除、投票或评论时删除缓存
+// 删除缓存时正在进行的加载可能已经读到了旧数据,加载完成后写回就会把旧数据重新放进缓存:
+// 	- 本实例的加载: 删除时增加帖子的版本号,加载前后版本号不一致时不写回
+// 	- 其他实例的加载: 过一小段时间后再删除一次
+
+var ErrorPostNotExist = errors.New("帖子不存在")
+
+const (
+	postCacheTTL         = 5 * time.Minute
+	postCacheJitter      = time.Minute
+	postCacheNegativeTTL = 30 * time.Second
+	postCacheNotFound    = "none" // 缓存"帖子不存在"的状态
+	postCacheRedelete    = time.Second
+	postCacheGenerations = 1024 // 版本号按帖子id分组,同一组的帖子共用版本号,多跳过的写回只会让下次请求重新加载
+)
+
+var postCache = &PostCache{
+	ttl:         postCacheTTL,
+	jitter:      postCacheJitter,
+	negativeTTL: postCacheNegativeTTL,
+	redelete:    postCacheRedelete,
+	getCached:   redis.GetCachedPostDetail,
+	setCached:   redis.SetCachedPostDetail,
+	delCached:   redis.DeleteCachedPostDetail,
+	load:        loadPostDetail,
+}
+
+// PostCache 帖子详情缓存
+type PostCache struct {
+	ttl         time.Duration
+	jitter      time.Duration
+	negativeTTL time.Duration
+	redelete    time.Duration // 删除缓存后再删除一次的延迟,为0时不再删除
+
+	getCached func(ctx context.Context, postID int64) (string, bool, error)
+	setCached func(ctx context.Context, postID int64, val string, ttl time.Duration) error
+	delCached func(ctx context.Context, postID int64) error
+	// load 组装帖子详情,帖子不存在时返回 ErrorPostNotExist
+	load func(ctx context.Context, postID int64) (*models.ApiPostDetail, error)
+
+	group       singleflight.Group
+	generations [postCacheGenerations]atomic.Uint64
+
+	hits         atomic.Int64
+	negativeHits atomic.Int64
+	misses       atomic.Int64
+	loads        atomic.Int64
+}
+
+// PostCacheStats 帖子详情缓存的命中情况
+type PostCacheStats struct {
+	Hits         int64 `json:"hits"`          // 命中的次数
+	NegativeHits int64 `json:"negative_hits"` // 命中"帖子不存在"的次数
+	Misses       int64 `json:"misses"`        // 未命中的次数
+	Loads        int64 `json:"loads"`         // 实际查询MySQL的次数,并发的未命中会被合并
+}
+
+// Get 查询帖子详情,返回的数据可能被多个请求共享,调用方不能修改
+func (c *PostCache) Get(ctx context.Context, postID int64) (*models.ApiPostDetail, error) {
+	val, ok, err := c.getCached(ctx, postID)
+	if err != nil {
+		// redis出错时直接查询MySQL
+		logger.FromContext(ctx).Warn("PostCache get cached failed", zap.Int64("post_id", postID), zap.Error(err))
+	}
+	if ok {
+		if val == postCacheNotFound {
+			c.negativeHits.Add(1)
+			return nil, ErrorPostNotExist
+		}
+		detail := new(models.ApiPostDetail)
+		if err := json.Unmarshal([]byte(val), detail); err == nil {
+			c.hits.Add(1)
+			return detail, nil
+		}
+		// 格式不对的缓存当作未命中处理,重新加载后会被覆盖
+	}
+	c.misses.Add(1)
+
+	// 合并的请求共用第一个请求的ctx,去掉取消信号,第一个请求被取消时不影响其他请求
+	loadCtx := context.WithoutCancel(ctx)
+	v, err, _ := c.group.Do(strconv.FormatInt(postID, 10), func() (interface{}, error) {
+		return c.loadAndStore(loadCtx, postID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*models.ApiPostDetail), nil
+}
+
+func (c *PostCache) loadAndStore(ctx context.Context, postID int64) (*models.ApiPostDetail, error) {
+	c.loads.Add(1)
+	gen := c.generation(postID).Load()
+	detail, err := c.load(ctx, postID)
+	if errors.Is(err, ErrorPostNotExist) {
+		c.store(ctx, postID, gen, postCacheNotFound, c.negativeTTL)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(detail)
+	if err != nil {
+		return nil, err
+	}
+	ttl := c.ttl
+	if c.jitter > 0 {
+		ttl += time.Duration(rand.Int63n(int64(c.jitter)))
+	}
+	c.store(ctx, postID, gen, string(data), ttl)
+	return detail, nil
+}
+
+// store 写回加载的数据,加载期间缓存被删除过(版本号不再是gen)时不写回
+func (c *PostCache) store(ctx context.Context, postID int64, gen uint64, val string, ttl time.Duration) {
+	if c.generation(postID).Load() != gen {
+		return
+	}
+	if err := c.setCached(ctx, postID, val, ttl); err != nil {
+		logger.FromContext(ctx).Warn("PostCache set cached failed", zap.Int64("post_id", postID), zap.Error(err))
+	}
+}
+
+// generation 帖子所在分组的版本号
+func (c *PostCache) generation(postID int64) *atomic.Uint64 {
+	return &c.generations[uint64(postID)%postCacheGenerations]
+}
+
+// Invalidate 删除帖子详情的缓存
+func (c *PostCache) Invalidate(ctx context.Context, postID int64) {
+	// 正在进行的加载可能读到旧数据,不让它写回,也不让之后的请求共用它的结果
+	c.generation(postID).Add(1)
+	c.group.Forget(strconv.FormatInt(postID, 10))
+	c.deleteCached(ctx, postID)
+	if c.redelete > 0 {
+		ctx := context.WithoutCancel(ctx)
+		time.AfterFunc(c.redelete, func() { c.deleteCached(ctx, postID) })
+	}
+}
+
+func (c *PostCache) deleteCached(ctx context.Context, postID int64) {
+	if err := c.delCached(ctx, postID); err != nil {
+		logger.FromContext(ctx).Warn("PostCache delete cached failed", zap.Int64("post_id", postID), zap.Error(err))
+	}
+}
+
+// Stats 返回缓存的命中情况
+func (c *PostCache) Stats() PostCacheStats {
+	return PostCacheStats{
+		Hits:         c.hits.Load(),
+		NegativeHits: c.negativeHits.Load(),
+		Misses:       c.misses.Load(),
+		Loads:        c.loads.Load(),
+	}
+}
+
+// GetPostCacheStats 查询帖子详情缓存的命中情况
+func GetPostCacheStats() PostCacheStats {
+	return postCache.Stats()
+}
+
+// invalidatePost 帖子变化后删除帖子详情的缓存
+func invalidatePost(ctx context.Context, postID int64) {
+	postCache.Invalidate(ctx, postID)
+}
+
+// loadPostDetail 从MySQL和redis组装帖子详情,不包括当前用户的投票
+func loadPostDetail(ctx context.Context, postID int64) (*models.ApiPostDetail, error) {
+	data, _, err := NewPostQuery(SourceIDs(postID)).
+		Enrich(EnrichAuthor, EnrichCommunity, EnrichVotes, EnrichCommentCount).
+		Run(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, ErrorPostNotExist
+	}
+	return data[0], nil
+}
@@ -0,0 +1,184 @@
+package logic
+
+import (
+	"bluebell/models"
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryStore 模拟redis
+type memoryStore struct {
+	mu   sync.Mutex
+	data map[int64]string
+}
+
+func (s *memoryStore) get(_ context.Context, postID int64) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	val, ok := s.data[postID]
+	return val, ok, nil
+}
+
+func (s *memoryStore) set(_ context.Context, postID int64, val string, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[postID] = val
+	return nil
+}
+
+func (s *memoryStore) del(_ context.Context, postID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, postID)
+	return nil
+}
+
+func newTestPostCache(load func(context.Context, int64) (*models.ApiPostDetail, error)) *PostCache {
+	store := &memoryStore{data: make(map[int64]string)}
+	return &PostCache{
+		ttl:       time.Minute,
+		getCached: store.get,
+		setCached: store.set,
+		delCached: store.del,
+		load:      load,
+	}
+}
+
+func TestPostCacheSingleflight(t *testing.T) {
+	release := make(chan struct{})
+	c := newTestPostCache(func(_ context.Context, id int64) (*models.ApiPostDetail, error) {
+		<-release
+		return &models.ApiPostDetail{AuthorName: "user", Post: &models.Post{ID: id}}, nil
+	})
+
+	const n = 20
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			detail, err := c.Get(context.Background(), 1)
+			if err != nil || detail.AuthorName != "user" {
+				t.Errorf("Get(1) = %+v, %v", detail, err)
+			}
+		}()
+	}
+	// 等所有请求都未命中后再放行加载
+	for c.Stats().Misses < n {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if got := c.Stats().Loads; got != 1 {
+		t.Fatalf("Loads = %d, want 1", got)
+	}
+	if _, err := c.Get(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Stats().Hits; got != 1 {
+		t.Fatalf("Hits = %d, want 1", got)
+	}
+}
+
+func TestPostCacheNegative(t *testing.T) {
+	c := newTestPostCache(func(_ context.Context, id int64) (*models.ApiPostDetail, error) {
+		return nil, ErrorPostNotExist
+	})
+	for i := 0; i < 3; i++ {
+		if _, err := c.Get(context.Background(), 1); err != ErrorPostNotExist {
+			t.Fatalf("Get(1) err = %v, want ErrorPostNotExist", err)
+		}
+	}
+	stats := c.Stats()
+	if stats.Loads != 1 || stats.NegativeHits != 2 {
+		t.Fatalf("stats = %+v, want 1 load and 2 negative hits", stats)
+	}
+}
+
+func TestPostCacheInvalidate(t *testing.T) {
+	title := "v1"
+	c := newTestPostCache(func(_ context.Context, id int64) (*models.ApiPostDetail, error) {
+		return &models.ApiPostDetail{Post: &models.Post{ID: id, Title: title}}, nil
+	})
+	if detail, _ := c.Get(context.Background(), 1); detail.Title != "v1" {
+		t.Fatalf("Title = %q, want v1", detail.Title)
+	}
+	title = "v2"
+	if detail, _ := c.Get(context.Background(), 1); detail.Title != "v1" {
+		t.Fatalf("Title = %q, want cached v1", detail.Title)
+	}
+	c.Invalidate(context.Background(), 1)
+	if detail, _ := c.Get(context.Background(), 1); detail.Title != "v2" {
+		t.Fatalf("Title = %q, want v2", detail.Title)
+	}
+}
+
+func TestPostCacheInvalidateDuringLoad(t *testing.T) {
+	var mu sync.Mutex
+	title := "v1"
+	started := make(chan struct{})
+	release := make(chan struct{})
+	c := newTestPostCache(func(_ context.Context, id int64) (*models.ApiPostDetail, error) {
+		mu.Lock()
+		detail := &models.ApiPostDetail{Post: &models.Post{ID: id, Title: title}}
+		mu.Unlock()
+		select {
+		case started <- struct{}{}:
+			<-release
+		default:
+		}
+		return detail, nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if detail, err := c.Get(context.Background(), 1); err != nil || detail.Title != "v1" {
+			t.Errorf("Get(1) = %+v, %v", detail, err)
+		}
+	}()
+	<-started
+	// 加载已经读到v1,这时帖子被修改并删除缓存
+	mu.Lock()
+	title = "v2"
+	mu.Unlock()
+	c.Invalidate(context.Background(), 1)
+	close(release)
+	<-done
+
+	// 旧的加载结果没有写回缓存
+	if detail, _ := c.Get(context.Background(), 1); detail.Title != "v2" {
+		t.Fatalf("Title = %q, want v2", detail.Title)
+	}
+	if got := c.Stats().Loads; got != 2 {
+		t.Fatalf("Loads = %d, want 2", got)
+	}
+}
+
+func TestPostCacheRedelete(t *testing.T) {
+	c := newTestPostCache(func(_ context.Context, id int64) (*models.ApiPostDetail, error) {
+		return &models.ApiPostDetail{Post: &models.Post{ID: id, Title: "v2"}}, nil
+	})
+	c.redelete = 10 * time.Millisecond
+	c.Invalidate(context.Background(), 1)
+	// 模拟其他实例在删除之后写回了旧数据
+	if err := c.setCached(context.Background(), 1, `{"title":"v1"}`, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, ok, _ := c.getCached(context.Background(), 1); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("stale value not deleted again")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if detail, _ := c.Get(context.Background(), 1); detail.Title != "v2" {
+		t.Fatalf("Title = %q, want v2", detail.Title)
+	}
+}
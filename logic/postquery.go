@@ -0,0 +1,454 @@
+package logic
+
+import (
+	"bluebell/logger"
+	"bluebell/models"
+	"bluebell/pkg/cursor"
+	"bluebell/pkg/tracing"
+	"context"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// 所有帖子列表接口共用的查询流水线:
+// 	1. PostSource 按排序依据和游标取出一页帖子id(或者直接取出帖子数据)
+// 	2. 按id批量查询帖子数据,保持来源给出的顺序,已经被删除的帖子直接跳过
+// 	3. 依次执行 PostEnricher 补充作者、社区、投票等数据
+//
+// 例如查询社区内按分数排序的帖子:
+// 	NewPostQuery(SourceCommunity(id)).Order(models.OrderScore).Paginate(cursor, page, size).Viewer(userID).Run(ctx)
+
+// PostQuery 帖子列表查询
+type PostQuery struct {
+	svc       *Service
+	source    PostSource
+	order     string
+	cursor    string
+	page      int64
+	size      int64
+	viewerID  int64
+	enrichers []PostEnricher
+}
+
+// NewPostQuery 使用默认的 Service 创建帖子列表查询
+func NewPostQuery(source PostSource) *PostQuery {
+	return std.NewPostQuery(source)
+}
+
+// NewPostQuery 创建帖子列表查询,默认按时间排序,使用 DefaultEnrichers 补充数据
+func (s *Service) NewPostQuery(source PostSource) *PostQuery {
+	return &PostQuery{
+		svc:       s,
+		source:    source,
+		order:     models.OrderTime,
+		page:      1,
+		size:      cursor.DefaultSize,
+		enrichers: DefaultEnrichers,
+	}
+}
+
+// Order 设置排序依据,models.OrderTime 或 models.OrderScore,不支持按分数排序的来源会忽略这个设置
+func (q *PostQuery) Order(order string) *PostQuery {
+	if order != "" {
+		q.order = order
+	}
+	return q
+}
+
+// Paginate 设置分页参数,cursorStr为空且page大于1时按页码分页(兼容旧的客户端)
+func (q *PostQuery) Paginate(cursorStr string, page, size int64) *PostQuery {
+	q.cursor = cursorStr
+	q.page = page
+	q.size = cursor.ClampSize(size)
+	return q
+}
+
+// Viewer 设置当前登录的用户,未登录时为0
+func (q *PostQuery) Viewer(userID int64) *PostQuery {
+	q.viewerID = userID
+	return q
+}
+
+// Enrich 替换补充数据的步骤,按给定的顺序执行
+func (q *PostQuery) Enrich(enrichers ...PostEnricher) *PostQuery {
+	q.enrichers = enrichers
+	return q
+}
+
+// Run 执行查询,返回帖子列表及下一页的游标
+func (q *PostQuery) Run(ctx context.Context) (data []*models.ApiPostDetail, next string, err error) {
+	ctx, span := tracing.Start(ctx, "postquery.Run")
+	defer func() { tracing.End(span, err) }()
+	page, err := q.source.fetch(ctx, q)
+	if err != nil {
+		return nil, "", err
+	}
+	posts := page.posts
+	if posts == nil && len(page.ids) > 0 {
+		// 返回的数据还要按照给定的id的顺序返回
+		if posts, err = q.svc.posts.GetPostListByIDs(ctx, page.ids); err != nil {
+			return nil, "", err
+		}
+	}
+	data = make([]*models.ApiPostDetail, 0, len(posts))
+	for _, post := range posts {
+		data = append(data, &models.ApiPostDetail{
+			Pinned: page.pinned[strconv.FormatInt(post.ID, 10)],
+			Post:   post,
+		})
+	}
+	if len(data) == 0 {
+		return data, page.next, nil
+	}
+	for _, e := range q.enrichers {
+		if data, err = e.enrich(ctx, q, data); err != nil {
+			return nil, "", err
+		}
+	}
+	return data, page.next, nil
+}
+
+// legacyPage 是否按页码分页
+func (q *PostQuery) legacyPage() bool {
+	return q.cursor == "" && q.page > 1
+}
+
+// param 转换成redis层使用的参数
+func (q *PostQuery) param() *models.ParamPostList {
+	return &models.ParamPostList{
+		Cursor: q.cursor,
+		Page:   q.page,
+		Size:   q.size,
+		Order:  q.order,
+	}
+}
+
+// ---- 帖子来源 ----
+
+// PostSource 帖子的来源,由 SourceXXX 函数创建
+type PostSource interface {
+	fetch(ctx context.Context, q *PostQuery) (*sourcePage, error)
+}
+
+// sourcePage 来源返回的一页数据,posts为nil时按ids查询帖子数据
+type sourcePage struct {
+	ids    []string
+	posts  []*models.Post
+	pinned map[string]bool
+	next   string
+}
+
+// SourceRecent 全站最新的帖子,直接从MySQL按发帖时间查询,不支持按分数排序
+func SourceRecent() PostSource {
+	return recentSource{}
+}
+
+type recentSource struct{}
+
+func (recentSource) fetch(ctx context.Context, q *PostQuery) (*sourcePage, error) {
+	if q.legacyPage() {
+		posts, err := q.svc.posts.GetPostList(ctx, q.page, q.size)
+		return &sourcePage{posts: posts}, err
+	}
+	c, err := cursor.Decode(q.cursor)
+	if err != nil {
+		return nil, err
+	}
+	posts, nc, err := q.svc.posts.GetPostListByCursor(ctx, c, q.size)
+	return &sourcePage{posts: posts, next: nc.Encode()}, err
+}
+
+// SourceGlobal 全站的帖子
+func SourceGlobal() PostSource {
+	return globalSource{}
+}
+
+type globalSource struct{}
+
+func (globalSource) fetch(ctx context.Context, q *PostQuery) (*sourcePage, error) {
+	p := q.param()
+	if q.legacyPage() {
+		ids, err := q.svc.feed.GetPostIDsInOrder(ctx, p)
+		return &sourcePage{ids: ids}, err
+	}
+	c, err := cursor.Decode(q.cursor)
+	if err != nil {
+		return nil, err
+	}
+	ids, nc, err := q.svc.feed.GetPostIDsByCursor(ctx, p, c)
+	return &sourcePage{ids: ids, next: nc.Encode()}, err
+}
+
+// SourceCommunity 社区内的帖子,第一页把置顶的帖子放在最前面,后面的页不再重复出现置顶的帖子
+func SourceCommunity(communityID int64) PostSource {
+	return communitySource{communityID: communityID}
+}
+
+type communitySource struct {
+	communityID int64
+}
+
+func (s communitySource) fetch(ctx context.Context, q *PostQuery) (*sourcePage, error) {
+	p := q.param()
+	p.CommunityID = s.communityID
+	page := new(sourcePage)
+	if q.legacyPage() {
+		ids, err := q.svc.feed.GetCommunityPostIDsInOrder(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		page.ids = ids
+	} else {
+		c, err := cursor.Decode(q.cursor)
+		if err != nil {
+			return nil, err
+		}
+		ids, nc, err := q.svc.feed.GetCommunityPostIDsByCursor(ctx, p, c)
+		if err != nil {
+			return nil, err
+		}
+		page.ids, page.next = ids, nc.Encode()
+	}
+
+	pinnedIDs, err := q.svc.feed.GetPinnedPostIDs(ctx, s.communityID)
+	if err != nil {
+		logger.FromContext(ctx).Error("feed.GetPinnedPostIDs failed",
+			zap.Int64("community_id", s.communityID),
+			zap.Error(err))
+		return page, nil
+	}
+	page.pinned = make(map[string]bool, len(pinnedIDs))
+	for _, id := range pinnedIDs {
+		page.pinned[id] = true
+	}
+	if q.cursor == "" && q.page <= 1 {
+		page.ids = mergePinnedIDs(pinnedIDs, page.ids)
+	} else {
+		page.ids = removePinnedIDs(page.pinned, page.ids)
+	}
+	return page, nil
+}
+
+// SourceFollowed 用户关注的所有社区内的帖子
+func SourceFollowed(userID int64) PostSource {
+	return followedSource{userID: userID}
+}
+
+type followedSource struct {
+	userID int64
+}
+
+func (s followedSource) fetch(ctx context.Context, q *PostQuery) (*sourcePage, error) {
+	p := q.param()
+	if q.legacyPage() {
+		ids, err := q.svc.feed.GetFollowedPostIDsInOrder(ctx, s.userID, p)
+		return &sourcePage{ids: ids}, err
+	}
+	c, err := cursor.Decode(q.cursor)
+	if err != nil {
+		return nil, err
+	}
+	ids, nc, err := q.svc.feed.GetFollowedPostIDsByCursor(ctx, s.userID, p, c)
+	return &sourcePage{ids: ids, next: nc.Encode()}, err
+}
+
+// SourceAuthor 某个作者发布的帖子,从MySQL按发帖时间查询,只支持游标分页
+func SourceAuthor(authorID int64) PostSource {
+	return authorSource{authorID: authorID}
+}
+
+type authorSource struct {
+	authorID int64
+}
+
+func (s authorSource) fetch(ctx context.Context, q *PostQuery) (*sourcePage, error) {
+	if q.legacyPage() {
+		posts, err := q.svc.posts.GetPostListByAuthorPage(ctx, s.authorID, q.page, q.size)
+		return &sourcePage{posts: posts}, err
+	}
+	c, err := cursor.Decode(q.cursor)
+	if err != nil {
+		return nil, err
+	}
+	posts, nc, err := q.svc.posts.GetPostListByAuthor(ctx, s.authorID, c, q.size)
+	return &sourcePage{posts: posts, next: nc.Encode()}, err
+}
+
+// SourceIDs 指定id的帖子,按给定的顺序返回,不分页
+func SourceIDs(ids ...int64) PostSource {
+	return idsSource(ids)
+}
+
+type idsSource []int64
+
+func (s idsSource) fetch(_ context.Context, _ *PostQuery) (*sourcePage, error) {
+	ids := make([]string, 0, len(s))
+	for _, id := range s {
+		ids = append(ids, strconv.FormatInt(id, 10))
+	}
+	return &sourcePage{ids: ids}, nil
+}
+
+// mergePinnedIDs 把置顶的帖子id放在最前面,并去掉ids中重复的id
+func mergePinnedIDs(pinnedIDs, ids []string) []string {
+	if len(pinnedIDs) == 0 {
+		return ids
+	}
+	merged := make([]string, 0, len(pinnedIDs)+len(ids))
+	seen := make(map[string]bool, len(pinnedIDs))
+	for _, id := range pinnedIDs {
+		seen[id] = true
+		merged = append(merged, id)
+	}
+	for _, id := range ids {
+		if !seen[id] {
+			merged = append(merged, id)
+		}
+	}
+	return merged
+}
+
+// removePinnedIDs 去掉ids中置顶的帖子id
+func removePinnedIDs(pinned map[string]bool, ids []string) []string {
+	if len(pinned) == 0 {
+		return ids
+	}
+	res := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !pinned[id] {
+			res = append(res, id)
+		}
+	}
+	return res
+}
+
+// ---- 补充数据 ----
+
+// PostEnricher 为帖子列表补充数据,可以去掉无法补充完整的帖子
+type PostEnricher interface {
+	enrich(ctx context.Context, q *PostQuery, list []*models.ApiPostDetail) ([]*models.ApiPostDetail, error)
+}
+
+type enricherFunc func(ctx context.Context, q *PostQuery, list []*models.ApiPostDetail) ([]*models.ApiPostDetail, error)
+
+func (f enricherFunc) enrich(ctx context.Context, q *PostQuery, list []*models.ApiPostDetail) ([]*models.ApiPostDetail, error) {
+	return f(ctx, q, list)
+}
+
+var (
+	// EnrichAuthor 补充作者的用户名,作者不存在的帖子会被去掉
+	EnrichAuthor PostEnricher = enricherFunc(enrichAuthor)
+	// EnrichCommunity 补充社区详情,社区不存在的帖子会被去掉
+	EnrichCommunity PostEnricher = enricherFunc(enrichCommunity)
+	// EnrichVotes 补充赞成票数
+	EnrichVotes PostEnricher = enricherFunc(enrichVotes)
+	// EnrichMyVote 补充当前用户的投票,未登录时跳过
+	EnrichMyVote PostEnricher = enricherFunc(enrichMyVote)
+	// EnrichCommentCount 补充评论数
+	EnrichCommentCount PostEnricher = enricherFunc(enrichCommentCount)
+
+	DefaultEnrichers = []PostEnricher{EnrichAuthor, EnrichCommunity, EnrichVotes, EnrichMyVote, EnrichCommentCount}
+)
+
+// 作者和社区信息整页去重后各自只批量查询一次,见 tieredCache
+func enrichAuthor(ctx context.Context, q *PostQuery, list []*models.ApiPostDetail) ([]*models.ApiPostDetail, error) {
+	ids := distinctIDs(list, func(d *models.ApiPostDetail) int64 { return d.AuthorID })
+	names, err := q.svc.usernameCache.loadMany(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	res := list[:0]
+	for _, d := range list {
+		name, ok := names[d.AuthorID]
+		if !ok {
+			logger.FromContext(ctx).Error("author of post not found",
+				zap.Int64("post_id", d.Post.ID),
+				zap.Int64("author_id", d.AuthorID))
+			continue
+		}
+		d.AuthorName = name
+		res = append(res, d)
+	}
+	return res, nil
+}
+
+func enrichCommunity(ctx context.Context, q *PostQuery, list []*models.ApiPostDetail) ([]*models.ApiPostDetail, error) {
+	ids := distinctIDs(list, func(d *models.ApiPostDetail) int64 { return d.Post.CommunityID })
+	communities, err := q.svc.communityCache.loadMany(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	res := list[:0]
+	for _, d := range list {
+		community, ok := communities[d.Post.CommunityID]
+		if !ok {
+			logger.FromContext(ctx).Error("community of post not found",
+				zap.Int64("post_id", d.Post.ID),
+				zap.Int64("community_id", d.Post.CommunityID))
+			continue
+		}
+		d.CommunityDetail = community
+		res = append(res, d)
+	}
+	return res, nil
+}
+
+func enrichVotes(ctx context.Context, q *PostQuery, list []*models.ApiPostDetail) ([]*models.ApiPostDetail, error) {
+	voteData, err := q.svc.votes.GetPostVoteData(ctx, postIDsOf(list))
+	if err != nil {
+		return nil, err
+	}
+	for i, d := range list {
+		d.VoteNum = voteData[i]
+	}
+	return list, nil
+}
+
+func enrichMyVote(ctx context.Context, q *PostQuery, list []*models.ApiPostDetail) ([]*models.ApiPostDetail, error) {
+	if q.viewerID == 0 {
+		return list, nil
+	}
+	votes, err := q.svc.votes.GetUserVotes(ctx, q.viewerID, postIDsOf(list))
+	if err != nil {
+		// 投票状态只影响展示,查询失败时不影响整个列表
+		logger.FromContext(ctx).Warn("votes.GetUserVotes failed", zap.Int64("user_id", q.viewerID), zap.Error(err))
+		return list, nil
+	}
+	for i, d := range list {
+		d.MyVote = votes[i]
+	}
+	return list, nil
+}
+
+func enrichCommentCount(ctx context.Context, q *PostQuery, list []*models.ApiPostDetail) ([]*models.ApiPostDetail, error) {
+	counts, err := q.svc.feed.GetPostCommentCounts(ctx, postIDsOf(list))
+	if err != nil {
+		logger.FromContext(ctx).Warn("feed.GetPostCommentCounts failed", zap.Error(err))
+		return list, nil
+	}
+	for i, d := range list {
+		d.CommentCount = counts[i]
+	}
+	return list, nil
+}
+
+func postIDsOf(list []*models.ApiPostDetail) []string {
+	ids := make([]string, 0, len(list))
+	for _, d := range list {
+		ids = append(ids, strconv.FormatInt(d.Post.ID, 10))
+	}
+	return ids
+}
+
+func distinctIDs(list []*models.ApiPostDetail, id func(*models.ApiPostDetail) int64) []int64 {
+	ids := make([]int64, 0, len(list))
+	seen := make(map[int64]bool, len(list))
+	for _, d := range list {
+		if v := id(d); !seen[v] {
+			seen[v] = true
+			ids = append(ids, v)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,54 @@
+package logic
+
+import (
+	"bluebell/models"
+	"context"
+	"reflect"
+	"testing"
+)
+
+type fakeSource struct {
+	posts []*models.Post
+}
+
+func (s fakeSource) fetch(_ context.Context, q *PostQuery) (*sourcePage, error) {
+	return &sourcePage{
+		posts:  s.posts,
+		pinned: map[string]bool{"2": true},
+		next:   "next",
+	}, nil
+}
+
+func TestPostQueryRun(t *testing.T) {
+	posts := testPosts(3)
+	var calls []int64
+	// 去掉第一个帖子,并记录执行时的viewer
+	drop := enricherFunc(func(_ context.Context, q *PostQuery, list []*models.ApiPostDetail) ([]*models.ApiPostDetail, error) {
+		calls = append(calls, q.viewerID)
+		return list[1:], nil
+	})
+	data, next, err := NewPostQuery(fakeSource{posts: posts}).Viewer(7).Enrich(drop, drop).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next != "next" {
+		t.Fatalf("next = %q, want \"next\"", next)
+	}
+	if !reflect.DeepEqual(calls, []int64{7, 7}) {
+		t.Fatalf("calls = %v, want [7 7]", calls)
+	}
+	if len(data) != 1 || data[0].Post != posts[2] || data[0].Pinned {
+		t.Fatalf("data = %+v, want only post 3", data)
+	}
+}
+
+func TestMergePinnedIDs(t *testing.T) {
+	got := mergePinnedIDs([]string{"3", "1"}, []string{"1", "2", "3", "4"})
+	if want := []string{"3", "1", "2", "4"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("mergePinnedIDs = %v, want %v", got, want)
+	}
+	got = removePinnedIDs(map[string]bool{"2": true}, []string{"1", "2", "3"})
+	if want := []string{"1", "3"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("removePinnedIDs = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,273 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/logger"
+	"bluebell/models"
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 从MySQL重建redis中帖子的时间zset、分数zset和社区set
+// 	1. 按post_id分批遍历MySQL中的帖子,补上索引中缺失的帖子,修正时间zset中不一致的分数
+// 	   分数zset中已有的帖子保留原分数,缺失时按投票记录(redis中没有时用归档的投票数据)重新计算
+// 	   写回索引时给帖子加共享锁,跳过读取之后已经被删除的帖子
+// 	2. 遍历索引中的成员,删除MySQL中已经不存在(或社区不一致)的帖子
+// 	3. 使用bleve搜索时把所有帖子重新写入本实例的搜索索引(命令行中没有打开搜索索引,不会重建)
+// 	4. 统计post_outbox中不再自动重试的事件数
+// dryRun为true时只统计,不修改redis和搜索索引
+
+const (
+	reindexBatchSize = 500
+	reindexLockName  = "reindex"
+	reindexLockTTL   = time.Minute // 持有锁期间每隔三分之一的时间续期一次
+)
+
+var ErrorReindexRunning = errors.New("正在重建索引")
+
+var reindexer = &Reindexer{
+	getPostsAfter:      mysql.GetPostsAfter,
+	getVoteArchives:    mysql.GetVoteArchives,
+	getCommunityList:   mysql.GetCommunityList,
+	getPostCommunities: mysql.GetPostCommunities,
+	withPostLocked:     mysql.WithPostShareLocked,
+	countDeadOutbox: func(ctx context.Context) (int64, error) {
+		return mysql.CountDeadOutboxEvents(ctx, outboxMaxAttempts)
+	},
+	lockTTL: reindexLockTTL,
+}
+
+// Reindexer 重建redis中的帖子索引,用redis中的锁保证所有实例和命令行同一时间只有一个在重建
+type Reindexer struct {
+	getPostsAfter      func(ctx context.Context, lastID int64, limit int) ([]*models.Post, error)
+	getVoteArchives    func(ctx context.Context, postIDs []int64) (map[int64]*models.VoteArchive, error)
+	getCommunityList   func(ctx context.Context) ([]*models.Community, error)
+	getPostCommunities func(ctx context.Context, ids []int64) (map[int64]int64, error)
+	withPostLocked     func(ctx context.Context, postID int64, fn func(post *models.Post) error) error
+	countDeadOutbox    func(ctx context.Context) (int64, error)
+	lockTTL            time.Duration
+}
+
+// Reindex 重建redis中的帖子索引
+func Reindex(ctx context.Context, dryRun bool) (*models.ReindexReport, error) {
+	return reindexer.Run(ctx, dryRun)
+}
+
+// Run 重建redis中的帖子索引,其他实例正在重建时返回 ErrorReindexRunning
+func (r *Reindexer) Run(ctx context.Context, dryRun bool) (*models.ReindexReport, error) {
+	token, ok, err := redis.AcquireLock(ctx, reindexLockName, r.lockTTL)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrorReindexRunning
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	stopRefresh := r.keepLock(ctx, cancel, token)
+	defer func() {
+		stopRefresh()
+		cancel()
+		if err := redis.ReleaseLock(context.WithoutCancel(ctx), reindexLockName, token); err != nil {
+			logger.FromContext(ctx).Warn("release reindex lock failed", zap.Error(err))
+		}
+	}()
+
+	report := &models.ReindexReport{DryRun: dryRun}
+	var lastID int64
+	for {
+		posts, err := r.getPostsAfter(ctx, lastID, reindexBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(posts) == 0 {
+			break
+		}
+		if err := r.reindexBatch(ctx, posts, report); err != nil {
+			return nil, err
+		}
+		lastID = posts[len(posts)-1].ID
+		if len(posts) < reindexBatchSize {
+			break
+		}
+	}
+	if err := r.removeStaleIndexMembers(ctx, report); err != nil {
+		return nil, err
+	}
+	if !dryRun {
+		indexed, err := BackfillSearchIndex(ctx)
+		if err != nil {
+			return nil, err
+		}
+		report.SearchIndexed = indexed
+	}
+	// 不再自动重试的事件需要管理员处理,重建只修复了redis的索引,不会替它们标记完成
+	if report.OutboxDead, err = r.countDeadOutbox(ctx); err != nil {
+		return nil, err
+	}
+	if report.OutboxDead > 0 {
+		logger.FromContext(ctx).Warn("there are dead outbox events", zap.Int64("count", report.OutboxDead))
+	}
+	logger.FromContext(ctx).Info("reindex finished", zap.Any("report", report))
+	return report, nil
+}
+
+// keepLock 在后台定期续期锁,续期失败(锁已经过期被其他实例获取)时调用cancel停止重建
+// 返回的函数停止续期并等待后台任务退出
+func (r *Reindexer) keepLock(ctx context.Context, cancel context.CancelFunc, token string) (stop func()) {
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(r.lockTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			ok, err := redis.RefreshLock(ctx, reindexLockName, token, r.lockTTL)
+			if err == nil && ok {
+				continue
+			}
+			logger.FromContext(ctx).Error("lost reindex lock", zap.Bool("held", ok), zap.Error(err))
+			cancel()
+			return
+		}
+	}()
+	return func() {
+		close(done)
+		<-exited
+	}
+}
+
+func (r *Reindexer) reindexBatch(ctx context.Context, posts []*models.Post, report *models.ReindexReport) error {
+	report.Posts += int64(len(posts))
+	states, err := redis.GetPostIndexStates(ctx, posts)
+	if err != nil {
+		return err
+	}
+	// 只为分数zset中缺失的帖子查询归档的投票数据
+	missingScore := make([]int64, 0)
+	for i, s := range states {
+		if !s.HasScore && s.UpVotes == 0 && s.DownVotes == 0 {
+			missingScore = append(missingScore, posts[i].ID)
+		}
+	}
+	archives, err := r.getVoteArchives(ctx, missingScore)
+	if err != nil {
+		return err
+	}
+
+	drifted := make([]*driftedPost, 0)
+	for i, post := range posts {
+		s := states[i]
+		drift := false
+		if !s.HasTime {
+			report.MissingTime++
+			drift = true
+		} else if int64(s.TimeScore) != post.CreateTime.Unix() {
+			report.MismatchedTime++
+			drift = true
+		}
+		up, down := s.UpVotes, s.DownVotes
+		if !s.HasScore {
+			report.MissingScore++
+			drift = true
+			if a, ok := archives[post.ID]; ok {
+				report.ScoreFromArchive++
+				up, down = a.UpVotes, a.DownVotes
+			}
+		}
+		if !s.InCommunity {
+			report.MissingCommunity++
+			drift = true
+		}
+		if drift {
+			drifted = append(drifted, &driftedPost{postID: post.ID, up: up, down: down})
+		}
+	}
+	if report.DryRun {
+		return nil
+	}
+	for _, d := range drifted {
+		if err := r.rebuildPost(ctx, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// driftedPost 索引不一致需要重建的帖子
+type driftedPost struct {
+	postID   int64
+	up, down int64
+}
+
+// rebuildPost 给帖子加共享锁后重新写入索引
+// 读取这一批帖子之后被删除的帖子不再写回索引,删除帖子需要排他锁,重建期间不会被删除
+func (r *Reindexer) rebuildPost(ctx context.Context, d *driftedPost) error {
+	err := r.withPostLocked(ctx, d.postID, func(post *models.Post) error {
+		return redis.RebuildPostIndex(ctx, []*models.PostIndexEntry{{
+			PostID:      post.ID,
+			CommunityID: post.CommunityID,
+			CreateTime:  post.CreateTime,
+			Score:       redis.PostScore(post.CreateTime, d.up, d.down),
+		}})
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		logger.FromContext(ctx).Debug("skip reindexing deleted post", zap.Int64("post_id", d.postID))
+		return nil
+	}
+	return err
+}
+
+// removeStaleIndexMembers 删除索引中MySQL里已经不存在的帖子,社区set中还要删除社区不一致的帖子
+func (r *Reindexer) removeStaleIndexMembers(ctx context.Context, report *models.ReindexReport) error {
+	communities, err := r.getCommunityList(ctx)
+	if err != nil {
+		return err
+	}
+	communityIDs := make([]int64, 0, len(communities))
+	for _, c := range communities {
+		communityIDs = append(communityIDs, c.ID)
+	}
+	for key, communityID := range redis.PostIndexKeys(communityIDs) {
+		err := redis.ScanIndexMembers(ctx, key, func(members []string) error {
+			ids := make([]int64, 0, len(members))
+			for _, m := range members {
+				if id, err := strconv.ParseInt(m, 10, 64); err == nil {
+					ids = append(ids, id)
+				}
+			}
+			existing, err := r.getPostCommunities(ctx, ids)
+			if err != nil {
+				return err
+			}
+			stale := make([]string, 0)
+			for _, m := range members {
+				id, err := strconv.ParseInt(m, 10, 64)
+				cid, ok := existing[id]
+				if err != nil || !ok || (communityID != 0 && cid != communityID) {
+					stale = append(stale, m)
+				}
+			}
+			report.Stale += int64(len(stale))
+			if report.DryRun {
+				return nil
+			}
+			// 删除当前批次中的成员不影响SCAN的遍历
+			return redis.RemoveIndexMembers(ctx, key, stale)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
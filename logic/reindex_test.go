@@ -0,0 +1,241 @@
+package logic
+
+import (
+	"bluebell/dao/redis"
+	"bluebell/models"
+	"bluebell/setting"
+	"context"
+	"database/sql"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+var reindexTestTime = time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local)
+
+// setupReindexRedis 把redis的客户端指向一个进程内的miniredis
+func setupReindexRedis(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	if err := redis.Init(&setting.RedisConfig{Host: mr.Host(), Port: port}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(redis.Close)
+	return mr
+}
+
+// newTestReindexer MySQL中有帖子1-5,帖子1-4属于社区1,帖子5属于社区2;帖子4有归档的投票数据
+// post_outbox中有一个不再自动重试的事件
+func newTestReindexer() *Reindexer {
+	posts := make([]*models.Post, 0, 5)
+	for i := int64(1); i <= 5; i++ {
+		communityID := int64(1)
+		if i == 5 {
+			communityID = 2
+		}
+		posts = append(posts, &models.Post{
+			ID:          i,
+			CommunityID: communityID,
+			CreateTime:  reindexTestTime.Add(time.Duration(i) * time.Hour),
+		})
+	}
+	return &Reindexer{
+		getPostsAfter: func(_ context.Context, lastID int64, limit int) ([]*models.Post, error) {
+			res := make([]*models.Post, 0, limit)
+			for _, p := range posts {
+				if p.ID > lastID && len(res) < limit {
+					res = append(res, p)
+				}
+			}
+			return res, nil
+		},
+		getVoteArchives: func(_ context.Context, ids []int64) (map[int64]*models.VoteArchive, error) {
+			res := make(map[int64]*models.VoteArchive)
+			for _, id := range ids {
+				if id == 4 {
+					res[id] = &models.VoteArchive{PostID: id, UpVotes: 3}
+				}
+			}
+			return res, nil
+		},
+		getCommunityList: func(context.Context) ([]*models.Community, error) {
+			return []*models.Community{{ID: 1}, {ID: 2}}, nil
+		},
+		getPostCommunities: func(_ context.Context, ids []int64) (map[int64]int64, error) {
+			res := make(map[int64]int64)
+			for _, id := range ids {
+				for _, p := range posts {
+					if p.ID == id {
+						res[id] = p.CommunityID
+					}
+				}
+			}
+			return res, nil
+		},
+		withPostLocked: func(ctx context.Context, postID int64, fn func(post *models.Post) error) error {
+			for _, p := range posts {
+				if p.ID == postID {
+					return fn(p)
+				}
+			}
+			return sql.ErrNoRows
+		},
+		countDeadOutbox: func(context.Context) (int64, error) { return 1, nil },
+		lockTTL:         time.Minute,
+	}
+}
+
+// addDrift 把帖子写入索引后制造各种不一致
+func addDrift(t *testing.T, mr *miniredis.Miniredis, r *Reindexer) {
+	t.Helper()
+	ctx := context.Background()
+	posts, _ := r.getPostsAfter(ctx, 0, 10)
+	for _, p := range posts {
+		if err := redis.AddPostToIndex(ctx, p.ID, p.CommunityID, p.CreateTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	timeKey := redis.Prefix + redis.KeyPostTimeZSet
+	scoreKey := redis.Prefix + redis.KeyPostScoreZSet
+	// 帖子1不在时间zset中
+	mr.ZRem(timeKey, "1")
+	// 帖子2在时间zset中的分数不对
+	mr.ZAdd(timeKey, 1, "2")
+	// 帖子3不在社区set中
+	mr.SRem(redis.Prefix+redis.KeyCommunitySetPF+"1", "3")
+	// 帖子4不在分数zset中,redis中没有投票记录,使用归档的投票数据
+	mr.ZRem(scoreKey, "4")
+	// 帖子99在MySQL中不存在,帖子5在社区1的set中但属于社区2
+	mr.ZAdd(timeKey, float64(reindexTestTime.Unix()), "99")
+	mr.SAdd(redis.Prefix+redis.KeyCommunitySetPF+"1", "5")
+}
+
+func TestReindex(t *testing.T) {
+	mr := setupReindexRedis(t)
+	r := newTestReindexer()
+	addDrift(t, mr, r)
+
+	report, err := r.Run(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.ReindexReport{
+		Posts:            5,
+		MissingTime:      1,
+		MismatchedTime:   1,
+		MissingScore:     1,
+		ScoreFromArchive: 1,
+		MissingCommunity: 1,
+		Stale:            2,
+		OutboxDead:       1,
+	}
+	if *report != want {
+		t.Fatalf("report = %+v, want %+v", *report, want)
+	}
+
+	timeKey := redis.Prefix + redis.KeyPostTimeZSet
+	if score, _ := mr.ZScore(timeKey, "1"); score != float64(reindexTestTime.Add(time.Hour).Unix()) {
+		t.Errorf("time score of post 1 = %v", score)
+	}
+	if score, _ := mr.ZScore(timeKey, "2"); score != float64(reindexTestTime.Add(2*time.Hour).Unix()) {
+		t.Errorf("time score of post 2 = %v", score)
+	}
+	wantScore := redis.PostScore(reindexTestTime.Add(4*time.Hour), 3, 0)
+	if score, _ := mr.ZScore(redis.Prefix+redis.KeyPostScoreZSet, "4"); score != wantScore {
+		t.Errorf("score of post 4 = %v, want %v", score, wantScore)
+	}
+	if ok, _ := mr.SIsMember(redis.Prefix+redis.KeyCommunitySetPF+"1", "3"); !ok {
+		t.Error("post 3 not added back to community 1")
+	}
+	if ok, _ := mr.SIsMember(redis.Prefix+redis.KeyCommunitySetPF+"1", "5"); ok {
+		t.Error("post 5 not removed from community 1")
+	}
+	if members, _ := mr.ZMembers(timeKey); len(members) != 5 {
+		t.Errorf("time zset members = %v, want posts 1-5", members)
+	}
+
+	// 重建之后没有不一致
+	report, err = r.Run(context.Background(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (models.ReindexReport{DryRun: true, Posts: 5, OutboxDead: 1}); *report != want {
+		t.Fatalf("report after reindex = %+v, want %+v", *report, want)
+	}
+}
+
+func TestReindexDryRun(t *testing.T) {
+	mr := setupReindexRedis(t)
+	r := newTestReindexer()
+	addDrift(t, mr, r)
+	before := mr.Dump()
+
+	report, err := r.Run(context.Background(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.MissingTime != 1 || report.Stale != 2 {
+		t.Fatalf("report = %+v, want drift counted", *report)
+	}
+	if after := mr.Dump(); after != before {
+		t.Fatalf("dry run modified redis:\nbefore:\n%s\nafter:\n%s", before, after)
+	}
+}
+
+func TestReindexLocked(t *testing.T) {
+	mr := setupReindexRedis(t)
+	r := newTestReindexer()
+	// 其他实例或命令行正在重建
+	token, ok, err := redis.AcquireLock(context.Background(), reindexLockName, time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("AcquireLock() = %v, %v", ok, err)
+	}
+	if _, err := r.Run(context.Background(), true); err != ErrorReindexRunning {
+		t.Fatalf("Run() err = %v, want ErrorReindexRunning", err)
+	}
+	if err := redis.ReleaseLock(context.Background(), reindexLockName, token); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Run(context.Background(), true); err != nil {
+		t.Fatalf("Run() after release err = %v", err)
+	}
+	// 重建完成后释放锁
+	if mr.Exists(redis.Prefix + redis.KeyLockPF + reindexLockName) {
+		t.Fatal("lock not released after reindex")
+	}
+}
+
+func TestReindexSkipsDeletedPost(t *testing.T) {
+	mr := setupReindexRedis(t)
+	r := newTestReindexer()
+	addDrift(t, mr, r)
+	// 读取帖子之后、重建索引之前帖子1被删除,删除事件已经把帖子1从索引中移除
+	withPostLocked := r.withPostLocked
+	r.withPostLocked = func(ctx context.Context, postID int64, fn func(post *models.Post) error) error {
+		if postID == 1 {
+			return sql.ErrNoRows
+		}
+		return withPostLocked(ctx, postID, fn)
+	}
+
+	if _, err := r.Run(context.Background(), false); err != nil {
+		t.Fatal(err)
+	}
+	timeKey := redis.Prefix + redis.KeyPostTimeZSet
+	members, _ := mr.ZMembers(timeKey)
+	for _, m := range members {
+		if m == "1" {
+			t.Fatal("deleted post 1 added back to the time zset")
+		}
+	}
+	// 其他不一致的帖子照常重建
+	if score, _ := mr.ZScore(timeKey, "2"); score != float64(reindexTestTime.Add(2*time.Hour).Unix()) {
+		t.Errorf("time score of post 2 = %v", score)
+	}
+	if ok, _ := mr.SIsMember(redis.Prefix+redis.KeyCommunitySetPF+"1", "3"); !ok {
+		t.Error("post 3 not added back to community 1")
+	}
+}
@@ -0,0 +1,151 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/models"
+	"bluebell/pkg/cursor"
+	"context"
+)
+
+// Service 依赖的数据访问接口
+// 线上使用下面转发到 dao/mysql、dao/redis 的实现,测试时可以换成 dao/memory 中的内存实现
+
+// PostRepo 帖子的存储
+type PostRepo interface {
+	// CreatePostWithOutbox 保存帖子,同时写入帖子创建的事件
+	CreatePostWithOutbox(ctx context.Context, p *models.Post) error
+	// GetPostById 查询帖子,不存在时返回 sql.ErrNoRows
+	GetPostById(ctx context.Context, pid int64) (*models.Post, error)
+	// GetPostListByIDs 按给定的id顺序查询帖子,不存在的帖子会被忽略
+	GetPostListByIDs(ctx context.Context, ids []string) ([]*models.Post, error)
+	// GetPostList 按发帖时间倒序分页查询
+	GetPostList(ctx context.Context, page, size int64) ([]*models.Post, error)
+	// GetPostListByCursor 按发帖时间倒序查询游标之后的帖子,返回下一页的游标
+	GetPostListByCursor(ctx context.Context, c *cursor.Cursor, size int64) ([]*models.Post, *cursor.Cursor, error)
+	// GetPostListByAuthor 按发帖时间倒序查询作者在游标之后的帖子,返回下一页的游标
+	GetPostListByAuthor(ctx context.Context, authorID int64, c *cursor.Cursor, size int64) ([]*models.Post, *cursor.Cursor, error)
+	// GetPostListByAuthorPage 按发帖时间倒序分页查询作者的帖子
+	GetPostListByAuthorPage(ctx context.Context, authorID, page, size int64) ([]*models.Post, error)
+}
+
+// UserRepo 用户的存储
+type UserRepo interface {
+	// GetUsersByIDs 批量查询用户,不存在的id会被忽略
+	GetUsersByIDs(ctx context.Context, ids []int64) ([]*models.User, error)
+}
+
+// CommunityRepo 社区的存储
+type CommunityRepo interface {
+	// GetCommunitiesByIDs 批量查询社区详情,不存在的id会被忽略
+	GetCommunitiesByIDs(ctx context.Context, ids []int64) ([]*models.CommunityDetail, error)
+}
+
+// VoteStore 投票记录和帖子分数
+type VoteStore interface {
+	// GetPostCreateTime 查询帖子的发帖时间(unix秒),帖子不存在时返回0
+	GetPostCreateTime(ctx context.Context, postID string) (float64, error)
+	// GetUserVote 查询用户给帖子投的票,没有投过票时返回0
+	GetUserVote(ctx context.Context, userID, postID string) (float64, error)
+	// SaveVote 保存用户的投票并把帖子的分数加上scoreDelta,value为0表示取消投票
+	SaveVote(ctx context.Context, userID, postID string, value, scoreDelta float64) error
+	// GetPostVoteData 批量查询帖子的赞成票数,返回值与ids一一对应
+	GetPostVoteData(ctx context.Context, ids []string) ([]int64, error)
+	// GetUserVotes 批量查询用户给帖子投的票,返回值与ids一一对应
+	GetUserVotes(ctx context.Context, userID int64, ids []string) ([]int8, error)
+}
+
+// FeedIndex 帖子列表使用的索引,按时间或分数排序
+type FeedIndex interface {
+	GetPostIDsInOrder(ctx context.Context, p *models.ParamPostList) ([]string, error)
+	GetPostIDsByCursor(ctx context.Context, p *models.ParamPostList, c *cursor.Cursor) ([]string, *cursor.Cursor, error)
+	GetCommunityPostIDsInOrder(ctx context.Context, p *models.ParamPostList) ([]string, error)
+	GetCommunityPostIDsByCursor(ctx context.Context, p *models.ParamPostList, c *cursor.Cursor) ([]string, *cursor.Cursor, error)
+	GetFollowedPostIDsInOrder(ctx context.Context, userID int64, p *models.ParamPostList) ([]string, error)
+	GetFollowedPostIDsByCursor(ctx context.Context, userID int64, p *models.ParamPostList, c *cursor.Cursor) ([]string, *cursor.Cursor, error)
+	// GetPinnedPostIDs 查询社区内置顶的帖子id,最近置顶的在前
+	GetPinnedPostIDs(ctx context.Context, communityID int64) ([]string, error)
+	// GetPostCommentCounts 批量查询帖子的评论数,返回值与ids一一对应
+	GetPostCommentCounts(ctx context.Context, ids []string) ([]int64, error)
+}
+
+type mysqlPostRepo struct{}
+
+func (mysqlPostRepo) CreatePostWithOutbox(ctx context.Context, p *models.Post) error {
+	return mysql.CreatePostWithOutbox(ctx, p)
+}
+func (mysqlPostRepo) GetPostById(ctx context.Context, pid int64) (*models.Post, error) {
+	return mysql.GetPostById(ctx, pid)
+}
+func (mysqlPostRepo) GetPostListByIDs(ctx context.Context, ids []string) ([]*models.Post, error) {
+	return mysql.GetPostListByIDs(ctx, ids)
+}
+func (mysqlPostRepo) GetPostList(ctx context.Context, page, size int64) ([]*models.Post, error) {
+	return mysql.GetPostList(ctx, page, size)
+}
+func (mysqlPostRepo) GetPostListByCursor(ctx context.Context, c *cursor.Cursor, size int64) ([]*models.Post, *cursor.Cursor, error) {
+	return mysql.GetPostListByCursor(ctx, c, size)
+}
+func (mysqlPostRepo) GetPostListByAuthor(ctx context.Context, authorID int64, c *cursor.Cursor, size int64) ([]*models.Post, *cursor.Cursor, error) {
+	return mysql.GetPostListByAuthor(ctx, authorID, c, size)
+}
+func (mysqlPostRepo) GetPostListByAuthorPage(ctx context.Context, authorID, page, size int64) ([]*models.Post, error) {
+	return mysql.GetPostListByAuthorPage(ctx, authorID, page, size)
+}
+
+type mysqlUserRepo struct{}
+
+func (mysqlUserRepo) GetUsersByIDs(ctx context.Context, ids []int64) ([]*models.User, error) {
+	return mysql.GetUsersByIDs(ctx, ids)
+}
+
+type mysqlCommunityRepo struct{}
+
+func (mysqlCommunityRepo) GetCommunitiesByIDs(ctx context.Context, ids []int64) ([]*models.CommunityDetail, error) {
+	return mysql.GetCommunitiesByIDs(ctx, ids)
+}
+
+type redisVoteStore struct{}
+
+func (redisVoteStore) GetPostCreateTime(ctx context.Context, postID string) (float64, error) {
+	return redis.GetPostCreateTime(ctx, postID)
+}
+func (redisVoteStore) GetUserVote(ctx context.Context, userID, postID string) (float64, error) {
+	return redis.GetUserVote(ctx, userID, postID)
+}
+func (redisVoteStore) SaveVote(ctx context.Context, userID, postID string, value, scoreDelta float64) error {
+	return redis.SaveVote(ctx, userID, postID, value, scoreDelta)
+}
+func (redisVoteStore) GetPostVoteData(ctx context.Context, ids []string) ([]int64, error) {
+	return redis.GetPostVoteData(ctx, ids)
+}
+func (redisVoteStore) GetUserVotes(ctx context.Context, userID int64, ids []string) ([]int8, error) {
+	return redis.GetUserVotes(ctx, userID, ids)
+}
+
+type redisFeedIndex struct{}
+
+func (redisFeedIndex) GetPostIDsInOrder(ctx context.Context, p *models.ParamPostList) ([]string, error) {
+	return redis.GetPostIDsInOrder(ctx, p)
+}
+func (redisFeedIndex) GetPostIDsByCursor(ctx context.Context, p *models.ParamPostList, c *cursor.Cursor) ([]string, *cursor.Cursor, error) {
+	return redis.GetPostIDsByCursor(ctx, p, c)
+}
+func (redisFeedIndex) GetCommunityPostIDsInOrder(ctx context.Context, p *models.ParamPostList) ([]string, error) {
+	return redis.GetCommunityPostIDsInOrder(ctx, p)
+}
+func (redisFeedIndex) GetCommunityPostIDsByCursor(ctx context.Context, p *models.ParamPostList, c *cursor.Cursor) ([]string, *cursor.Cursor, error) {
+	return redis.GetCommunityPostIDsByCursor(ctx, p, c)
+}
+func (redisFeedIndex) GetFollowedPostIDsInOrder(ctx context.Context, userID int64, p *models.ParamPostList) ([]string, error) {
+	return redis.GetFollowedPostIDsInOrder(ctx, userID, p)
+}
+func (redisFeedIndex) GetFollowedPostIDsByCursor(ctx context.Context, userID int64, p *models.ParamPostList, c *cursor.Cursor) ([]string, *cursor.Cursor, error) {
+	return redis.GetFollowedPostIDsByCursor(ctx, userID, p, c)
+}
+func (redisFeedIndex) GetPinnedPostIDs(ctx context.Context, communityID int64) ([]string, error) {
+	return redis.GetPinnedPostIDs(ctx, communityID)
+}
+func (redisFeedIndex) GetPostCommentCounts(ctx context.Context, ids []string) ([]int64, error) {
+	return redis.GetPostCommentCounts(ctx, ids)
+}
@@ -0,0 +1,198 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/search"
+	"bluebell/models"
+	"bluebell/pkg/cursor"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var ErrorInvalidSince = errors.New("无效的起始时间")
+
+const searchBackfillBatchSize = 500
+
+// 新建的bleve索引在后台从MySQL回填,退出时取消
+var (
+	searchBackfillCancel context.CancelFunc
+	searchBackfillDone   chan struct{}
+)
+
+// Search 全文搜索帖子,返回高亮后的片段及命中总数
+// 搜索结果按相关度排序,无法按键值分页,游标中保存的是偏移量
+func Search(ctx context.Context, p *models.ParamSearch, viewerID int64) (*models.ApiSearchResult, string, error) {
+	q := &search.Query{
+		Keyword:     p.Q,
+		CommunityID: p.CommunityID,
+		AuthorName:  p.Author,
+		Size:        p.Size,
+	}
+	if p.Cursor == "" && p.Page > 1 {
+		q.Offset = (p.Page - 1) * p.Size
+	} else {
+		c, err := cursor.Decode(p.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		if c != nil {
+			q.Offset = c.Offset
+		}
+	}
+	if p.Since != "" {
+		since, err := parseSince(p.Since)
+		if err != nil {
+			return nil, "", ErrorInvalidSince
+		}
+		q.Since = since
+	}
+	src := &searchSource{query: q}
+	list, next, err := NewPostQuery(src).Paginate("", 1, q.Size).Viewer(viewerID).Run(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	data := &models.ApiSearchResult{
+		Total: src.total,
+		List:  make([]*models.ApiSearchHit, 0, len(list)),
+	}
+	for _, d := range list {
+		data.List = append(data.List, &models.ApiSearchHit{
+			ApiPostDetail: d,
+			Highlight:     src.highlights[d.Post.ID],
+		})
+	}
+	return data, next, nil
+}
+
+// searchSource 搜索结果按相关度排序,索引中存在但已经被删除的帖子会被跳过
+// 查询完成后total和highlights中保存命中总数及每个帖子高亮后的片段
+type searchSource struct {
+	query      *search.Query
+	total      int64
+	highlights map[int64]*models.ApiHighlight
+}
+
+func (s *searchSource) fetch(ctx context.Context, _ *PostQuery) (*sourcePage, error) {
+	res, err := search.Search(ctx, s.query)
+	if err != nil {
+		return nil, err
+	}
+	s.total = res.Total
+	s.highlights = make(map[int64]*models.ApiHighlight, len(res.Hits))
+	page := &sourcePage{ids: make([]string, 0, len(res.Hits))}
+	for _, hit := range res.Hits {
+		page.ids = append(page.ids, strconv.FormatInt(hit.PostID, 10))
+		s.highlights[hit.PostID] = &models.ApiHighlight{
+			Title:   hit.Title,
+			Content: hit.Snippet,
+		}
+	}
+	if n := s.query.Offset + int64(len(res.Hits)); len(res.Hits) > 0 && n < res.Total {
+		page.next = (&cursor.Cursor{Offset: n}).Encode()
+	}
+	return page, nil
+}
+
+// parseSince 解析起始时间,支持 2006-01-02 和 RFC3339 两种格式
+func parseSince(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// postAuthorName 查询帖子作者的用户名,帖子不存在时返回 sql.ErrNoRows
+func postAuthorName(ctx context.Context, postID int64) (string, error) {
+	post, err := mysql.GetPostById(ctx, postID)
+	if err != nil {
+		return "", err
+	}
+	user, err := mysql.GetUserById(ctx, post.AuthorID)
+	if err != nil {
+		return "", fmt.Errorf("get author %d: %w", post.AuthorID, err)
+	}
+	return user.Username, nil
+}
+
+// indexPost 更新帖子的搜索索引
+func indexPost(ctx context.Context, p *models.Post, authorName string) error {
+	return search.IndexPost(ctx, p, authorName)
+}
+
+// unindexPost 删除帖子的搜索索引
+func unindexPost(ctx context.Context, postID int64) error {
+	return search.DeletePost(ctx, postID)
+}
+
+// BackfillSearchIndex 按post_id分批把MySQL中的所有帖子写入搜索索引,返回写入的帖子数
+// 只有bleve需要回填,MySQL的FULLTEXT索引由MySQL自己维护,这时什么都不做
+// 回填期间新发的帖子由outbox同步,重复写入同一个帖子只会覆盖
+func BackfillSearchIndex(ctx context.Context) (indexed int64, err error) {
+	if search.Engine() != search.EngineBleve {
+		return 0, nil
+	}
+	var lastID int64
+	for {
+		posts, err := mysql.GetPostDocsAfter(ctx, lastID, searchBackfillBatchSize)
+		if err != nil {
+			return indexed, err
+		}
+		if len(posts) == 0 {
+			break
+		}
+		authorIDs := make([]int64, 0, len(posts))
+		for _, p := range posts {
+			authorIDs = append(authorIDs, p.AuthorID)
+		}
+		users, err := mysql.GetUsersByIDs(ctx, authorIDs)
+		if err != nil {
+			return indexed, err
+		}
+		authorNames := make(map[int64]string, len(users))
+		for _, u := range users {
+			authorNames[u.UserID] = u.Username
+		}
+		if err := search.IndexPosts(ctx, posts, authorNames); err != nil {
+			return indexed, err
+		}
+		indexed += int64(len(posts))
+		lastID = posts[len(posts)-1].ID
+		if len(posts) < searchBackfillBatchSize {
+			break
+		}
+	}
+	return indexed, nil
+}
+
+// StartSearchBackfill bleve索引是新建的时候在后台回填已有的帖子,不阻塞启动
+func StartSearchBackfill() {
+	if !search.NeedsBackfill() {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	searchBackfillCancel, searchBackfillDone = cancel, make(chan struct{})
+	go func() {
+		defer close(searchBackfillDone)
+		start := time.Now()
+		indexed, err := BackfillSearchIndex(ctx)
+		if err != nil {
+			zap.L().Error("backfill search index failed", zap.Int64("indexed", indexed), zap.Error(err))
+			return
+		}
+		zap.L().Info("search index backfilled", zap.Int64("indexed", indexed), zap.Duration("cost", time.Since(start)))
+	}()
+}
+
+// StopSearchBackfill 取消还没有完成的回填并等待它退出,需要在关闭搜索引擎之前调用
+func StopSearchBackfill() {
+	if searchBackfillCancel == nil {
+		return
+	}
+	searchBackfillCancel()
+	<-searchBackfillDone
+}
@@ -0,0 +1,213 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/logger"
+	"bluebell/models"
+	"bluebell/pkg/badword"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+// 敏感词库保存在MySQL中,管理员按分类添加、删除、导入和导出
+// 修改后立即重新加载本实例的过滤器,再通过redis发布通知,其他实例收到后重新加载
+// 过滤器使用的自动机不支持增量修改,每次都从MySQL加载整个词库重新构建,一万多个词的词库重新构建一次约一百多毫秒,构建期间继续使用旧的自动机
+
+var (
+	ErrorInvalidSensitiveWord = errors.New("无效的敏感词")
+	ErrorInvalidCategory      = errors.New("无效的敏感词分类")
+)
+
+const (
+	maxSensitiveWordLen = 128 // 和 sensitive_word.word 的长度一致
+	maxCategoryLen      = 64  // 和 sensitive_word.category 的长度一致
+)
+
+// loadSensitiveWords 从MySQL加载所有的敏感词,作为过滤器的 badword.Loader
+func loadSensitiveWords(ctx context.Context) ([]badword.Entry, error) {
+	words, err := mysql.GetSensitiveWords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]badword.Entry, 0, len(words))
+	for _, w := range words {
+		entries = append(entries, badword.Entry{Word: w.Word, Category: w.Category})
+	}
+	return entries, nil
+}
+
+// seedSensitiveWords MySQL中还没有敏感词时导入dir目录中的词库文件,文件名是分类名
+// 多个实例同时启动时可能都会导入,重复的词被忽略
+func seedSensitiveWords(ctx context.Context, dir string) error {
+	if dir == "" {
+		return nil
+	}
+	count, err := mysql.CountSensitiveWords(ctx)
+	if err != nil || count > 0 {
+		return err
+	}
+	entries, err := badword.LoadDir(dir)
+	if err != nil {
+		return err
+	}
+	var categories []string
+	byCategory := make(map[string][]string)
+	for _, e := range entries {
+		if _, ok := byCategory[e.Category]; !ok {
+			categories = append(categories, e.Category)
+		}
+		byCategory[e.Category] = append(byCategory[e.Category], e.Word)
+	}
+	for _, category := range categories {
+		words, err := cleanSensitiveWords(byCategory[category])
+		if err != nil {
+			return err
+		}
+		added, err := mysql.AddSensitiveWords(ctx, category, words)
+		if err != nil {
+			return err
+		}
+		zap.L().Info("sensitive words seeded", zap.String("dir", dir), zap.String("category", category), zap.Int64("words", added))
+	}
+	return nil
+}
+
+// checkCategory 分类名不能为空、不能过长,也不能包含空白和控制字符,分类名会出现在导出的文件名中
+func checkCategory(category string) error {
+	if category == "" || !utf8.ValidString(category) || utf8.RuneCountInString(category) > maxCategoryLen {
+		return ErrorInvalidCategory
+	}
+	for _, r := range category {
+		if unicode.IsSpace(r) || unicode.IsControl(r) || r == '/' || r == '\\' {
+			return ErrorInvalidCategory
+		}
+	}
+	return nil
+}
+
+// cleanSensitiveWords 去掉首尾的空白、空词和重复的词,词过长或不是有效的UTF-8时返回 ErrorInvalidSensitiveWord
+func cleanSensitiveWords(words []string) ([]string, error) {
+	seen := make(map[string]bool, len(words))
+	res := make([]string, 0, len(words))
+	for _, w := range words {
+		w = strings.TrimSpace(w)
+		if w == "" || seen[w] {
+			continue
+		}
+		if !utf8.ValidString(w) || utf8.RuneCountInString(w) > maxSensitiveWordLen {
+			return nil, ErrorInvalidSensitiveWord
+		}
+		seen[w] = true
+		res = append(res, w)
+	}
+	return res, nil
+}
+
+// GetSensitiveWordCategories 查询所有的分类及每个分类中词的数量
+func GetSensitiveWordCategories(ctx context.Context) ([]*models.SensitiveWordCategory, error) {
+	return mysql.GetSensitiveWordCategories(ctx)
+}
+
+// ExportSensitiveWords 导出分类中的所有敏感词
+func ExportSensitiveWords(ctx context.Context, category string) ([]string, error) {
+	if err := checkCategory(category); err != nil {
+		return nil, err
+	}
+	return mysql.GetCategorySensitiveWords(ctx, category)
+}
+
+// AddSensitiveWords 向分类中添加敏感词,分类不存在时自动创建
+func AddSensitiveWords(ctx context.Context, category string, words []string) (*models.SensitiveWordsResult, error) {
+	if err := checkCategory(category); err != nil {
+		return nil, err
+	}
+	words, err := cleanSensitiveWords(words)
+	if err != nil {
+		return nil, err
+	}
+	if len(words) == 0 {
+		return nil, ErrorInvalidSensitiveWord
+	}
+	added, err := mysql.AddSensitiveWords(ctx, category, words)
+	if err != nil {
+		return nil, err
+	}
+	if added > 0 {
+		sensitiveWordsChanged(ctx, category)
+	}
+	return &models.SensitiveWordsResult{Added: added}, nil
+}
+
+// RemoveSensitiveWords 从分类中删除敏感词,词要和添加时的原始形式一致
+func RemoveSensitiveWords(ctx context.Context, category string, words []string) (*models.SensitiveWordsResult, error) {
+	if err := checkCategory(category); err != nil {
+		return nil, err
+	}
+	words, err := cleanSensitiveWords(words)
+	if err != nil {
+		return nil, err
+	}
+	if len(words) == 0 {
+		return nil, ErrorInvalidSensitiveWord
+	}
+	removed, err := mysql.RemoveSensitiveWords(ctx, category, words)
+	if err != nil {
+		return nil, err
+	}
+	if removed > 0 {
+		sensitiveWordsChanged(ctx, category)
+	}
+	return &models.SensitiveWordsResult{Removed: removed}, nil
+}
+
+// ImportSensitiveWords 从每行一个词的文本导入敏感词,格式和 ExportSensitiveWords 导出的一致
+// replace为true时用导入的词替换分类中原有的词,否则只添加
+func ImportSensitiveWords(ctx context.Context, category string, r io.Reader, replace bool) (*models.SensitiveWordsResult, error) {
+	if err := checkCategory(category); err != nil {
+		return nil, err
+	}
+	words, err := badword.ReadWords(r)
+	if err != nil {
+		return nil, err
+	}
+	if words, err = cleanSensitiveWords(words); err != nil {
+		return nil, err
+	}
+	// 空文件多半是上传错了,不能用来清空分类
+	if len(words) == 0 {
+		return nil, ErrorInvalidSensitiveWord
+	}
+	res := new(models.SensitiveWordsResult)
+	if replace {
+		res.Added, res.Removed, err = mysql.ReplaceSensitiveWords(ctx, category, words)
+	} else {
+		res.Added, err = mysql.AddSensitiveWords(ctx, category, words)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if res.Added > 0 || res.Removed > 0 {
+		sensitiveWordsChanged(ctx, category)
+	}
+	return res, nil
+}
+
+// sensitiveWordsChanged 立即重新加载本实例的词库,并通知其他实例
+// 失败时只记录日志,修改已经保存,其他实例会在定期加载时同步
+func sensitiveWordsChanged(ctx context.Context, category string) {
+	if moderation != nil {
+		if _, err := moderation.Reload(ctx); err != nil {
+			logger.FromContext(ctx).Error("reload sensitive words failed", zap.String("category", category), zap.Error(err))
+		}
+	}
+	if err := redis.PublishSensitiveWordsChanged(ctx, category); err != nil {
+		logger.FromContext(ctx).Error("redis.PublishSensitiveWordsChanged failed", zap.String("category", category), zap.Error(err))
+	}
+}
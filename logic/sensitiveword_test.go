@@ -0,0 +1,37 @@
+package logic
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCleanSensitiveWords(t *testing.T) {
+	got, err := cleanSensitiveWords([]string{" 刷单 ", "", "代购", "刷单", "SM", "sm"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 大小写由过滤器统一转换,保存时保留原始形式
+	if want := []string{"刷单", "代购", "SM", "sm"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("cleanSensitiveWords() = %q, want %q", got, want)
+	}
+	for _, bad := range []string{strings.Repeat("词", maxSensitiveWordLen+1), "a\xff"} {
+		if _, err := cleanSensitiveWords([]string{bad}); !errors.Is(err, ErrorInvalidSensitiveWord) {
+			t.Errorf("cleanSensitiveWords(%q) error = %v, want ErrorInvalidSensitiveWord", bad, err)
+		}
+	}
+}
+
+func TestCheckCategory(t *testing.T) {
+	for _, category := range []string{"广告", "涉枪涉爆违法信息关键词", "ads-2"} {
+		if err := checkCategory(category); err != nil {
+			t.Errorf("checkCategory(%q) = %v, want nil", category, err)
+		}
+	}
+	for _, category := range []string{"", "a b", "../ads", "a\nb", strings.Repeat("类", maxCategoryLen+1)} {
+		if err := checkCategory(category); !errors.Is(err, ErrorInvalidCategory) {
+			t.Errorf("checkCategory(%q) = %v, want ErrorInvalidCategory", category, err)
+		}
+	}
+}
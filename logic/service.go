@@ -0,0 +1,110 @@
+package logic
+
+import (
+	"bluebell/dao/redis"
+	"bluebell/models"
+	"bluebell/pkg/badword"
+	"bluebell/pkg/lru"
+	"bluebell/pkg/snowflake"
+	"context"
+	"time"
+)
+
+// Service 帖子的发布、投票和列表查询,依赖的存储通过接口注入
+// 包级别的同名函数使用连接MySQL和redis的默认实例 std,
+// 测试时用 NewService 传入内存实现,不需要启动任何外部服务
+
+type Service struct {
+	posts       PostRepo
+	users       UserRepo
+	communities CommunityRepo
+	votes       VoteStore
+	feed        FeedIndex
+
+	usernameCache  *tieredCache[string]
+	communityCache *tieredCache[*models.CommunityDetail]
+
+	now           func() time.Time
+	genID         func() int64
+	notify        func()                                    // 写入outbox事件后唤醒后台任务
+	onPostChanged func(ctx context.Context, postID int64)   // 帖子的票数等数据变化后调用,默认实例中删除帖子详情的缓存
+	moderate      func(text string) badword.Verdict         // 按分类处理文本中的敏感词,默认实例中使用共享的敏感词过滤器
+	queueReview   func(ctx context.Context, p *models.Post) // 把命中需要审核的敏感词的帖子交给版主审核
+}
+
+// NewService 创建Service,作者和社区信息只使用进程内缓存
+func NewService(posts PostRepo, users UserRepo, communities CommunityRepo, votes VoteStore, feed FeedIndex) *Service {
+	s := &Service{
+		posts:         posts,
+		users:         users,
+		communities:   communities,
+		votes:         votes,
+		feed:          feed,
+		now:           time.Now,
+		genID:         snowflake.GenID,
+		notify:        func() {},
+		onPostChanged: func(context.Context, int64) {},
+		moderate:      func(text string) badword.Verdict { return badword.Verdict{Text: text} },
+		queueReview:   func(context.Context, *models.Post) {},
+	}
+	s.usernameCache = &tieredCache[string]{
+		name:  "username",
+		local: lru.New[int64, string](localUsernameCacheSize, localCacheTTL),
+		fetch: s.fetchUsernames,
+	}
+	s.communityCache = &tieredCache[*models.CommunityDetail]{
+		name:  "community",
+		local: lru.New[int64, *models.CommunityDetail](localCommunityCacheSize, localCacheTTL),
+		fetch: s.fetchCommunities,
+	}
+	return s
+}
+
+// std 默认实例,在init中创建以避免和 postCache 之间的初始化循环
+var std *Service
+
+func init() {
+	std = newDefaultService()
+}
+
+// newDefaultService 使用MySQL和redis的实例,作者和社区信息额外缓存在redis中
+func newDefaultService() *Service {
+	s := NewService(mysqlPostRepo{}, mysqlUserRepo{}, mysqlCommunityRepo{}, redisVoteStore{}, redisFeedIndex{})
+	s.usernameCache.getCached = redis.GetCachedUsernames
+	s.usernameCache.setCached = redis.SetCachedUsernames
+	s.usernameCache.delCached = redis.DeleteCachedUsername
+	s.usernameCache.publish = redis.PublishCacheInvalidated
+	s.communityCache.getCached = redis.GetCachedCommunities
+	s.communityCache.setCached = redis.SetCachedCommunities
+	s.communityCache.delCached = redis.DeleteCachedCommunity
+	s.communityCache.publish = redis.PublishCacheInvalidated
+	s.notify = notifyOutbox
+	s.onPostChanged = invalidatePost
+	s.moderate = moderateText
+	s.queueReview = queuePostReview
+	return s
+}
+
+func (s *Service) fetchUsernames(ctx context.Context, ids []int64) (map[int64]string, error) {
+	users, err := s.users.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[int64]string, len(users))
+	for _, user := range users {
+		names[user.UserID] = user.Username
+	}
+	return names, nil
+}
+
+func (s *Service) fetchCommunities(ctx context.Context, ids []int64) (map[int64]*models.CommunityDetail, error) {
+	communities, err := s.communities.GetCommunitiesByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[int64]*models.CommunityDetail, len(communities))
+	for _, community := range communities {
+		res[community.ID] = community
+	}
+	return res, nil
+}
@@ -0,0 +1,351 @@
+package logic
+
+import (
+	"bluebell/dao/memory"
+	"bluebell/dao/redis"
+	"bluebell/models"
+	"bluebell/pkg/badword"
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local)
+
+// newTestService 使用内存存储的Service,时间固定为testNow
+func newTestService(store *memory.Store) *Service {
+	s := NewService(store, store, store, store, store)
+	s.now = func() time.Time { return testNow }
+	return s
+}
+
+func TestServiceCreatePost(t *testing.T) {
+	errSave := errors.New("save failed")
+	tests := []struct {
+		name       string
+		saveErr    error
+		wantErr    error
+		wantNotify int
+	}{
+		{name: "ok", wantNotify: 1},
+		{name: "save failed", saveErr: errSave, wantErr: errSave},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memory.NewStore()
+			s := newTestService(store)
+			if tt.saveErr != nil {
+				s.posts = failingPostRepo{PostRepo: store, err: tt.saveErr}
+			}
+			s.genID = func() int64 { return 42 }
+			notified := 0
+			s.notify = func() { notified++ }
+
+			p := &models.Post{AuthorID: 1, CommunityID: 1, Title: "title", Content: "content"}
+			err := s.CreatePost(context.Background(), p)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreatePost() error = %v, want %v", err, tt.wantErr)
+			}
+			if notified != tt.wantNotify {
+				t.Errorf("notified %d times, want %d", notified, tt.wantNotify)
+			}
+			if p.ID != 42 {
+				t.Errorf("post id = %d, want 42", p.ID)
+			}
+			if _, ok := store.Post(42); ok != (tt.wantErr == nil) {
+				t.Errorf("post saved = %v, want %v", ok, tt.wantErr == nil)
+			}
+		})
+	}
+}
+
+func TestServiceCreatePostSensitive(t *testing.T) {
+	m := badword.NewMatcher([]badword.Entry{
+		{Word: "刷单", Category: "广告"},
+		{Word: "example.com", Category: "网址"},
+		{Word: "代购", Category: "代购"},
+	})
+	policies := badword.Policies{
+		Default:    badword.PolicyReject,
+		Categories: map[string]badword.Policy{"网址": badword.PolicyMask, "代购": badword.PolicyReview},
+	}
+	tests := []struct {
+		name        string
+		content     string
+		wantErr     *SensitiveWordError
+		wantContent string
+		wantReview  bool
+	}{
+		{name: "clean", content: "正常内容", wantContent: "正常内容"},
+		{name: "reject", content: "兼职刷单 example.com", wantErr: &SensitiveWordError{Field: "content", Category: "广告", Word: "刷单"}},
+		{name: "mask", content: "访问example.com", wantContent: "访问***********"},
+		{name: "review", content: "海外代购 example.com", wantContent: "海外代购 ***********", wantReview: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memory.NewStore()
+			s := newTestService(store)
+			s.genID = func() int64 { return 42 }
+			s.moderate = func(text string) badword.Verdict { return policies.Apply(text, m.FindAll(text)) }
+			reviewed := 0
+			s.queueReview = func(context.Context, *models.Post) { reviewed++ }
+
+			p := &models.Post{AuthorID: 1, CommunityID: 1, Title: "title", Content: tt.content}
+			err := s.CreatePost(context.Background(), p)
+			if tt.wantErr != nil {
+				var se *SensitiveWordError
+				if !errors.As(err, &se) || *se != *tt.wantErr {
+					t.Fatalf("CreatePost() error = %v, want %v", err, tt.wantErr)
+				}
+				if p.ID != 0 || p.Content != tt.content {
+					t.Fatalf("rejected post should not be saved or changed, id = %d, content = %q", p.ID, p.Content)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			saved, ok := store.Post(42)
+			if !ok {
+				t.Fatal("post not saved")
+			}
+			if saved.Content != tt.wantContent {
+				t.Errorf("saved content = %q, want %q", saved.Content, tt.wantContent)
+			}
+			if (reviewed == 1) != tt.wantReview {
+				t.Errorf("queued for review %d times, want review = %v", reviewed, tt.wantReview)
+			}
+		})
+	}
+}
+
+type failingPostRepo struct {
+	PostRepo
+	err error
+}
+
+func (r failingPostRepo) CreatePostWithOutbox(context.Context, *models.Post) error { return r.err }
+
+func TestServiceVoteForPost(t *testing.T) {
+	const userID = 7
+	tests := []struct {
+		name      string
+		postAge   time.Duration
+		prevVote  int8 // 之前投的票,0表示没有投过
+		direction int8
+		wantErr   error
+		wantDelta float64 // 帖子分数的变化
+	}{
+		{name: "up", direction: 1, wantDelta: 432},
+		{name: "down", direction: -1, wantDelta: -432},
+		{name: "down to up", prevVote: -1, direction: 1, wantDelta: 864},
+		{name: "up to down", prevVote: 1, direction: -1, wantDelta: -864},
+		{name: "cancel up", prevVote: 1, direction: 0, wantDelta: -432},
+		{name: "cancel down", prevVote: -1, direction: 0, wantDelta: 432},
+		{name: "repeat", prevVote: 1, direction: 1, wantErr: redis.ErrVoteRepeated},
+		{name: "cancel without vote", direction: 0, wantErr: redis.ErrVoteRepeated},
+		{name: "expired", postAge: 8 * 24 * time.Hour, direction: 1, wantErr: redis.ErrVoteTimeExpire},
+		{name: "last day", postAge: 6 * 24 * time.Hour, direction: 1, wantDelta: 432},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memory.NewStore()
+			store.AddPost(&models.Post{ID: 1, AuthorID: 1, CommunityID: 1, CreateTime: testNow.Add(-tt.postAge)})
+			s := newTestService(store)
+			if tt.prevVote != 0 {
+				p := &models.ParamVoteData{PostID: "1", Direction: tt.prevVote}
+				if err := s.VoteForPost(context.Background(), userID, p); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var changed []int64
+			s.onPostChanged = func(_ context.Context, postID int64) { changed = append(changed, postID) }
+			before := store.PostScore(1)
+
+			err := s.VoteForPost(context.Background(), userID, &models.ParamVoteData{PostID: "1", Direction: tt.direction})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VoteForPost() error = %v, want %v", err, tt.wantErr)
+			}
+			if delta := store.PostScore(1) - before; delta != tt.wantDelta {
+				t.Errorf("score delta = %v, want %v", delta, tt.wantDelta)
+			}
+			wantVote := tt.direction
+			wantChanged := []int64{1}
+			if tt.wantErr != nil {
+				wantVote, wantChanged = tt.prevVote, nil
+			}
+			votes, _ := store.GetUserVotes(context.Background(), userID, []string{"1"})
+			if votes[0] != wantVote {
+				t.Errorf("my vote = %d, want %d", votes[0], wantVote)
+			}
+			if !reflect.DeepEqual(changed, wantChanged) {
+				t.Errorf("changed posts = %v, want %v", changed, wantChanged)
+			}
+		})
+	}
+}
+
+// newListStore 两个社区各3个帖子,帖子id越大发帖时间越晚,帖子6的作者不存在
+func newListStore() *memory.Store {
+	store := memory.NewStore()
+	store.AddUser(&models.User{UserID: 1, Username: "alice"})
+	store.AddUser(&models.User{UserID: 2, Username: "bob"})
+	store.AddCommunity(&models.CommunityDetail{ID: 1, Name: "go"})
+	store.AddCommunity(&models.CommunityDetail{ID: 2, Name: "rust"})
+	for i := int64(1); i <= 6; i++ {
+		authorID := i%2 + 1
+		if i == 6 {
+			authorID = 99
+		}
+		store.AddPost(&models.Post{
+			ID:          i,
+			AuthorID:    authorID,
+			CommunityID: (i-1)/3 + 1,
+			Title:       "post " + strconv.FormatInt(i, 10),
+			CreateTime:  testNow.Add(time.Duration(i-10) * time.Hour),
+		})
+	}
+	return store
+}
+
+func postIDs(list []*models.ApiPostDetail) []int64 {
+	ids := make([]int64, 0, len(list))
+	for _, d := range list {
+		ids = append(ids, d.Post.ID)
+	}
+	return ids
+}
+
+func TestServicePostLists(t *testing.T) {
+	type listFunc func(s *Service, cursor string) ([]*models.ApiPostDetail, string, error)
+	recent := func(s *Service, c string) ([]*models.ApiPostDetail, string, error) {
+		return s.GetPostList(context.Background(), 1, 2, c, 0)
+	}
+	list := func(p models.ParamPostList) listFunc {
+		return func(s *Service, c string) ([]*models.ApiPostDetail, string, error) {
+			p.Cursor = c
+			return s.GetPostListNew(context.Background(), &p, 0)
+		}
+	}
+	tests := []struct {
+		name  string
+		setup func(s *Service, store *memory.Store)
+		list  listFunc
+		want  [][]int64 // 每一页的帖子id
+	}{
+		{
+			name: "recent",
+			list: recent,
+			// 帖子6的作者不存在,被去掉
+			want: [][]int64{{5}, {4, 3}, {2, 1}, {}},
+		},
+		{
+			name: "global by time",
+			list: list(models.ParamPostList{Page: 1, Size: 4, Order: models.OrderTime}),
+			want: [][]int64{{5, 4, 3}, {2, 1}},
+		},
+		{
+			name: "global by score",
+			// 帖子之间相差一小时,9票(3888秒)让帖子4排到帖子5前面
+			setup: func(s *Service, _ *memory.Store) {
+				for uid := int64(1); uid <= 9; uid++ {
+					_ = s.VoteForPost(context.Background(), uid, &models.ParamVoteData{PostID: "4", Direction: 1})
+				}
+			},
+			list: list(models.ParamPostList{Page: 1, Size: 3, Order: models.OrderScore}),
+			want: [][]int64{{4, 5}, {3, 2, 1}},
+		},
+		{
+			name: "community with pinned post",
+			setup: func(_ *Service, store *memory.Store) {
+				store.Pin(1, 1)
+			},
+			list: list(models.ParamPostList{Page: 1, Size: 2, CommunityID: 1, Order: models.OrderTime}),
+			// 第一页把置顶的帖子放在最前面,后面的页不再出现
+			want: [][]int64{{1, 3, 2}, {}},
+		},
+		{
+			name: "author",
+			list: list(models.ParamPostList{Page: 1, Size: 2, AuthorID: 2}),
+			want: [][]int64{{5, 3}, {1}},
+		},
+		{
+			name: "author by page",
+			list: list(models.ParamPostList{Page: 2, Size: 2, AuthorID: 2}),
+			want: [][]int64{{1}},
+		},
+		{
+			name: "feed",
+			setup: func(_ *Service, store *memory.Store) {
+				store.Follow(7, 2)
+			},
+			list: func(s *Service, c string) ([]*models.ApiPostDetail, string, error) {
+				return s.GetFeed(context.Background(), 7, &models.ParamPostList{Page: 1, Size: 2, Cursor: c, Order: models.OrderTime})
+			},
+			want: [][]int64{{5}, {4}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newListStore()
+			s := newTestService(store)
+			if tt.setup != nil {
+				tt.setup(s, store)
+			}
+			var got [][]int64
+			next := ""
+			for page := range tt.want {
+				data, n, err := tt.list(s, next)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, postIDs(data))
+				if n == "" && page < len(tt.want)-1 {
+					break
+				}
+				next = n
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("pages = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServicePostListEnrich(t *testing.T) {
+	store := newListStore()
+	store.Pin(1, 2)
+	store.SetCommentCount(3, 4)
+	s := newTestService(store)
+	for _, v := range []struct {
+		userID    int64
+		direction int8
+	}{{7, 1}, {8, 1}, {9, -1}} {
+		if err := s.VoteForPost(context.Background(), v.userID, &models.ParamVoteData{PostID: "3", Direction: v.direction}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := &models.ParamPostList{Page: 1, Size: 10, CommunityID: 1, Order: models.OrderTime}
+	data, _, err := s.GetPostListNew(context.Background(), p, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := postIDs(data); !reflect.DeepEqual(got, []int64{2, 3, 1}) {
+		t.Fatalf("post ids = %v, want [2 3 1]", got)
+	}
+	pinned, post3 := data[0], data[1]
+	if !pinned.Pinned || post3.Pinned {
+		t.Errorf("pinned = %v, %v, want true, false", pinned.Pinned, post3.Pinned)
+	}
+	if post3.AuthorName != "bob" || post3.CommunityDetail.Name != "go" {
+		t.Errorf("author = %q, community = %+v", post3.AuthorName, post3.CommunityDetail)
+	}
+	if post3.VoteNum != 2 || post3.MyVote != -1 || post3.CommentCount != 4 {
+		t.Errorf("votes = %d, my vote = %d, comments = %d, want 2, -1, 4",
+			post3.VoteNum, post3.MyVote, post3.CommentCount)
+	}
+}
@@ -5,14 +5,18 @@ import (
 	"bluebell/models"
 	"bluebell/pkg/jwt"
 	"bluebell/pkg/snowflake"
+	"context"
 	"fmt"
 )
 
 // 存放业务逻辑的代码
 
-func SignUp(p *models.ParamSignUp) (err error) {
+func SignUp(ctx context.Context, p *models.ParamSignUp) (err error) {
+	if err := checkUsername(p.Username); err != nil {
+		return err
+	}
 	// 1.判断用户存不存在
-	if err := mysql.CheckUserExist(p.Username); err != nil {
+	if err := mysql.CheckUserExist(ctx, p.Username); err != nil {
 		return err
 	}
 	// 2.生成UID
@@ -24,21 +28,21 @@ func SignUp(p *models.ParamSignUp) (err error) {
 		Password: p.Password,
 	}
 	// 3.保存进数据库
-	return mysql.InsertUser(user)
+	return mysql.InsertUser(ctx, user)
 }
 
-func Login(p *models.ParamLogin) (user *models.User, err error) {
+func Login(ctx context.Context, p *models.ParamLogin) (user *models.User, err error) {
 	user = &models.User{
 		Username: p.Username,
 		Password: p.Password,
 	}
 	// 传递的是指针，就能拿到user.UserID
-	if err := mysql.Login(user); err != nil {
+	if err := mysql.Login(ctx, user); err != nil {
 		return nil, err
 	}
 	fmt.Println(user)
 	// 生成JWT
-	token, err := jwt.GenToken(user.UserID, user.Username)
+	token, err := jwt.GenToken(user.UserID, user.Username, user.Role)
 	if err != nil {
 		return
 	}
@@ -46,18 +50,44 @@ func Login(p *models.ParamLogin) (user *models.User, err error) {
 	return
 }
 
-func GetUserPage(userID, postID int64) (user *models.User, post *models.Post) {
+func GetUserPage(ctx context.Context, userID, postID int64) (user *models.User, post *models.Post) {
 	// 通过userID拿到用户数据
 	user = new(models.User)
 	post = new(models.Post)
-	user, err := mysql.GetUserById(userID)
+	user, err := mysql.GetUserById(ctx, userID)
 	if err != nil {
 		return
 	}
 	// 通过帖子ID拿到帖子数据
-	post, err = mysql.GetPostById(postID)
+	post, err = mysql.GetPostById(ctx, postID)
 	if err != nil {
 		return
 	}
 	return
 }
+
+// CreateAdmin 创建管理员账号,用户已存在时把它提升为管理员(不修改密码)
+func CreateAdmin(ctx context.Context, username, password string) (created bool, err error) {
+	err = mysql.CheckUserExist(ctx, username)
+	if err == mysql.ErrorUserExist {
+		userID, err := mysql.SetUserRole(ctx, username, models.UserRoleRoot)
+		if err != nil {
+			return false, err
+		}
+		InvalidateUserCache(ctx, userID)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	user := &models.User{
+		UserID:   snowflake.GenID(),
+		Username: username,
+		Password: password,
+		Role:     models.UserRoleRoot,
+	}
+	if err := mysql.InsertUser(ctx, user); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -1,8 +1,12 @@
 package logic
 
 import (
+	"bluebell/dao/mysql"
 	"bluebell/dao/redis"
+	"bluebell/logger"
 	"bluebell/models"
+	"bluebell/pkg/metrics"
+	"context"
 	"strconv"
 
 	"go.uber.org/zap"
@@ -32,10 +36,73 @@ direction=-1时，有两种情况：
 */
 
 // VoteForPost 为帖子投票的函数
-func VoteForPost(userID int64, p *models.ParamVoteData) error {
-	zap.L().Debug("VoteForPost",
+func VoteForPost(ctx context.Context, userID int64, p *models.ParamVoteData) error {
+	return std.VoteForPost(ctx, userID, p)
+}
+
+// VoteForPost 为帖子投票,投票期已过返回 redis.ErrVoteTimeExpire,重复投票返回 redis.ErrVoteRepeated
+func (s *Service) VoteForPost(ctx context.Context, userID int64, p *models.ParamVoteData) error {
+	logger.FromContext(ctx).Debug("VoteForPost",
 		zap.Int64("userID", userID),
 		zap.String("postID", p.PostID),
 		zap.Int8("direction", p.Direction))
-	return redis.VoteForPost(strconv.Itoa(int(userID)), p.PostID, float64(p.Direction))
+	uid := strconv.FormatInt(userID, 10)
+	// 1. 判断投票限制
+	postTime, err := s.votes.GetPostCreateTime(ctx, p.PostID)
+	if err != nil {
+		return err
+	}
+	if float64(s.now().Unix())-postTime > redis.OneWeekInSeconds {
+		return redis.ErrVoteTimeExpire
+	}
+	// 2. 和之前的投票比较,计算分数的变化
+	ov, err := s.votes.GetUserVote(ctx, uid, p.PostID)
+	if err != nil {
+		return err
+	}
+	value := float64(p.Direction)
+	if value == ov {
+		return redis.ErrVoteRepeated
+	}
+	// 3. 更新帖子的分数并记录投票
+	if err := s.votes.SaveVote(ctx, uid, p.PostID, value, (value-ov)*redis.ScorePerVote); err != nil {
+		return err
+	}
+	metrics.Votes.WithLabelValues(metrics.VoteDirection(p.Direction)).Inc()
+	// 帖子详情中包含投票数
+	if postID, err := strconv.ParseInt(p.PostID, 10, 64); err == nil {
+		s.onPostChanged(ctx, postID)
+	}
+	return nil
+}
+
+const archiveBatchSize = 500
+
+// ArchiveVotes 把投票期已经结束的帖子的票数归档到MySQL,返回归档的帖子数
+// redis中的投票记录仍然保留,帖子列表和当前用户的投票状态还要从中读取
+func ArchiveVotes(ctx context.Context) (int64, error) {
+	var total, offset int64
+	for {
+		ids, err := redis.GetExpiredVotePostIDs(ctx, offset, archiveBatchSize)
+		if err != nil {
+			return total, err
+		}
+		if len(ids) == 0 {
+			break
+		}
+		archives, err := redis.GetPostVoteCounts(ctx, ids)
+		if err != nil {
+			return total, err
+		}
+		if err := mysql.SaveVoteArchives(ctx, archives); err != nil {
+			return total, err
+		}
+		total += int64(len(archives))
+		offset += int64(len(ids))
+		if len(ids) < archiveBatchSize {
+			break
+		}
+	}
+	logger.FromContext(ctx).Info("archive votes finished", zap.Int64("posts", total))
+	return total, nil
 }
@@ -4,11 +4,28 @@ import (
 	"bluebell/controller"
 	"bluebell/dao/mysql"
 	"bluebell/dao/redis"
+	"bluebell/dao/search"
 	"bluebell/logger"
+	"bluebell/logic"
+	"bluebell/pkg/lifecycle"
 	"bluebell/pkg/snowflake"
+	"bluebell/pkg/tracing"
 	"bluebell/router"
 	"bluebell/setting"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
 )
 
 // @title bluebell项目接口文档
@@ -20,44 +37,239 @@ import (
 // @host 127.0.0.1:8080
 // @BasePath /api/v1
 func main() {
-	//if len(os.Args) < 2 {
-	//	fmt.Println("need config file.eg: bluebell config.yaml")
-	//	return
-	//}
+	fs := flag.NewFlagSet("bluebell", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath(), "配置文件路径,也可以用环境变量 BLUEBELL_CONFIG 指定")
+	fs.Usage = func() { usage(fs) }
+	_ = fs.Parse(os.Args[1:])
+
+	args := fs.Args()
+	// 兼容旧的启动方式: bluebell conf/config.yaml
+	if len(args) > 0 && isConfigFile(args[0]) {
+		*configPath, args = args[0], args[1:]
+	}
+	name := "serve"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Printf("unknown command %q\n\n", name)
+		usage(fs)
+		os.Exit(2)
+	}
 	// 加载配置
-	if err := setting.Init(); err != nil {
+	if err := setting.Init(*configPath); err != nil {
 		fmt.Printf("load config failed, err:%v\n", err)
-		return
+		os.Exit(1)
 	}
-	if err := logger.Init(setting.Conf.LogConfig, setting.Conf.Mode); err != nil {
-		fmt.Printf("init logger failed, err:%v\n", err)
-		return
+	if err := cmd.run(args); err != nil {
+		fmt.Printf("%s failed, err:%v\n", name, err)
+		os.Exit(1)
+	}
+}
+
+// command 子命令
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]*command{
+	"serve":         {usage: "启动web服务(默认)", run: runServe},
+	"migrate":       {usage: "执行数据库迁移: migrate up | migrate down [-steps N]", run: runMigrate},
+	"reindex":       {usage: "从MySQL重建redis中的帖子索引: reindex [-dry-run]", run: runReindex},
+	"create-admin":  {usage: "创建管理员账号: create-admin -username NAME -password PASS", run: runCreateAdmin},
+	"archive-votes": {usage: "把投票期结束的帖子的票数归档到MySQL", run: runArchiveVotes},
+	"check-config":  {usage: "检查配置并打印生效的配置(隐藏密码等敏感信息)", run: runCheckConfig},
+}
+
+func usage(fs *flag.FlagSet) {
+	fmt.Fprintf(fs.Output(), "usage: bluebell [-config FILE] <command> [args]\n\ncommands:\n")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(fs.Output(), "  %-14s %s\n", name, commands[name].usage)
 	}
-	if err := mysql.Init(setting.Conf.MySQLConfig); err != nil {
-		fmt.Printf("init mysql failed, err:%v\n", err)
-		return
+	fmt.Fprintf(fs.Output(), "\nflags:\n")
+	fs.PrintDefaults()
+	fmt.Fprintf(fs.Output(), "\n配置项可以用 BLUEBELL_ 开头的环境变量覆盖,如 BLUEBELL_MYSQL_HOST 覆盖 mysql.host\n")
+}
+
+func defaultConfigPath() string {
+	if path := os.Getenv("BLUEBELL_CONFIG"); path != "" {
+		return path
 	}
-	defer mysql.Close() // 程序退出关闭数据库连接
-	if err := redis.Init(setting.Conf.RedisConfig); err != nil {
-		fmt.Printf("init redis failed, err:%v\n", err)
-		return
+	return "./conf/config.yaml"
+}
+
+func isConfigFile(arg string) bool {
+	switch filepath.Ext(arg) {
+	case ".yaml", ".yml", ".json", ".toml":
+		return true
 	}
-	defer redis.Close()
+	return false
+}
 
-	if err := snowflake.Init(setting.Conf.StartTime, setting.Conf.MachineID); err != nil {
-		fmt.Printf("init snowflake failed, err:%v\n", err)
-		return
+// runServe 启动web服务,收到SIGINT或SIGTERM后在 shutdown_timeout 内停止接收新请求、
+// 等待处理中的请求结束,再按启动的相反顺序停止后台任务和关闭连接
+func runServe(_ []string) error {
+	if err := setting.Conf.Validate(); err != nil {
+		return err
+	}
+	if err := logger.Init(setting.Conf.LogConfig, setting.Conf.Mode); err != nil {
+		return fmt.Errorf("init logger failed: %w", err)
 	}
+	defer zap.L().Sync()
 	// 初始化gin框架内置的校验器使用的翻译器
-	if err := controller.InitTrans("zh"); err != nil {
-		fmt.Printf("init validator trans failed, err:%v\n", err)
-		return
-	}
-	// 注册路由
-	r := router.SetupRouter(setting.Conf.Mode)
-	err := r.Run(fmt.Sprintf(":%d", setting.Conf.Port))
-	if err != nil {
-		fmt.Printf("run server failed, err:%v\n", err)
-		return
+	if err := controller.InitTrans(); err != nil {
+		return fmt.Errorf("init validator trans failed: %w", err)
+	}
+
+	serveErr := make(chan error, 1)
+	lc := lifecycle.New()
+	// 最先启动,退出时最后停止,导出其他组件停止过程中产生的span
+	var shutdownTracing func(context.Context) error
+	lc.Append(lifecycle.Hook{
+		Name: "tracing",
+		OnStart: func(context.Context) (err error) {
+			shutdownTracing, err = tracing.Init(tracing.Options{
+				ServiceName:    setting.Conf.Name,
+				ServiceVersion: setting.Conf.Version,
+				Exporter:       setting.Conf.TracingConfig.Exporter,
+				Endpoint:       setting.Conf.TracingConfig.Endpoint,
+				Insecure:       setting.Conf.TracingConfig.Insecure,
+				SampleRatio:    setting.Conf.TracingConfig.SampleRatio,
+			})
+			return err
+		},
+		OnStop: func(ctx context.Context) error { return shutdownTracing(ctx) },
+	})
+	lc.Append(lifecycle.Hook{
+		Name: "mysql",
+		OnStart: func(ctx context.Context) error {
+			if err := mysql.Init(setting.Conf.MySQLConfig); err != nil {
+				return err
+			}
+			if setting.Conf.MySQLConfig.AutoMigrate {
+				return migrateUp(ctx)
+			}
+			return nil
+		},
+		OnStop: lifecycle.StopFunc(mysql.Close),
+	})
+	lc.Append(lifecycle.Hook{
+		Name:    "redis",
+		OnStart: func(context.Context) error { return redis.Init(setting.Conf.RedisConfig) },
+		OnStop:  lifecycle.StopFunc(redis.Close),
+	})
+	// 其他实例修改用户名或社区信息后,删除本实例进程内缓存中的旧数据
+	lc.Append(lifecycle.Hook{
+		Name:    "cache invalidation",
+		OnStart: func(context.Context) error { return logic.StartCacheInvalidation() },
+		OnStop:  lifecycle.StopFunc(logic.StopCacheInvalidation),
+	})
+	// bleve索引是新建的时候在后台从MySQL回填
+	lc.Append(lifecycle.Hook{
+		Name: "search",
+		OnStart: func(context.Context) error {
+			if err := search.Init(setting.Conf.SearchConfig); err != nil {
+				return err
+			}
+			logic.StartSearchBackfill()
+			return nil
+		},
+		OnStop: lifecycle.StopFunc(func() {
+			logic.StopSearchBackfill()
+			search.Close()
+		}),
+	})
+	lc.Append(lifecycle.Hook{
+		Name: "snowflake",
+		OnStart: func(context.Context) error {
+			return snowflake.Init(setting.Conf.StartTime, setting.Conf.MachineID)
+		},
+	})
+	// 敏感词库,管理员修改后通过redis通知所有实例重新加载
+	lc.Append(lifecycle.Hook{
+		Name:    "moderation",
+		OnStart: func(context.Context) error { return logic.InitModeration(setting.Conf.ModerationConfig) },
+		OnStop:  lifecycle.StopFunc(logic.StopModeration),
+	})
+	// 把帖子的变更事件同步到redis和搜索索引
+	lc.Append(lifecycle.Hook{
+		Name:    "outbox relay",
+		OnStart: func(context.Context) error { logic.StartOutboxRelay(); return nil },
+		OnStop:  lifecycle.StopFunc(logic.StopOutboxRelay),
+	})
+	lc.Append(httpServerHook(serveErr))
+	// 最后启动,退出时最先停止: 让就绪检查失败,等负载均衡摘除本实例后再关闭HTTP服务
+	lc.Append(lifecycle.Hook{
+		Name: "readiness",
+		OnStop: func(ctx context.Context) error {
+			logic.SetShuttingDown()
+			select {
+			case <-time.After(setting.Conf.ShutdownDelay):
+			case <-ctx.Done():
+			}
+			return nil
+		},
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := lc.Start(ctx); err != nil {
+		return err
+	}
+	zap.L().Info("server started", zap.Int("port", setting.Conf.Port))
+
+	var err error
+	select {
+	case <-ctx.Done():
+		zap.L().Info("shutting down server")
+	case err = <-serveErr:
+		zap.L().Error("server stopped unexpectedly", zap.Error(err))
+	}
+	stop() // 再次收到信号时直接退出
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), setting.Conf.ShutdownTimeout)
+	defer cancel()
+	if stopErr := lc.Stop(shutdownCtx); stopErr != nil {
+		zap.L().Error("shutdown failed", zap.Error(stopErr))
+		err = errors.Join(err, stopErr)
+	}
+	zap.L().Info("server exited")
+	return err
+}
+
+// httpServerHook 监听端口后在后台处理请求,停止时等待处理中的请求结束
+// 服务意外退出时把错误发送到serveErr
+func httpServerHook(serveErr chan<- error) lifecycle.Hook {
+	cfg := setting.Conf.ServerConfig
+	srv := &http.Server{
+		Addr:         fmt.Sprintf(":%d", setting.Conf.Port),
+		Handler:      router.SetupRouter(setting.Conf.Mode),
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+	return lifecycle.Hook{
+		Name: "http server",
+		OnStart: func(context.Context) error {
+			// 先监听端口,端口被占用时启动失败
+			ln, err := net.Listen("tcp", srv.Addr)
+			if err != nil {
+				return err
+			}
+			go func() {
+				if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					serveErr <- err
+				}
+			}()
+			return nil
+		},
+		OnStop: srv.Shutdown,
 	}
 }
@@ -3,10 +3,12 @@ package middlewares
 import (
 	"bluebell/controller"
 	"bluebell/dao/mysql"
+	"bluebell/logger"
 	"bluebell/pkg/jwt"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 // JWTAuthMiddleware 基于JWT的认证中间件
@@ -38,11 +40,29 @@ func JWTAuthMiddleware() func(c *gin.Context) {
 		}
 		// 将当前请求的userID信息保存到请求的上下文c上
 		c.Set(controller.CtxUserIDKey, mc.UserID)
+		c.Set(controller.CtxUserRoleKey, mc.Role)
+		withUserLogger(c, mc.UserID)
 
 		c.Next() // 后续的处理请求的函数中 可以用过c.Get(CtxUserIDKey) 来获取当前请求的用户信息
 	}
 }
 
+// JWTAuthOptionalMiddleware 可选的JWT认证中间件
+// 携带有效Token时与 JWTAuthMiddleware 一样保存用户信息,否则按未登录处理,不拦截请求
+func JWTAuthOptionalMiddleware() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		parts := strings.SplitN(c.Request.Header.Get("Authorization"), " ", 2)
+		if len(parts) == 2 && parts[0] == "Bearer" {
+			if mc, err := jwt.ParseToken(parts[1]); err == nil {
+				c.Set(controller.CtxUserIDKey, mc.UserID)
+				c.Set(controller.CtxUserRoleKey, mc.Role)
+				withUserLogger(c, mc.UserID)
+			}
+		}
+		c.Next()
+	}
+}
+
 // AuthManager 管理员中间件身份验证
 func AuthManager() func(ctx *gin.Context) {
 	return func(c *gin.Context) { // 这里的具体实现方式要依据你的实际业务情况决定
@@ -75,14 +95,20 @@ func AuthManager() func(ctx *gin.Context) {
 			return
 		}
 		// 数据库查询信息
-		if _, err := mysql.GetUserById(id); err != nil {
+		if _, err := mysql.GetUserById(c.Request.Context(), id); err != nil {
 			controller.ResponseError(c, controller.CodeInvalidToken)
 			c.Abort()
 			return
 		}
 		// 将当前请求的userID信息保存到请求的上下文c上
 		c.Set(controller.CtxUserIDKey, mc.UserID)
+		withUserLogger(c, mc.UserID)
 		// 后续的处理请求的函数中 可以用过c.Get(CtxUserIDKey) 来获取当前请求的用户信息
 		c.Next()
 	}
 }
+
+// withUserLogger 之后这个请求的日志都带上user_id
+func withUserLogger(c *gin.Context, userID int64) {
+	c.Request = c.Request.WithContext(logger.With(c.Request.Context(), zap.Int64("user_id", userID)))
+}
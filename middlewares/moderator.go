@@ -0,0 +1,92 @@
+package middlewares
+
+import (
+	"bluebell/controller"
+	"bluebell/dao/mysql"
+	"bluebell/logger"
+	"bluebell/logic"
+	"bluebell/models"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// CommunityModerator 社区版主中间件,需要放在JWTAuthMiddleware之后
+// 站点管理员(root)或目标社区的版主/所有者才能通过
+func CommunityModerator() func(c *gin.Context) {
+	return communityRoleRequired(models.ModeratorRoleModerator)
+}
+
+// CommunityOwner 社区所有者中间件,需要放在JWTAuthMiddleware之后
+// 站点管理员(root)或目标社区的所有者才能通过
+func CommunityOwner() func(c *gin.Context) {
+	return communityRoleRequired(models.ModeratorRoleOwner)
+}
+
+func communityRoleRequired(required int8) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		uid, ok := c.Get(controller.CtxUserIDKey)
+		if !ok {
+			controller.ResponseError(c, controller.CodeNeedLogin)
+			c.Abort()
+			return
+		}
+		userID, _ := uid.(int64)
+		// 1. 确定目标社区: 路径中的community_id优先,否则从目标帖子中查询所属社区
+		communityID, err := resolveCommunityID(c)
+		if err != nil {
+			controller.HandleError(c, err)
+			return
+		}
+		// 2. 站点管理员拥有所有社区的权限
+		if c.GetString(controller.CtxUserRoleKey) != "root" {
+			role, err := logic.GetModeratorRole(c.Request.Context(), communityID, userID)
+			if err != nil {
+				logger.FromContext(c.Request.Context()).Error("logic.GetModeratorRole failed",
+					zap.Int64("community_id", communityID),
+					zap.Int64("user_id", userID),
+					zap.Error(err))
+				controller.HandleError(c, err)
+				return
+			}
+			if role < required {
+				controller.ResponseError(c, controller.CodeNoPermission)
+				c.Abort()
+				return
+			}
+		}
+		// 将目标社区保存到请求的上下文c上,后续的处理函数只能操作这个社区
+		c.Set(controller.CtxCommunityIDKey, communityID)
+		c.Next()
+	}
+}
+
+// resolveCommunityID 从路径参数community_id或post_id中解析出社区ID
+func resolveCommunityID(c *gin.Context) (int64, error) {
+	if cidStr := c.Param("community_id"); cidStr != "" {
+		communityID, err := strconv.ParseInt(cidStr, 10, 64)
+		if err != nil {
+			return 0, controller.NewAppError(controller.CodeInvalidParam).WithErr(err)
+		}
+		return communityID, nil
+	}
+	pidStr := c.Param("post_id")
+	if pidStr == "" {
+		return 0, controller.NewAppError(controller.CodeInvalidParam)
+	}
+	postID, err := strconv.ParseInt(pidStr, 10, 64)
+	if err != nil {
+		return 0, controller.NewAppError(controller.CodeInvalidParam).WithErr(err)
+	}
+	communityID, err := logic.GetPostCommunityID(c.Request.Context(), postID)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.GetPostCommunityID failed", zap.Int64("post_id", postID), zap.Error(err))
+		if errors.Is(err, mysql.ErrorInvalidID) {
+			return 0, controller.NewAppError(controller.CodePostNotExist).WithErr(err)
+		}
+		return 0, err
+	}
+	return communityID, nil
+}
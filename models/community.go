@@ -5,11 +5,19 @@ import "time"
 type Community struct {
 	ID   int64  `json:"id" db:"community_id"`
 	Name string `json:"name" db:"community_name"`
+	Slug string `json:"slug" db:"slug"`
 }
 
 type CommunityDetail struct {
 	ID           int64     `json:"id" db:"community_id"`
 	Name         string    `json:"name" db:"community_name"`
+	Slug         string    `json:"slug" db:"slug"`
 	Introduction string    `json:"introduction,omitempty" db:"introduction"`
 	CreateTime   time.Time `json:"create_time" db:"create_time"`
 }
+
+// ApiCommunityPosts 社区主页接口的结构体
+type ApiCommunityPosts struct {
+	Community *CommunityDetail `json:"community"` // 社区详情
+	Posts     []*ApiPostDetail `json:"posts"`     // 按排序依据分页的帖子列表
+}
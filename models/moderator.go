@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// 版主角色
+const (
+	ModeratorRoleNone      int8 = 0 // 不是版主
+	ModeratorRoleModerator int8 = 1 // 版主
+	ModeratorRoleOwner     int8 = 2 // 社区所有者
+)
+
+// CommunityModerator 社区版主
+type CommunityModerator struct {
+	CommunityID int64     `json:"community_id" db:"community_id"`
+	UserID      int64     `json:"user_id,string" db:"user_id"`
+	Role        int8      `json:"role" db:"role"`
+	CreateTime  time.Time `json:"create_time" db:"create_time"`
+}
+
+// CommunityBan 社区内的禁言记录
+type CommunityBan struct {
+	CommunityID int64      `json:"community_id" db:"community_id"`
+	UserID      int64      `json:"user_id,string" db:"user_id"`
+	OperatorID  int64      `json:"operator_id,string" db:"operator_id"`
+	Reason      string     `json:"reason" db:"reason"`
+	ExpireTime  *time.Time `json:"expire_time" db:"expire_time"` // 为空表示永久禁言
+}
+
+// UserSuspension 全站封禁记录
+type UserSuspension struct {
+	UserID     int64      `json:"user_id,string" db:"user_id"`
+	OperatorID int64      `json:"operator_id,string" db:"operator_id"`
+	Reason     string     `json:"reason" db:"reason"`
+	ExpireTime *time.Time `json:"expire_time" db:"expire_time"` // 为空表示永久封禁
+}
+
+// Active 判断禁言是否仍然有效
+func (b *CommunityBan) Active(now time.Time) bool {
+	return b.ExpireTime == nil || b.ExpireTime.After(now)
+}
+
+// Active 判断封禁是否仍然有效
+func (s *UserSuspension) Active(now time.Time) bool {
+	return s.ExpireTime == nil || s.ExpireTime.After(now)
+}
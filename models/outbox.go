@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// 帖子变更事件的类型
+const (
+	OutboxPostCreated = "post_created"
+	OutboxPostUpdated = "post_updated"
+	OutboxPostDeleted = "post_deleted"
+)
+
+// OutboxEvent 与帖子在同一个事务中写入的变更事件,由后台任务同步到redis和搜索索引
+type OutboxEvent struct {
+	ID          int64      `json:"id" db:"id"`
+	EventType   string     `json:"event_type" db:"event_type"`
+	PostID      int64      `json:"post_id,string" db:"post_id"`
+	CommunityID int64      `json:"community_id" db:"community_id"`
+	Attempts    int        `json:"attempts" db:"attempts"`
+	LastError   string     `json:"last_error" db:"last_error"`
+	CreateTime  time.Time  `json:"create_time" db:"create_time"`
+	ProcessedAt *time.Time `json:"processed_time" db:"processed_time"`
+}
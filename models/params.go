@@ -31,7 +31,62 @@ type ParamVoteData struct {
 // ParamPostList 获取帖子列表query string参数
 type ParamPostList struct {
 	CommunityID int64  `json:"community_id" form:"community_id"`   // 可以为空
-	Page        int64  `json:"page" form:"page" example:"1"`       // 页码
+	AuthorID    int64  `json:"author_id" form:"author_id"`         // 只看某个作者的帖子,可以为空
+	Cursor      string `json:"cursor" form:"cursor"`               // 上一页响应中的next_cursor,为空表示第一页
+	Page        int64  `json:"page" form:"page" example:"1"`       // 页码,已废弃,请使用cursor
 	Size        int64  `json:"size" form:"size" example:"10"`      // 每页数据量
 	Order       string `json:"order" form:"order" example:"score"` // 排序依据
 }
+
+// ParamModerator 添加版主请求参数
+type ParamModerator struct {
+	UserID int64 `json:"user_id,string" binding:"required"`  // 用户id
+	Role   int8  `json:"role" binding:"omitempty,oneof=1 2"` // 版主(1)还是社区所有者(2),默认为版主
+}
+
+// ParamCreateCommunity 创建社区请求参数
+type ParamCreateCommunity struct {
+	Name         string `json:"name" binding:"required,max=128"`         // 社区名称
+	Introduction string `json:"introduction" binding:"required,max=256"` // 社区简介
+}
+
+// ParamBanUser 社区禁言请求参数
+type ParamBanUser struct {
+	UserID   int64  `json:"user_id,string" binding:"required"` // 被禁言的用户id
+	Duration int64  `json:"duration" binding:"gte=0"`          // 禁言时长(秒),0表示永久
+	Reason   string `json:"reason" binding:"max=256"`          // 禁言原因
+}
+
+// ParamSuspendUser 全站封禁请求参数
+type ParamSuspendUser struct {
+	UserID   int64  `json:"user_id,string" binding:"required"` // 被封禁的用户id
+	Duration int64  `json:"duration" binding:"gte=0"`          // 封禁时长(秒),0表示永久
+	Reason   string `json:"reason" binding:"required,max=256"` // 封禁原因
+}
+
+// ParamEditPost 修改帖子请求参数
+type ParamEditPost struct {
+	Title   string `json:"title" binding:"required"`   // 帖子标题
+	Content string `json:"content" binding:"required"` // 帖子内容
+}
+
+// ParamSearch 搜索帖子query string参数
+type ParamSearch struct {
+	Q           string `json:"q" form:"q" binding:"required"`           // 关键词
+	CommunityID int64  `json:"community_id" form:"community_id"`        // 可以为空
+	Author      string `json:"author" form:"author"`                    // 作者用户名,可以为空
+	Since       string `json:"since" form:"since" example:"2020-08-01"` // 起始发帖日期,可以为空
+	Cursor      string `json:"cursor" form:"cursor"`                    // 上一页响应中的next_cursor,为空表示第一页
+	Page        int64  `json:"page" form:"page" example:"1"`            // 页码,已废弃,请使用cursor
+	Size        int64  `json:"size" form:"size" example:"10"`           // 每页数据量
+}
+
+// ParamRequeueOutbox 重新处理帖子变更事件请求参数
+type ParamRequeueOutbox struct {
+	IDs []int64 `json:"ids" binding:"max=1000"` // 事件ID,为空时重新加入所有不再自动重试的事件
+}
+
+// ParamSensitiveWords 添加或删除敏感词请求参数
+type ParamSensitiveWords struct {
+	Words []string `json:"words" binding:"required,min=1,max=1000,dive,required,max=128"` // 敏感词,每次最多1000个
+}
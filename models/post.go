@@ -16,15 +16,37 @@ type Post struct {
 
 // ApiPostDetail 帖子详情接口的结构体
 type ApiPostDetail struct {
-	AuthorName       string             `json:"author_name"` // 作者
-	VoteNum          int64              `json:"vote_num"`    // 投票数
+	AuthorName       string             `json:"author_name"`   // 作者
+	VoteNum          int64              `json:"vote_num"`      // 投票数
+	Pinned           bool               `json:"pinned"`        // 是否在社区内置顶
+	MyVote           int8               `json:"my_vote"`       // 当前用户的投票,未登录或没有投票时为0
+	CommentCount     int64              `json:"comment_count"` // 评论数
 	*Post                               // 嵌入帖子结构体
 	*CommunityDetail `json:"community"` // 嵌入社区信息
 }
 
 type Comment struct {
-	ID      int64  `json:"id,string" db:"post_id"`
-	UserID  int64  `json:"userID" db:"user_id"`
-	Content string `json:"content" db:"content" binding:"required"`
-	Time    int64  `json:"create_time" db:"create_time"`
+	ID        int64  `json:"id,string" db:"post_id"`
+	CommentID int64  `json:"comment_id,string" db:"comment_id"`
+	UserID    int64  `json:"userID" db:"user_id"`
+	Content   string `json:"content" db:"content" binding:"required"`
+	Time      int64  `json:"create_time" db:"create_time"`
+}
+
+// ApiSearchHit 搜索结果中的帖子
+type ApiSearchHit struct {
+	*ApiPostDetail
+	Highlight *ApiHighlight `json:"highlight"` // 高亮后的片段
+}
+
+// ApiHighlight 搜索结果中高亮后的片段,关键词用<mark>标签包起来
+type ApiHighlight struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+// ApiSearchResult 搜索接口的结构体
+type ApiSearchResult struct {
+	Total int64           `json:"total"` // 命中的总数
+	List  []*ApiSearchHit `json:"list"`
 }
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// VoteArchive 投票期结束后归档到MySQL的投票数据
+type VoteArchive struct {
+	PostID    int64 `json:"post_id,string" db:"post_id"`
+	UpVotes   int64 `json:"up_votes" db:"up_votes"`
+	DownVotes int64 `json:"down_votes" db:"down_votes"`
+}
+
+// PostIndexEntry 帖子在redis索引中应该有的数据
+type PostIndexEntry struct {
+	PostID      int64
+	CommunityID int64
+	CreateTime  time.Time
+	Score       float64 // 分数zset中不存在这个帖子时使用的分数
+}
+
+// ReindexReport 重建redis索引的结果,DryRun为true时只统计不修改
+type ReindexReport struct {
+	DryRun           bool  `json:"dry_run"`
+	Posts            int64 `json:"posts"`              // MySQL中的帖子数
+	MissingTime      int64 `json:"missing_time"`       // 不在时间zset中的帖子数
+	MismatchedTime   int64 `json:"mismatched_time"`    // 时间zset中的分数与发帖时间不一致的帖子数
+	MissingScore     int64 `json:"missing_score"`      // 不在分数zset中的帖子数
+	ScoreFromArchive int64 `json:"score_from_archive"` // 分数根据归档的投票数据计算的帖子数
+	MissingCommunity int64 `json:"missing_community"`  // 不在社区set中的帖子数
+	Stale            int64 `json:"stale"`              // 索引中存在但MySQL中已经没有(或社区不一致)的成员数
+	SearchIndexed    int64 `json:"search_indexed"`     // 写入bleve搜索索引的帖子数,使用mysql搜索或DryRun时为0
+	OutboxDead       int64 `json:"outbox_dead"`        // 不再自动重试的帖子变更事件数,见 /manager/outbox/dead
+}
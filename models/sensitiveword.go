@@ -0,0 +1,19 @@
+package models
+
+// SensitiveWord 敏感词库中的一个词
+type SensitiveWord struct {
+	Category string `json:"category" db:"category"`
+	Word     string `json:"word" db:"word"`
+}
+
+// SensitiveWordCategory 敏感词的分类及词的数量
+type SensitiveWordCategory struct {
+	Category string `json:"category" db:"category"`
+	Count    int64  `json:"count" db:"count"`
+}
+
+// SensitiveWordsResult 修改敏感词库的结果
+type SensitiveWordsResult struct {
+	Added   int64 `json:"added"`   // 新增的词数,已经存在的词不计入
+	Removed int64 `json:"removed"` // 删除的词数,不存在的词不计入
+}
@@ -1,5 +1,10 @@
 package models
 
+const (
+	UserRoleUser = "user" // 普通用户
+	UserRoleRoot = "root" // 管理员
+)
+
 type User struct {
 	UserID   int64  `db:"user_id"`
 	Username string `db:"username"`
@@ -7,6 +12,7 @@ type User struct {
 	Avatar   string `db:"avatar"`
 	Email    string `db:"email"`
 	Phone    string `db:"phone"`
+	Role     string `db:"role"`
 	Token    string
 }
 
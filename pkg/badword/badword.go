@@ -2,8 +2,6 @@ package badword
 
 import (
 	"bluebell/models"
-	"bufio"
-	"os"
 	"sync"
 )
 
@@ -37,6 +35,10 @@ func (t *TrieV1) Insert(word string) {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
+	t.insert(word)
+}
+
+func (t *TrieV1) insert(word string) {
 	node := t.root
 	for _, char := range []rune(word) {
 		if _, ok := node.children[char]; !ok {
@@ -56,34 +58,28 @@ func (t *TrieV1) Insert(word string) {
 
 // Contains 检测文本中是否包含敏感词
 func (t *TrieV1) Contains(text string) bool {
-	t.lock.RLock()
-	defer t.lock.RUnlock()
-	node := t.root
-	for _, char := range []rune(text) {
-		if _, ok := node.children[char]; !ok {
-			continue
-		}
-		node = node.children[char]
-		if node.isEnd {
-			return true
-		}
-	}
-	return false
+	return t.Check(text) != ""
 }
 
 // Check 检测文本中是否包含敏感词，并返回第一个敏感词
+// 从每个字符开始重新从根节点匹配,复杂度是 O(文本长度*最长敏感词长度),
+// 词库较大时使用 Matcher
 func (t *TrieV1) Check(text string) string {
 	t.lock.RLock()
 	defer t.lock.RUnlock()
 
-	node := t.root
-	for _, char := range text {
-		if _, ok := node.children[char]; !ok {
-			continue
-		}
-		node = node.children[char]
-		if node.isEnd {
-			return node.Text
+	runes := []rune(text)
+	for start := range runes {
+		node := t.root
+		for _, char := range runes[start:] {
+			next, ok := node.children[char]
+			if !ok {
+				break
+			}
+			node = next
+			if node.isEnd {
+				return node.Text
+			}
 		}
 	}
 
@@ -95,10 +91,10 @@ func (t *TrieV1) Rebuild(words []string) {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
-	t.root = &TrieV1Node{}
+	t.root = &TrieV1Node{children: make(map[rune]*TrieV1Node)}
 
 	for _, word := range words {
-		t.Insert(word)
+		t.insert(word)
 	}
 }
 
@@ -114,25 +110,21 @@ func (t *TrieV1) Delete(word string) {
 			return
 		}
 		node = node.children[char]
+	}
 
-		if node.isEnd {
-			node.isEnd = false
-			node.Text = ""
-
-			if len(node.children) > 0 { // 有子节点，不能删除
-				break
-			}
-
-			// 递归删除
-			t.doDel(node)
-		}
-
+	if !node.isEnd {
+		return
 	}
+	node.isEnd = false
+	node.Text = ""
+
+	// 没有子节点时删除这个节点及不再需要的上级节点
+	t.doDel(node)
 }
 
 func (t *TrieV1) doDel(node *TrieV1Node) {
-	// 再次判断是否可以删除
-	if node == nil || len(node.children) > 0 {
+	// 根节点、其他敏感词的结尾和有子节点的节点不能删除
+	if node == nil || node.parent == nil || node.isEnd || len(node.children) > 0 {
 		return
 	}
 
@@ -143,25 +135,15 @@ func (t *TrieV1) doDel(node *TrieV1Node) {
 	t.doDel(node.parent)
 }
 
-// LoadWordsFromFile 从文件加载敏感词列表
+// LoadWordsFromFile 从文件加载敏感词列表,加入到已有的敏感词中
 func (t *TrieV1) LoadWordsFromFile(filename string) error {
-	file, err := os.Open(filename)
+	words, err := readWords(filename)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	var words []string
-	for scanner.Scan() {
-		words = append(words, scanner.Text())
-	}
-
-	if err := scanner.Err(); err != nil {
-		return err
+	for _, word := range words {
+		t.Insert(word)
 	}
-
-	t.Rebuild(words)
 	return nil
 }
 
@@ -0,0 +1,242 @@
+package badword
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+// wordFileExt 词库文件的扩展名,每行一个敏感词
+const wordFileExt = ".txt"
+
+// reloadDelay 收到变化通知后等待这么久再重新加载,批量修改词库时会连续发出多个通知
+const reloadDelay = 200 * time.Millisecond
+
+// reloadTimeout 后台加载一次词库的超时时间
+const reloadTimeout = 30 * time.Second
+
+// Loader 加载所有分类的敏感词,返回的是词库中的原始形式,由过滤器转换
+type Loader func(ctx context.Context) ([]Entry, error)
+
+// DirLoader 从目录中的词库文件加载敏感词,见 LoadDir
+func DirLoader(dir string) Loader {
+	return func(context.Context) ([]Entry, error) { return LoadDir(dir) }
+}
+
+// ModerationFilter 用 Loader 加载的敏感词构建的过滤器,可以被多个请求同时使用
+// 命中后按分类的处理方式处理
+// 词库和待检查的文本都先经过 Normalizer 转换,返回的位置是在原文中的位置
+// 词库变化时在后台重新构建,构建完成后原子替换,加载失败时继续使用旧的词库
+type ModerationFilter struct {
+	load       Loader
+	policies   Policies
+	normalizer *Normalizer // 为nil时不转换
+	matcher    atomic.Pointer[Matcher]
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewModerationFilter 创建过滤器并加载词库
+func NewModerationFilter(ctx context.Context, load Loader, policies Policies, normalizer *Normalizer) (*ModerationFilter, error) {
+	f := &ModerationFilter{load: load, policies: policies, normalizer: normalizer}
+	if _, err := f.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Reload 重新加载词库,返回敏感词的数量
+// 新的自动机由加载的词整体构建,Matcher 不支持增量修改
+func (f *ModerationFilter) Reload(ctx context.Context) (int, error) {
+	entries, err := f.load(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if f.normalizer != nil {
+		for i := range entries {
+			entries[i].Word = f.normalizer.Normalize(entries[i].Word).Text
+		}
+	}
+	m := NewMatcher(entries)
+	f.matcher.Store(m)
+	return m.Len(), nil
+}
+
+// Check 返回文本中的第一个敏感词,不包含敏感词时返回空字符串
+// 返回的是词库中的敏感词经过转换后的形式
+func (f *ModerationFilter) Check(text string) string {
+	if matches := f.FindAll(text); len(matches) > 0 {
+		return matches[0].Word
+	}
+	return ""
+}
+
+// FindAll 返回文本中所有的敏感词及其在原文中的位置和分类
+func (f *ModerationFilter) FindAll(text string) []Match {
+	var matches []Match
+	if f.normalizer == nil {
+		matches = f.matcher.Load().FindAll(text)
+	} else {
+		n := f.normalizer.Normalize(text)
+		matches = n.Original(f.matcher.Load().FindAll(n.Text))
+	}
+	return wholeWords(text, matches)
+}
+
+// wholeWords 以字母或数字开头(结尾)的敏感词,前(后)面不能紧跟着字母或数字,
+// 否则像 "SM" 这样的短词在去掉空格和转换成小写后会出现在很多正常的英文中,如 "is more"
+func wholeWords(text string, matches []Match) []Match {
+	res := matches[:0]
+	for _, m := range matches {
+		first, _ := utf8.DecodeRuneInString(m.Word)
+		last, _ := utf8.DecodeLastRuneInString(m.Word)
+		if isAlnum(first) && m.Start > 0 {
+			if r, _ := utf8.DecodeLastRuneInString(text[:m.Start]); isAlnum(foldWidth(r)) {
+				continue
+			}
+		}
+		if isAlnum(last) && m.End < len(text) {
+			if r, _ := utf8.DecodeRuneInString(text[m.End:]); isAlnum(foldWidth(r)) {
+				continue
+			}
+		}
+		res = append(res, m)
+	}
+	return res
+}
+
+func isAlnum(r rune) bool {
+	return r < utf8.RuneSelf && (r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+}
+
+// Replace 把文本中所有的敏感词替换成*,不区分分类的处理方式
+func (f *ModerationFilter) Replace(text string) (string, []Match) {
+	matches := f.FindAll(text)
+	return Mask(text, matches), matches
+}
+
+// Moderate 按分类的处理方式处理文本
+func (f *ModerationFilter) Moderate(text string) Verdict {
+	return f.policies.Apply(text, f.FindAll(text))
+}
+
+// Watch 在后台重新加载词库: 每次从changes收到通知时,以及每隔interval,interval为0时不定期加载
+// 短时间内的多个通知合并成一次加载;changes被关闭后只定期加载,调用Close停止
+func (f *ModerationFilter) Watch(changes <-chan string, interval time.Duration) {
+	f.done = make(chan struct{})
+	f.wg.Add(1)
+	go f.watch(changes, interval)
+}
+
+func (f *ModerationFilter) watch(changes <-chan string, interval time.Duration) {
+	defer f.wg.Done()
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	var delay <-chan time.Time
+	for {
+		select {
+		case <-f.done:
+			return
+		case category, ok := <-changes:
+			if !ok {
+				changes = nil
+				continue
+			}
+			zap.L().Debug("sensitive words changed", zap.String("category", category))
+			if delay == nil {
+				delay = time.After(reloadDelay)
+			}
+		case <-delay:
+			delay = nil
+			f.reload()
+		case <-tick:
+			f.reload()
+		}
+	}
+}
+
+func (f *ModerationFilter) reload() {
+	ctx, cancel := context.WithTimeout(context.Background(), reloadTimeout)
+	defer cancel()
+	n, err := f.Reload(ctx)
+	if err != nil {
+		zap.L().Error("reload sensitive words failed, keep using the old list", zap.Error(err))
+		return
+	}
+	zap.L().Info("sensitive words reloaded", zap.Int("words", n))
+}
+
+// Close 停止后台加载
+func (f *ModerationFilter) Close() error {
+	if f.done == nil {
+		return nil
+	}
+	close(f.done)
+	f.wg.Wait()
+	return nil
+}
+
+// LoadDir 加载目录下所有词库文件中的敏感词
+// 每个词库文件是一个分类,分类名是去掉扩展名的文件名
+func LoadDir(dir string) ([]Entry, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+wordFileExt))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, errors.New("no sensitive word files in " + dir)
+	}
+	var entries []Entry
+	for _, file := range files {
+		words, err := readWords(file)
+		if err != nil {
+			return nil, err
+		}
+		category := strings.TrimSuffix(filepath.Base(file), wordFileExt)
+		for _, w := range words {
+			entries = append(entries, Entry{Word: w, Category: category})
+		}
+	}
+	return entries, nil
+}
+
+// readWords 读取文件中的敏感词
+func readWords(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadWords(file)
+}
+
+// ReadWords 读取每行一个的敏感词,忽略空行、首尾的空白和文件开头的BOM
+func ReadWords(r io.Reader) ([]string, error) {
+	var words []string
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if w := strings.TrimSpace(text); w != "" {
+			words = append(words, w)
+		}
+	}
+	return words, scanner.Err()
+}
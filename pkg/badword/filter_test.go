@@ -0,0 +1,154 @@
+package badword
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func writeWords(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestModerationFilterLoadsAllFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeWords(t, dir, "ads.txt", "刷单\n 代购 \n\n")
+	writeWords(t, dir, "urls.txt", "example.com\n")
+	writeWords(t, dir, "readme.md", "不是词库\n")
+
+	f, err := NewModerationFilter(context.Background(), DirLoader(dir), Policies{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "兼职刷单", want: "刷单"},
+		{text: "海外代购", want: "代购"}, // 首尾的空白被去掉
+		{text: "访问example.com", want: "example.com"},
+		{text: "不是词库", want: ""},
+		{text: "正常内容", want: ""},
+	}
+	for _, tt := range tests {
+		if got := f.Check(tt.text); got != tt.want {
+			t.Errorf("Check(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestModerationFilterCategories(t *testing.T) {
+	dir := t.TempDir()
+	writeWords(t, dir, "广告.txt", "刷单\n")
+	writeWords(t, dir, "网址.txt", "example.com\n")
+	f, err := NewModerationFilter(context.Background(), DirLoader(dir), Policies{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := f.FindAll("刷单请访问example.com")
+	if len(got) != 2 || got[0].Category != "广告" || got[1].Category != "网址" {
+		t.Fatalf("FindAll() = %+v, want categories from the file names", got)
+	}
+}
+
+func TestModerationFilterEmptyDir(t *testing.T) {
+	if _, err := NewModerationFilter(context.Background(), DirLoader(t.TempDir()), Policies{}, nil); err == nil {
+		t.Fatal("want error for a directory without word files")
+	}
+}
+
+func TestModerationFilterReload(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		entries = words("ads", "刷单")
+		loadErr error
+		calls   int
+	)
+	load := func(context.Context) ([]Entry, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		return append([]Entry(nil), entries...), loadErr
+	}
+	loaded := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls
+	}
+	f, err := NewModerationFilter(context.Background(), load, Policies{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := make(chan string, 1)
+	f.Watch(changes, 0)
+	defer f.Close()
+
+	mu.Lock()
+	entries = words("ads", "代购")
+	mu.Unlock()
+	changes <- "ads"
+	waitFor(t, func() bool { return f.Check("海外代购") == "代购" })
+	if got := f.Check("兼职刷单"); got != "" {
+		t.Fatalf("Check after reload = %q, want the removed word to be gone", got)
+	}
+
+	// 加载失败时继续使用旧的词库
+	mu.Lock()
+	entries, loadErr = nil, errors.New("db down")
+	mu.Unlock()
+	before := loaded()
+	changes <- "ads"
+	waitFor(t, func() bool { return loaded() > before })
+	if got := f.Check("海外代购"); got != "代购" {
+		t.Fatalf("Check after a failed reload = %q, want the old list kept", got)
+	}
+}
+
+func TestModerationFilterPeriodicReload(t *testing.T) {
+	var calls atomic.Int32
+	load := func(context.Context) ([]Entry, error) {
+		if calls.Add(1) > 1 {
+			return words("ads", "代购"), nil
+		}
+		return words("ads", "刷单"), nil
+	}
+	f, err := NewModerationFilter(context.Background(), load, Policies{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// changes为nil时只定期加载,弥补丢失的通知
+	f.Watch(nil, 10*time.Millisecond)
+	defer f.Close()
+	waitFor(t, func() bool { return f.Check("海外代购") == "代购" })
+}
+
+func TestReadWords(t *testing.T) {
+	got, err := ReadWords(strings.NewReader("\ufeff刷单\r\n\n 代购 \n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"刷单", "代购"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("ReadWords() = %q, want %q", got, want)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before deadline")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
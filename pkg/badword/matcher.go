@@ -0,0 +1,176 @@
+package badword
+
+import "unicode/utf8"
+
+// Matcher 基于Aho-Corasick自动机的多模式匹配,扫描一遍文本就能找出所有敏感词,包括互相重叠的
+// 构建完成后只读,可以被多个goroutine同时使用,词库变化时构建一个新的Matcher替换旧的
+
+// Entry 一个敏感词及其分类
+type Entry struct {
+	Word     string
+	Category string // 分类,如 广告、政治类,一般是词库文件名
+}
+
+// Match 文本中命中的一个敏感词
+// Start 和 End 是在原文本中的字节偏移,text[Start:End] 就是命中的内容
+type Match struct {
+	Word     string
+	Category string
+	Start    int
+	End      int
+}
+
+type acNode struct {
+	children map[rune]int32
+	fail     int32 // 失配时跳转的节点: 当前路径的最长真后缀对应的节点
+	dict     int32 // 沿fail链能到达的最近的词尾节点,没有时为-1
+	depth    int   // 路径的字节长度,用于计算匹配的起始位置
+	entries  []int // 以这个节点结尾的词在 Matcher.entries 中的下标
+}
+
+// Matcher 敏感词自动机
+type Matcher struct {
+	nodes   []acNode
+	entries []Entry
+}
+
+// NewMatcher 用敏感词构建自动机,忽略空的词和不是有效UTF-8的词
+// 同一个词出现在多个分类中时,每个分类都会作为一个匹配返回
+func NewMatcher(entries []Entry) *Matcher {
+	m := &Matcher{nodes: []acNode{newACNode(0)}}
+	seen := make(map[Entry]bool, len(entries))
+	for _, e := range entries {
+		if e.Word == "" || !utf8.ValidString(e.Word) || seen[e] {
+			continue
+		}
+		seen[e] = true
+		m.insert(e)
+	}
+	m.build()
+	return m
+}
+
+func newACNode(depth int) acNode {
+	return acNode{dict: -1, depth: depth}
+}
+
+func (m *Matcher) insert(e Entry) {
+	cur := int32(0)
+	for _, r := range e.Word {
+		next, ok := m.nodes[cur].children[r]
+		if !ok {
+			next = int32(len(m.nodes))
+			m.nodes = append(m.nodes, newACNode(m.nodes[cur].depth+utf8.RuneLen(r)))
+			if m.nodes[cur].children == nil {
+				m.nodes[cur].children = make(map[rune]int32)
+			}
+			m.nodes[cur].children[r] = next
+		}
+		cur = next
+	}
+	m.nodes[cur].entries = append(m.nodes[cur].entries, len(m.entries))
+	m.entries = append(m.entries, e)
+}
+
+// build 按层次遍历计算fail和dict链接,父节点的链接总是先于子节点计算
+func (m *Matcher) build() {
+	queue := make([]int32, 0, len(m.nodes))
+	for _, child := range m.nodes[0].children {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for r, child := range m.nodes[cur].children {
+			fail := m.nodes[cur].fail
+			for {
+				if next, ok := m.nodes[fail].children[r]; ok {
+					fail = next
+					break
+				}
+				if fail == 0 {
+					break
+				}
+				fail = m.nodes[fail].fail
+			}
+			m.nodes[child].fail = fail
+			if len(m.nodes[fail].entries) > 0 {
+				m.nodes[child].dict = fail
+			} else {
+				m.nodes[child].dict = m.nodes[fail].dict
+			}
+			queue = append(queue, child)
+		}
+	}
+}
+
+// Len 敏感词的数量
+func (m *Matcher) Len() int {
+	return len(m.entries)
+}
+
+// scan 扫描文本,每命中一个词调用一次fn,fn返回false时停止
+// 匹配按结束位置排序,结束位置相同时长的词在前
+func (m *Matcher) scan(text string, fn func(Match) bool) {
+	if len(m.entries) == 0 {
+		return
+	}
+	cur := int32(0)
+	for i, r := range text {
+		end := i + utf8.RuneLen(r)
+		if r == utf8.RuneError {
+			// 无效的UTF-8字节不会出现在敏感词中
+			if _, size := utf8.DecodeRuneInString(text[i:]); size == 1 {
+				cur = 0
+				continue
+			}
+		}
+		for {
+			if next, ok := m.nodes[cur].children[r]; ok {
+				cur = next
+				break
+			}
+			if cur == 0 {
+				break
+			}
+			cur = m.nodes[cur].fail
+		}
+		for n := cur; n > 0; n = m.nodes[n].dict {
+			for _, idx := range m.nodes[n].entries {
+				e := m.entries[idx]
+				if !fn(Match{Word: e.Word, Category: e.Category, Start: end - m.nodes[n].depth, End: end}) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// FindAll 返回文本中所有的敏感词,包括重叠的和重复出现的
+func (m *Matcher) FindAll(text string) []Match {
+	var res []Match
+	m.scan(text, func(match Match) bool {
+		res = append(res, match)
+		return true
+	})
+	return res
+}
+
+// First 返回文本中结束位置最靠前的敏感词
+func (m *Matcher) First(text string) (Match, bool) {
+	var (
+		res   Match
+		found bool
+	)
+	m.scan(text, func(match Match) bool {
+		res, found = match, true
+		return false
+	})
+	return res, found
+}
+
+// Contains 检测文本中是否包含敏感词
+func (m *Matcher) Contains(text string) bool {
+	_, ok := m.First(text)
+	return ok
+}
@@ -0,0 +1,242 @@
+package badword
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// naiveFindAll 参考实现: 在每个字符的位置逐个比较所有的词,entries 中不能有重复
+func naiveFindAll(entries []Entry, text string) []Match {
+	var res []Match
+	for start := 0; start < len(text); {
+		for _, e := range entries {
+			if e.Word == "" {
+				continue
+			}
+			if strings.HasPrefix(text[start:], e.Word) {
+				res = append(res, Match{Word: e.Word, Category: e.Category, Start: start, End: start + len(e.Word)})
+			}
+		}
+		_, size := utf8.DecodeRuneInString(text[start:])
+		start += size
+	}
+	return res
+}
+
+// dedup 同一个词和分类只保留一个
+func dedup(entries []Entry) []Entry {
+	seen := make(map[Entry]bool)
+	var res []Entry
+	for _, e := range entries {
+		if !seen[e] {
+			seen[e] = true
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+func sortMatches(ms []Match) {
+	sort.Slice(ms, func(i, j int) bool {
+		a, b := ms[i], ms[j]
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		if a.End != b.End {
+			return a.End < b.End
+		}
+		return a.Category < b.Category
+	})
+}
+
+func words(category string, ws ...string) []Entry {
+	res := make([]Entry, 0, len(ws))
+	for _, w := range ws {
+		res = append(res, Entry{Word: w, Category: category})
+	}
+	return res
+}
+
+func TestMatcherFindAll(t *testing.T) {
+	m := NewMatcher(words("test", "he", "she", "his", "hers"))
+	got := m.FindAll("ushers")
+	want := []Match{
+		{Word: "she", Category: "test", Start: 1, End: 4},
+		{Word: "he", Category: "test", Start: 2, End: 4},
+		{Word: "hers", Category: "test", Start: 2, End: 6},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("FindAll() = %+v, want %+v", got, want)
+	}
+}
+
+func TestMatcherRestartsAfterPartialMatch(t *testing.T) {
+	// TrieV1 原来的实现在 "法轮" 匹配失败后不会回到根节点,找不到后面的 "轮功"
+	// 而且会跳过不匹配的字符,把 "法x轮x功" 当成 "法轮功"
+	m := NewMatcher(words("政治类", "法轮功", "轮功"))
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "法轮轮功", want: []string{"轮功"}},
+		{text: "法x轮x功", want: nil},
+		{text: "学法轮功", want: []string{"法轮功", "轮功"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, match := range m.FindAll(tt.text) {
+			if tt.text[match.Start:match.End] != match.Word {
+				t.Errorf("%q[%d:%d] = %q, want %q", tt.text, match.Start, match.End, tt.text[match.Start:match.End], match.Word)
+			}
+			got = append(got, match.Word)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FindAll(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMatcherCategories(t *testing.T) {
+	entries := append(words("广告", "代购", "刷单"), words("网址", "example.com")...)
+	entries = append(entries, Entry{Word: "刷单", Category: "诈骗"}, Entry{Word: "代购", Category: "广告"})
+	m := NewMatcher(entries)
+	if m.Len() != 4 {
+		t.Fatalf("Len() = %d, want 4 (duplicates removed)", m.Len())
+	}
+	got := m.FindAll("代购请访问example.com,刷单")
+	sortMatches(got)
+	want := []Match{
+		{Word: "代购", Category: "广告", Start: 0, End: 6},
+		{Word: "example.com", Category: "网址", Start: 15, End: 26},
+		{Word: "刷单", Category: "广告", Start: 27, End: 33},
+		{Word: "刷单", Category: "诈骗", Start: 27, End: 33},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("FindAll() = %+v, want %+v", got, want)
+	}
+
+	first, ok := m.First("访问example.com后代购")
+	if !ok || first.Word != "example.com" {
+		t.Fatalf("First() = %+v, %v, want example.com", first, ok)
+	}
+	if m.Contains("正常内容") {
+		t.Fatal("Contains() = true for a clean text")
+	}
+}
+
+func TestMatcherInvalidUTF8(t *testing.T) {
+	m := NewMatcher(words("test", "ab", "\xff"))
+	if m.Len() != 1 {
+		t.Fatalf("Len() = %d, want invalid words ignored", m.Len())
+	}
+	if got := m.FindAll("a\xffb"); got != nil {
+		t.Fatalf("FindAll() = %+v, want an invalid byte to break the match", got)
+	}
+	if got := m.FindAll("\xffab"); len(got) != 1 || got[0].Start != 1 {
+		t.Fatalf("FindAll() = %+v, want ab at 1", got)
+	}
+}
+
+func TestMatcherEmpty(t *testing.T) {
+	m := NewMatcher(nil)
+	if m.Contains("任何内容") {
+		t.Fatal("empty matcher should match nothing")
+	}
+}
+
+func TestTrieV1(t *testing.T) {
+	trie := NewTrieV1()
+	trie.Rebuild([]string{"法轮功", "轮功", "赌"})
+	if got := trie.Check("法轮轮功"); got != "轮功" {
+		t.Fatalf("Check() = %q, want 轮功", got)
+	}
+	if trie.Contains("法x轮x功") {
+		t.Fatal("Contains() matched a subsequence")
+	}
+
+	trie.Insert("赌博")
+	trie.Delete("赌博")
+	if got := trie.Check("赌博"); got != "赌" {
+		t.Fatalf("Check() after deleting a longer word = %q, want the prefix 赌 to stay", got)
+	}
+	trie.Delete("赌")
+	if trie.Contains("赌博") {
+		t.Fatal("Contains() after Delete = true")
+	}
+}
+
+func FuzzMatcher(f *testing.F) {
+	f.Add("he\nshe\nhis\nhers", "ushers")
+	f.Add("法轮功\n轮功", "学法轮轮功")
+	f.Add("aa\na\naaa", "aaaaa")
+	f.Add("ab\nbc\nabcd", "xabcdbc")
+	f.Fuzz(func(t *testing.T, list, text string) {
+		if !utf8.ValidString(list) || !utf8.ValidString(text) {
+			t.Skip()
+		}
+		var entries []Entry
+		for i, w := range strings.Split(list, "\n") {
+			entries = append(entries, Entry{Word: w, Category: string(rune('a' + i%3))})
+		}
+		got := NewMatcher(entries).FindAll(text)
+		want := naiveFindAll(dedup(entries), text)
+		sortMatches(got)
+		sortMatches(want)
+		if len(got) != len(want) || len(got) > 0 && !reflect.DeepEqual(got, want) {
+			t.Fatalf("FindAll(%q) with %q = %+v, want %+v", text, list, got, want)
+		}
+	})
+}
+
+// benchEntries 使用项目自带的词库
+func benchEntries(b *testing.B) []Entry {
+	entries, err := LoadDir("../sensitivewords")
+	if err != nil {
+		b.Fatal(err)
+	}
+	return entries
+}
+
+var benchText = strings.Repeat("这是一段正常的帖子内容,讨论一下Go语言的并发模型和channel的用法。", 20) + "兼职刷单"
+
+func BenchmarkMatcher(b *testing.B) {
+	m := NewMatcher(benchEntries(b))
+	b.SetBytes(int64(len(benchText)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.FindAll(benchText)
+	}
+}
+
+func BenchmarkTrieV1(b *testing.B) {
+	entries := benchEntries(b)
+	trie := NewTrieV1()
+	for _, e := range entries {
+		trie.Insert(e.Word)
+	}
+	b.SetBytes(int64(len(benchText)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		trie.Check(benchText)
+	}
+}
+
+func BenchmarkNaive(b *testing.B) {
+	entries := dedup(benchEntries(b))
+	b.SetBytes(int64(len(benchText)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		naiveFindAll(entries, benchText)
+	}
+}
+
+func BenchmarkNewMatcher(b *testing.B) {
+	entries := benchEntries(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewMatcher(entries)
+	}
+}
@@ -0,0 +1,188 @@
+package badword
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 匹配之前先把文本和敏感词转换成统一的形式,防止用户通过插入空格、标点,
+// 使用全角字符、大小写、繁体字或形近字绕过过滤
+// 每个字符最多转换成一个字符,所以转换后的位置可以映射回原文,屏蔽时替换的是原文中对应的部分
+
+//go:embed data/t2s.txt
+var t2sData string
+
+// NormalizeOptions 文本转换的选项
+type NormalizeOptions struct {
+	FoldWidth        bool          // 全角字母、数字、标点转换成半角
+	FoldCase         bool          // 转换成小写
+	RemoveSeparators bool          // 去掉空白、标点、符号和不可见的格式字符
+	Stopwords        string        // 额外忽略的字符,如 "丶_"
+	Simplified       bool          // 繁体字转换成简体字
+	Homoglyphs       map[rune]rune // 形近字到标准字符的映射,见 LoadHomoglyphs
+}
+
+// DefaultNormalizeOptions 除形近字表外都开启
+func DefaultNormalizeOptions() NormalizeOptions {
+	return NormalizeOptions{
+		FoldWidth:        true,
+		FoldCase:         true,
+		RemoveSeparators: true,
+		Simplified:       true,
+	}
+}
+
+// Normalizer 按选项转换文本,nil表示不转换
+type Normalizer struct {
+	opts    NormalizeOptions
+	mapping map[rune]rune // 繁体字和形近字的映射
+	stop    map[rune]bool
+}
+
+// NewNormalizer 创建 Normalizer
+func NewNormalizer(opts NormalizeOptions) *Normalizer {
+	n := &Normalizer{opts: opts, mapping: make(map[rune]rune), stop: make(map[rune]bool)}
+	if opts.Simplified {
+		// 内置的映射表在编译时嵌入,格式错误是程序的bug
+		m, err := parseMapping(strings.NewReader(t2sData))
+		if err != nil {
+			panic("badword: invalid t2s table: " + err.Error())
+		}
+		for from, to := range m {
+			n.mapping[from] = to
+		}
+	}
+	for from, to := range opts.Homoglyphs {
+		n.mapping[from] = to
+	}
+	for _, r := range opts.Stopwords {
+		n.stop[r] = true
+	}
+	return n
+}
+
+// fold 转换一个字符,返回false表示忽略这个字符
+func (n *Normalizer) fold(r rune) (rune, bool) {
+	if n.opts.FoldWidth {
+		r = foldWidth(r)
+	}
+	if n.opts.FoldCase {
+		r = unicode.ToLower(r)
+	}
+	if to, ok := n.mapping[r]; ok {
+		r = to
+	}
+	if n.stop[r] || n.opts.RemoveSeparators && isSeparator(r) {
+		return 0, false
+	}
+	return r, true
+}
+
+// foldWidth 全角的ASCII字符和全角空格转换成半角
+func foldWidth(r rune) rune {
+	switch {
+	case r >= 0xFF01 && r <= 0xFF5E:
+		return r - 0xFEE0
+	case r == 0x3000:
+		return ' '
+	}
+	return r
+}
+
+// isSeparator 插在敏感词中间不影响阅读的字符
+func isSeparator(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) ||
+		unicode.In(r, unicode.Cc, unicode.Cf, unicode.Mn)
+}
+
+// Normalized 转换后的文本,以及每个字节在原文中对应的字符的位置
+type Normalized struct {
+	Text   string
+	starts []int // starts[i] 转换后第i个字节所在的字符在原文中的起始位置
+	ends   []int // ends[i] 对应的原文字符的结束位置
+}
+
+// Normalize 转换文本,n为nil时原样返回
+func (n *Normalizer) Normalize(text string) *Normalized {
+	res := &Normalized{
+		starts: make([]int, 0, len(text)),
+		ends:   make([]int, 0, len(text)),
+	}
+	var b strings.Builder
+	b.Grow(len(text))
+	for i, r := range text {
+		if r == utf8.RuneError {
+			if _, size := utf8.DecodeRuneInString(text[i:]); size == 1 {
+				// 保留无效的字节,这样它会打断匹配,和原文中一样
+				b.WriteByte(text[i])
+				res.starts = append(res.starts, i)
+				res.ends = append(res.ends, i+1)
+				continue
+			}
+		}
+		size := utf8.RuneLen(r)
+		if n != nil {
+			var ok bool
+			if r, ok = n.fold(r); !ok {
+				continue
+			}
+		}
+		b.WriteRune(r)
+		for j := utf8.RuneLen(r); j > 0; j-- {
+			res.starts = append(res.starts, i)
+			res.ends = append(res.ends, i+size)
+		}
+	}
+	res.Text = b.String()
+	return res
+}
+
+// Original 把在转换后的文本中找到的匹配映射回原文,范围包括敏感词中间被忽略的字符
+func (n *Normalized) Original(matches []Match) []Match {
+	res := make([]Match, len(matches))
+	for i, m := range matches {
+		m.Start, m.End = n.starts[m.Start], n.ends[m.End-1]
+		res[i] = m
+	}
+	return res
+}
+
+// LoadHomoglyphs 从文件加载形近字表,每行是一个形近字和对应的标准字符,用空白分隔,#开头的行是注释
+func LoadHomoglyphs(filename string) (map[rune]rune, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	m, err := parseMapping(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return m, nil
+}
+
+// parseMapping 解析字符映射表,格式见 LoadHomoglyphs
+func parseMapping(r io.Reader) (map[rune]rune, error) {
+	m := make(map[rune]rune)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		s := strings.TrimSpace(scanner.Text())
+		if s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+		fields := strings.Fields(s)
+		if len(fields) != 2 || utf8.RuneCountInString(fields[0]) != 1 || utf8.RuneCountInString(fields[1]) != 1 {
+			return nil, fmt.Errorf("line %d: want two single characters, got %q", line, s)
+		}
+		from, _ := utf8.DecodeRuneInString(fields[0])
+		to, _ := utf8.DecodeRuneInString(fields[1])
+		m[from] = to
+	}
+	return m, scanner.Err()
+}
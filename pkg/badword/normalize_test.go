@@ -0,0 +1,134 @@
+package badword
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func testNormalizer() *Normalizer {
+	opts := DefaultNormalizeOptions()
+	opts.Stopwords = "丶"
+	opts.Homoglyphs = map[rune]rune{'а': 'a', 'о': 'o'} // 西里尔字母
+	return NewNormalizer(opts)
+}
+
+func TestNormalize(t *testing.T) {
+	n := testNormalizer()
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "法 轮 功", want: "法轮功"},
+		{text: "法.轮-功!", want: "法轮功"},
+		{text: "法​轮‍功", want: "法轮功"}, // 零宽字符
+		{text: "法丶轮丶功", want: "法轮功"},
+		{text: "法輪功", want: "法轮功"},
+		{text: "ＦａＬｕＮ　ＧＯＮＧ", want: "falungong"},
+		{text: "fаlun gоng", want: "falungong"},
+		{text: "代开发票", want: "代开发票"},
+	}
+	for _, tt := range tests {
+		if got := n.Normalize(tt.text).Text; got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+
+	var nilNormalizer *Normalizer
+	if got := nilNormalizer.Normalize("Ａ B").Text; got != "Ａ B" {
+		t.Errorf("nil Normalizer changed the text to %q", got)
+	}
+}
+
+func TestNormalizedOriginal(t *testing.T) {
+	n := testNormalizer()
+	m := NewMatcher(words("test", n.Normalize("法轮功").Text, n.Normalize("FALUN").Text))
+	text := "学习 法-輪 功,ｆａｌｕｎ!"
+	norm := n.Normalize(text)
+	matches := norm.Original(m.FindAll(norm.Text))
+	var got []string
+	for _, match := range matches {
+		got = append(got, text[match.Start:match.End])
+	}
+	if want := []string{"法-輪 功", "ｆａｌｕｎ"}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("original spans = %q, want %q", got, want)
+	}
+	if masked := Mask(text, matches); masked != "学习 *****,*****!" {
+		t.Fatalf("Mask() = %q", masked)
+	}
+}
+
+func TestModerationFilterNormalize(t *testing.T) {
+	dir := t.TempDir()
+	writeWords(t, dir, "广告.txt", "代 开 发 票\nQQ群\nSM\n")
+	f, err := NewModerationFilter(context.Background(), DirLoader(dir), Policies{}, testNormalizer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "专业代-开-發-票", want: "专业*******"},
+		{text: "加ｑｑ群", want: "加***"},
+		{text: "正常内容", want: "正常内容"},
+		{text: "S.M 调教", want: "*** 调教"},
+		{text: "this is more", want: "this is more"}, // 字母开头的词只匹配完整的单词
+		{text: "SMS", want: "SMS"},
+	}
+	for _, tt := range tests {
+		if got, _ := f.Replace(tt.text); got != tt.want {
+			t.Errorf("Replace(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParseMapping(t *testing.T) {
+	m, err := parseMapping(strings.NewReader("# 注释\n\nа a\n"))
+	if err != nil || m['а'] != 'a' {
+		t.Fatalf("parseMapping() = %v, %v", m, err)
+	}
+	if _, err := parseMapping(strings.NewReader("ab c\n")); err == nil {
+		t.Fatal("want error for a line with more than one character")
+	}
+}
+
+// FuzzNormalizedOriginal 映射回原文的位置必须落在字符边界上,且原文中的这一段转换后就是匹配的词
+func FuzzNormalizedOriginal(f *testing.F) {
+	f.Add("法轮功", "学习 法-輪 功")
+	f.Add("ab", "Ａ​ｂ\xffab")
+	f.Fuzz(func(t *testing.T, word, text string) {
+		n := testNormalizer()
+		w := n.Normalize(word).Text
+		if w == "" || !utf8.ValidString(w) {
+			t.Skip()
+		}
+		// range 给出每个字符(包括无效的字节)的起始位置
+		boundary := map[int]bool{len(text): true}
+		for i := range text {
+			boundary[i] = true
+		}
+		norm := n.Normalize(text)
+		for _, m := range norm.Original(NewMatcher(words("test", w)).FindAll(norm.Text)) {
+			if m.Start < 0 || m.End > len(text) || m.Start >= m.End {
+				t.Fatalf("span [%d:%d] out of range for %q", m.Start, m.End, text)
+			}
+			span := text[m.Start:m.End]
+			if !boundary[m.Start] || !boundary[m.End] {
+				t.Fatalf("span [%d:%d] of %q is not on rune boundaries", m.Start, m.End, text)
+			}
+			if got := n.Normalize(span).Text; got != w {
+				t.Fatalf("Normalize(%q) = %q, want %q", span, got, w)
+			}
+		}
+	})
+}
+
+func BenchmarkNormalize(b *testing.B) {
+	n := NewNormalizer(DefaultNormalizeOptions())
+	b.SetBytes(int64(len(benchText)))
+	for i := 0; i < b.N; i++ {
+		n.Normalize(benchText)
+	}
+}
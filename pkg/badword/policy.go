@@ -0,0 +1,105 @@
+package badword
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// Policy 命中某个分类的敏感词后的处理方式
+type Policy string
+
+const (
+	PolicyMask   Policy = "mask"   // 敏感词替换成*后正常发布
+	PolicyReview Policy = "review" // 正常发布,同时交给版主审核
+	PolicyReject Policy = "reject" // 拒绝提交
+)
+
+// severity 同时命中多种处理方式时按最严格的处理
+var severity = map[Policy]int{
+	PolicyMask:   1,
+	PolicyReview: 2,
+	PolicyReject: 3,
+}
+
+// ParsePolicy 解析配置中的处理方式
+func ParsePolicy(s string) (Policy, error) {
+	p := Policy(s)
+	if _, ok := severity[p]; !ok {
+		return "", fmt.Errorf("unknown sensitive word policy %q", s)
+	}
+	return p, nil
+}
+
+// Policies 每个分类的处理方式,没有配置的分类使用Default
+type Policies struct {
+	Default    Policy
+	Categories map[string]Policy
+}
+
+// Of 返回分类的处理方式
+func (p Policies) Of(category string) Policy {
+	if policy, ok := p.Categories[category]; ok {
+		return policy
+	}
+	if p.Default == "" {
+		return PolicyReject
+	}
+	return p.Default
+}
+
+// Verdict 对一段文本的处理结果
+type Verdict struct {
+	Policy  Policy  // 最严格的处理方式,没有命中敏感词时为空
+	Match   Match   // 决定处理方式的第一个敏感词
+	Text    string  // 按 PolicyMask 屏蔽了敏感词后的文本,其他分类的敏感词保持原样
+	Matches []Match // 命中的所有敏感词
+}
+
+// Apply 按各个分类的处理方式处理文本中命中的敏感词
+func (p Policies) Apply(text string, matches []Match) Verdict {
+	v := Verdict{Text: text, Matches: matches}
+	var masked []Match
+	for _, m := range matches {
+		policy := p.Of(m.Category)
+		if policy == PolicyMask {
+			masked = append(masked, m)
+		}
+		if severity[policy] > severity[v.Policy] {
+			v.Policy, v.Match = policy, m
+		}
+	}
+	if len(masked) > 0 {
+		v.Text = Mask(text, masked)
+	}
+	return v
+}
+
+// Mask 把文本中命中的敏感词的每个字符替换成一个*,重叠的敏感词也能正确处理
+func Mask(text string, matches []Match) string {
+	if len(matches) == 0 {
+		return text
+	}
+	covered := make([]bool, len(text))
+	for _, m := range matches {
+		for i := m.Start; i < m.End; i++ {
+			covered[i] = true
+		}
+	}
+	buf := make([]byte, 0, len(text))
+	for i := 0; i < len(text); {
+		_, size := utf8.DecodeRuneInString(text[i:])
+		if covered[i] {
+			buf = append(buf, '*')
+		} else {
+			buf = append(buf, text[i:i+size]...)
+		}
+		i += size
+	}
+	return string(buf)
+}
+
+// Replace 把文本中所有的敏感词替换成*,返回替换后的文本和命中的敏感词
+func (m *Matcher) Replace(text string) (string, []Match) {
+	matches := m.FindAll(text)
+	return Mask(text, matches), matches
+}
@@ -0,0 +1,61 @@
+package badword
+
+import "testing"
+
+func TestReplace(t *testing.T) {
+	m := NewMatcher(append(words("test", "法轮功", "轮功", "ab"), words("网址", "example.com")...))
+	tests := []struct {
+		text string
+		want string
+		n    int
+	}{
+		{text: "学法轮功", want: "学***", n: 2}, // 重叠的敏感词
+		{text: "法轮轮功", want: "法轮**", n: 1},
+		{text: "访问example.com, abab", want: "访问***********, ****", n: 3},
+		{text: "正常内容", want: "正常内容", n: 0},
+	}
+	for _, tt := range tests {
+		got, matches := m.Replace(tt.text)
+		if got != tt.want || len(matches) != tt.n {
+			t.Errorf("Replace(%q) = %q, %d matches, want %q, %d", tt.text, got, len(matches), tt.want, tt.n)
+		}
+	}
+}
+
+func TestPoliciesApply(t *testing.T) {
+	m := NewMatcher([]Entry{
+		{Word: "刷单", Category: "广告"},
+		{Word: "example.com", Category: "网址"},
+		{Word: "枪支", Category: "涉枪涉爆"},
+	})
+	p := Policies{
+		Default:    PolicyReject,
+		Categories: map[string]Policy{"广告": PolicyReview, "网址": PolicyMask},
+	}
+	tests := []struct {
+		text       string
+		wantPolicy Policy
+		wantWord   string
+		wantText   string
+	}{
+		{text: "正常内容", wantPolicy: "", wantText: "正常内容"},
+		{text: "访问example.com", wantPolicy: PolicyMask, wantWord: "example.com", wantText: "访问***********"},
+		{text: "刷单example.com", wantPolicy: PolicyReview, wantWord: "刷单", wantText: "刷单***********"},
+		{text: "刷单买枪支", wantPolicy: PolicyReject, wantWord: "枪支", wantText: "刷单买枪支"}, // 没有配置的分类使用Default
+	}
+	for _, tt := range tests {
+		v := p.Apply(tt.text, m.FindAll(tt.text))
+		if v.Policy != tt.wantPolicy || v.Match.Word != tt.wantWord || v.Text != tt.wantText {
+			t.Errorf("Apply(%q) = %s/%q/%q, want %s/%q/%q", tt.text, v.Policy, v.Match.Word, v.Text, tt.wantPolicy, tt.wantWord, tt.wantText)
+		}
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	if p, err := ParsePolicy("mask"); err != nil || p != PolicyMask {
+		t.Fatalf("ParsePolicy(mask) = %q, %v", p, err)
+	}
+	if _, err := ParsePolicy("block"); err == nil {
+		t.Fatal("ParsePolicy(block) should fail")
+	}
+}
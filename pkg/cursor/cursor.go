@@ -0,0 +1,61 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// 游标分页
+// 客户端拿到的是编码后的不透明字符串,下一页请求时原样带回
+
+const (
+	DefaultSize = 10
+	MaxSize     = 50 // 服务端限制的每页最大数据量
+)
+
+var ErrInvalidCursor = errors.New("无效的游标")
+
+// Cursor 上一页最后一条数据的位置
+type Cursor struct {
+	Score  float64 `json:"s,omitempty"` // 排序字段的值,例如发帖时间或帖子分数
+	ID     int64   `json:"i,omitempty"` // 排序字段的值相同时用id区分先后
+	Member string  `json:"m,omitempty"` // 成员本身没有可比较的id时(例如保存成json的评论),用完整的zset成员区分先后
+	Offset int64   `json:"o,omitempty"` // 无法按键值分页时(例如按相关度排序的搜索)使用的偏移量
+}
+
+// Encode 把游标编码成不透明的字符串
+func (c *Cursor) Encode() string {
+	if c == nil {
+		return ""
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode 解析客户端带回的游标,空字符串表示从第一页开始,返回nil
+func Decode(s string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	c := new(Cursor)
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// ClampSize 把每页数据量限制在(0, MaxSize]之间
+func ClampSize(size int64) int64 {
+	if size <= 0 {
+		return DefaultSize
+	}
+	if size > MaxSize {
+		return MaxSize
+	}
+	return size
+}
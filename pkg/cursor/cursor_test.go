@@ -0,0 +1,29 @@
+package cursor
+
+import "testing"
+
+func TestEncodeDecode(t *testing.T) {
+	c := &Cursor{Score: 1597026583, ID: 14283784123846656}
+	got, err := Decode(c.Encode())
+	if err != nil {
+		t.Fatalf("Decode failed, err:%v", err)
+	}
+	if *got != *c {
+		t.Fatalf("Decode(Encode(c)) = %+v, want %+v", got, c)
+	}
+
+	if got, err := Decode(""); got != nil || err != nil {
+		t.Fatalf("Decode(\"\") = %v, %v, want nil, nil", got, err)
+	}
+	if _, err := Decode("not a cursor"); err != ErrInvalidCursor {
+		t.Fatalf("Decode(invalid) err = %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestClampSize(t *testing.T) {
+	for size, want := range map[int64]int64{-1: DefaultSize, 0: DefaultSize, 20: 20, 1000: MaxSize} {
+		if got := ClampSize(size); got != want {
+			t.Errorf("ClampSize(%d) = %d, want %d", size, got, want)
+		}
+	}
+}
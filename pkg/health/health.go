@@ -0,0 +1,120 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 就绪检查: 并发检查所有依赖,任意一个失败或超时都算未就绪
+// 服务开始退出后直接返回未就绪,让负载均衡不再转发新的请求
+
+const (
+	StatusUp           = "up"
+	StatusDown         = "down"
+	StatusShuttingDown = "shutting_down"
+)
+
+// CheckFunc 检查一个依赖是否可用,要在ctx结束前返回
+type CheckFunc func(ctx context.Context) error
+
+// Result 一个依赖的检查结果
+type Result struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report 就绪检查的结果
+type Report struct {
+	Status string    `json:"status"`
+	Checks []*Result `json:"checks"`
+}
+
+// Ready 是否可以接收请求
+func (r *Report) Ready() bool {
+	return r.Status == StatusUp
+}
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Checker 保存需要检查的依赖
+type Checker struct {
+	mu           sync.RWMutex
+	checks       []check
+	shuttingDown atomic.Bool
+}
+
+// NewChecker 创建一个Checker
+func NewChecker() *Checker {
+	return &Checker{}
+}
+
+// Add 添加一个依赖的检查
+func (c *Checker) Add(name string, fn CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, check{name: name, fn: fn})
+}
+
+// SetShuttingDown 标记服务正在退出,之后的检查都返回未就绪
+func (c *Checker) SetShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+// Check 并发执行所有检查,超时时间由ctx控制
+// 检查函数没有在ctx结束前返回时记为失败,不再等待它
+func (c *Checker) Check(ctx context.Context) *Report {
+	c.mu.RLock()
+	checks := c.checks
+	c.mu.RUnlock()
+
+	report := &Report{Status: StatusUp, Checks: make([]*Result, len(checks))}
+	var wg sync.WaitGroup
+	for i, ch := range checks {
+		wg.Add(1)
+		go func(i int, ch check) {
+			defer wg.Done()
+			report.Checks[i] = run(ctx, ch)
+		}(i, ch)
+	}
+	wg.Wait()
+
+	for _, r := range report.Checks {
+		if r.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	if c.shuttingDown.Load() {
+		report.Status = StatusShuttingDown
+	}
+	return report
+}
+
+func run(ctx context.Context, ch check) *Result {
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- ch.fn(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	res := &Result{
+		Name:      ch.name,
+		Status:    StatusUp,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		res.Status = StatusDown
+		res.Error = err.Error()
+	}
+	return res
+}
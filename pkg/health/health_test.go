@@ -0,0 +1,82 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChecker(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	fail := func(context.Context) error { return errors.New("connection refused") }
+	// 不理会ctx的检查函数,超时后不再等待
+	hang := func(context.Context) error { time.Sleep(time.Second); return nil }
+
+	tests := []struct {
+		name         string
+		checks       map[string]CheckFunc
+		shuttingDown bool
+		wantStatus   string
+		wantChecks   map[string]string
+	}{
+		{
+			name:       "all up",
+			checks:     map[string]CheckFunc{"mysql": ok, "redis": ok},
+			wantStatus: StatusUp,
+			wantChecks: map[string]string{"mysql": StatusUp, "redis": StatusUp},
+		},
+		{
+			name:       "one down",
+			checks:     map[string]CheckFunc{"mysql": ok, "redis": fail},
+			wantStatus: StatusDown,
+			wantChecks: map[string]string{"mysql": StatusUp, "redis": StatusDown},
+		},
+		{
+			name:       "timeout",
+			checks:     map[string]CheckFunc{"mysql": hang, "redis": ok},
+			wantStatus: StatusDown,
+			wantChecks: map[string]string{"mysql": StatusDown, "redis": StatusUp},
+		},
+		{
+			name:         "shutting down",
+			checks:       map[string]CheckFunc{"mysql": ok},
+			shuttingDown: true,
+			wantStatus:   StatusShuttingDown,
+			wantChecks:   map[string]string{"mysql": StatusUp},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker()
+			for name, fn := range tt.checks {
+				c.Add(name, fn)
+			}
+			if tt.shuttingDown {
+				c.SetShuttingDown()
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			report := c.Check(ctx)
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("Check took %v, want it to stop at the timeout", elapsed)
+			}
+			if report.Status != tt.wantStatus || report.Ready() != (tt.wantStatus == StatusUp) {
+				t.Errorf("status = %q, ready = %v, want %q", report.Status, report.Ready(), tt.wantStatus)
+			}
+			if len(report.Checks) != len(tt.wantChecks) {
+				t.Fatalf("got %d checks, want %d", len(report.Checks), len(tt.wantChecks))
+			}
+			for _, r := range report.Checks {
+				if r.Status != tt.wantChecks[r.Name] {
+					t.Errorf("%s: status = %q, want %q", r.Name, r.Status, tt.wantChecks[r.Name])
+				}
+				if (r.Status == StatusDown) != (r.Error != "") {
+					t.Errorf("%s: status = %q, error = %q", r.Name, r.Status, r.Error)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 响应中提示信息的多语言支持
+// 每种语言一个json文件(locales/<locale>.json),内容是提示信息的key到文本的映射
+// 请求的语言按以下顺序确定: 参数lang > cookie lang(用户在页面上选择的语言) > Accept-Language > DefaultLocale
+
+// DefaultLocale 找不到请求的语言或翻译时使用的语言
+const DefaultLocale = "zh"
+
+// LangParam 指定语言的请求参数和cookie的名字
+const LangParam = "lang"
+
+//go:embed locales/*.json
+var localeFS embed.FS
+
+// catalogs locale -> key -> 文本
+var catalogs = mustLoadCatalogs()
+
+func mustLoadCatalogs() map[string]map[string]string {
+	files, err := localeFS.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	res := make(map[string]map[string]string, len(files))
+	for _, f := range files {
+		data, err := localeFS.ReadFile(path.Join("locales", f.Name()))
+		if err != nil {
+			panic(err)
+		}
+		catalog := make(map[string]string)
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			panic("i18n: invalid catalog " + f.Name() + ": " + err.Error())
+		}
+		res[strings.TrimSuffix(f.Name(), ".json")] = catalog
+	}
+	if _, ok := res[DefaultLocale]; !ok {
+		panic("i18n: missing catalog for default locale " + DefaultLocale)
+	}
+	return res
+}
+
+// Locales 所有支持的语言
+func Locales() []string {
+	locales := make([]string, 0, len(catalogs))
+	for l := range catalogs {
+		locales = append(locales, l)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Lookup 查找某种语言的翻译,不回退到默认语言
+func Lookup(locale, key string) (string, bool) {
+	msg, ok := catalogs[locale][key]
+	return msg, ok
+}
+
+// Message 返回key在locale中的文本,没有翻译时使用默认语言,都没有时返回key
+func Message(locale, key string) string {
+	if msg, ok := Lookup(locale, key); ok {
+		return msg
+	}
+	if msg, ok := Lookup(DefaultLocale, key); ok {
+		return msg
+	}
+	return key
+}
+
+// Match 从Accept-Language中选出支持的语言,按q值从高到低匹配,如 "en-US,en;q=0.9,zh;q=0.8"
+// 只比较主语言(en-US按en处理),都不支持时返回空字符串
+func Match(acceptLanguage string) string {
+	type candidate struct {
+		locale string
+		q      float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = f
+		}
+		if tag == "" || q <= 0 {
+			continue
+		}
+		candidates = append(candidates, candidate{locale: normalize(tag), q: q})
+	}
+	// 稳定排序,q值相同时保持请求头中的顺序
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	for _, c := range candidates {
+		if _, ok := catalogs[c.locale]; ok {
+			return c.locale
+		}
+	}
+	return ""
+}
+
+// normalize 取主语言并转成小写,如 zh-CN -> zh
+func normalize(tag string) string {
+	base, _, _ := strings.Cut(tag, "-")
+	base, _, _ = strings.Cut(base, "_")
+	return strings.ToLower(strings.TrimSpace(base))
+}
+
+type localeKey struct{}
+
+// NewContext 把请求的语言保存到ctx中
+func NewContext(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// FromContext 返回ctx中保存的语言,没有时返回 DefaultLocale
+func FromContext(ctx context.Context) string {
+	if locale, ok := ctx.Value(localeKey{}).(string); ok {
+		return locale
+	}
+	return DefaultLocale
+}
+
+// resolve 按参数、cookie、Accept-Language的顺序确定请求的语言
+func resolve(c *gin.Context) string {
+	candidates := []string{c.Query(LangParam)}
+	if cookie, err := c.Cookie(LangParam); err == nil {
+		candidates = append(candidates, cookie)
+	}
+	for _, l := range candidates {
+		if l = normalize(l); l != "" {
+			if _, ok := catalogs[l]; ok {
+				return l
+			}
+		}
+	}
+	if l := Match(c.GetHeader("Accept-Language")); l != "" {
+		return l
+	}
+	return DefaultLocale
+}
+
+// GinLocale 确定请求的语言并保存在 c.Request 的context中,响应头Content-Language中返回使用的语言
+func GinLocale() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		locale := resolve(c)
+		c.Header("Content-Language", locale)
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), locale))
+		c.Next()
+	}
+}
@@ -0,0 +1,81 @@
+package i18n
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "", want: ""},
+		{header: "en", want: "en"},
+		{header: "en-US,en;q=0.9", want: "en"},
+		{header: "fr-FR,zh-CN;q=0.8,en;q=0.5", want: "zh"},
+		{header: "zh;q=0.5,en;q=0.9", want: "en"},
+		{header: "en;q=0,zh", want: "zh"},
+		{header: "fr,de;q=0.9", want: ""},
+		{header: "en;q=abc,zh;q=0.1", want: "zh"},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.header); got != tt.want {
+			t.Errorf("Match(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestMessageFallback(t *testing.T) {
+	if got := Message("en", "server_busy"); got != "Server is busy" {
+		t.Fatalf("Message(en) = %q", got)
+	}
+	// 不支持的语言使用默认语言
+	if got, want := Message("fr", "server_busy"), catalogs[DefaultLocale]["server_busy"]; got != want {
+		t.Fatalf("Message(fr) = %q, want %q", got, want)
+	}
+	if got := Message("en", "no_such_key"); got != "no_such_key" {
+		t.Fatalf("Message(missing key) = %q, want the key", got)
+	}
+}
+
+func TestGinLocale(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinLocale())
+	r.GET("/", func(c *gin.Context) { c.String(http.StatusOK, FromContext(c.Request.Context())) })
+
+	tests := []struct {
+		name   string
+		query  string
+		cookie string
+		accept string
+		want   string
+	}{
+		{name: "default", want: DefaultLocale},
+		{name: "accept language", accept: "en-GB,en;q=0.9", want: "en"},
+		{name: "cookie over header", cookie: "zh", accept: "en", want: "zh"},
+		{name: "query over cookie", query: "en", cookie: "zh", want: "en"},
+		{name: "unsupported query", query: "fr", accept: "en", want: "en"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/?lang="+tt.query, nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: LangParam, Value: tt.cookie})
+			}
+			if tt.accept != "" {
+				req.Header.Set("Accept-Language", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Body.String() != tt.want || w.Header().Get("Content-Language") != tt.want {
+				t.Fatalf("locale = %q (Content-Language %q), want %q",
+					w.Body.String(), w.Header().Get("Content-Language"), tt.want)
+			}
+		})
+	}
+}
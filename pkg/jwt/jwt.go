@@ -23,12 +23,12 @@ type MyClaims struct {
 }
 
 // GenToken 生成JWT
-func GenToken(userID int64, username string) (string, error) {
+func GenToken(userID int64, username, role string) (string, error) {
 	// 创建一个我们自己的声明的数据
 	c := MyClaims{
 		userID,
-		"username", // 自定义字段
-		"user",
+		username, // 自定义字段
+		role,
 		jwt.StandardClaims{
 			ExpiresAt: time.Now().Add(
 				time.Duration(viper.GetInt("auth.jwt_expire")) * time.Hour).Unix(), // 过期时间
@@ -0,0 +1,96 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Hook 一个需要启动和停止的组件,如数据库连接、后台任务、HTTP服务
+// OnStart 不能阻塞,长时间运行的任务要自己启动goroutine
+// OnStop 要在ctx结束前返回,超时后剩下的清理工作会被放弃
+type Hook struct {
+	Name    string
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+}
+
+// Manager 按添加的顺序启动组件,按相反的顺序停止
+// 后添加的组件可以依赖先添加的组件,停止时先停掉依赖别人的组件
+type Manager struct {
+	mu      sync.Mutex
+	hooks   []Hook
+	started int // 已经启动成功的hook个数
+}
+
+// New 创建一个Manager
+func New() *Manager {
+	return &Manager{}
+}
+
+// Append 添加一个组件,必须在Start之前调用
+func (m *Manager) Append(h Hook) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, h)
+}
+
+// Start 依次启动所有组件,某个组件启动失败时停止已经启动的组件并返回错误
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for m.started < len(m.hooks) {
+		h := m.hooks[m.started]
+		if h.OnStart != nil {
+			if err := h.OnStart(ctx); err != nil {
+				err = fmt.Errorf("start %s: %w", h.Name, err)
+				if stopErr := m.stop(ctx); stopErr != nil {
+					err = errors.Join(err, stopErr)
+				}
+				return err
+			}
+		}
+		m.started++
+	}
+	return nil
+}
+
+// Stop 按启动的相反顺序停止已经启动的组件,返回所有停止失败的错误
+// 某个组件停止失败或超时不影响停止其他组件
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stop(ctx)
+}
+
+func (m *Manager) stop(ctx context.Context) error {
+	var errs []error
+	for ; m.started > 0; m.started-- {
+		h := m.hooks[m.started-1]
+		if h.OnStop == nil {
+			continue
+		}
+		if err := h.OnStop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stop %s: %w", h.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// StopFunc 把不支持ctx的停止函数包装成 OnStop,ctx结束时不再等待它返回
+func StopFunc(fn func()) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		done := make(chan struct{})
+		go func() {
+			fn()
+			close(done)
+		}()
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func recordHook(name string, events *[]string, startErr error) Hook {
+	return Hook{
+		Name: name,
+		OnStart: func(context.Context) error {
+			*events = append(*events, "start "+name)
+			return startErr
+		},
+		OnStop: func(context.Context) error {
+			*events = append(*events, "stop "+name)
+			return nil
+		},
+	}
+}
+
+func TestManagerOrder(t *testing.T) {
+	var events []string
+	m := New()
+	m.Append(recordHook("mysql", &events, nil))
+	m.Append(recordHook("redis", &events, nil))
+	m.Append(recordHook("http", &events, nil))
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// 再次停止不会重复调用 OnStop
+	if err := m.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"start mysql", "start redis", "start http", "stop http", "stop redis", "stop mysql"}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("events = %v, want %v", events, want)
+	}
+}
+
+func TestManagerStartFailure(t *testing.T) {
+	var events []string
+	boom := errors.New("boom")
+	m := New()
+	m.Append(recordHook("mysql", &events, nil))
+	m.Append(recordHook("redis", &events, boom))
+	m.Append(recordHook("http", &events, nil))
+	err := m.Start(context.Background())
+	if !errors.Is(err, boom) {
+		t.Fatalf("Start() error = %v, want %v", err, boom)
+	}
+	// 启动失败的组件和之后的组件都不会被停止
+	want := []string{"start mysql", "start redis", "stop mysql"}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("events = %v, want %v", events, want)
+	}
+}
+
+func TestStopFuncTimeout(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	stop := StopFunc(func() { <-block })
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("stop() error = %v, want deadline exceeded", err)
+	}
+}
@@ -0,0 +1,36 @@
+package lru
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCacheEvict(t *testing.T) {
+	c := New[int64, string](2, 0)
+	c.Add(1, "a")
+	c.Add(2, "b")
+	c.Get(1) // 1 最近被使用过, 淘汰 2
+	c.Add(3, "c")
+	if _, ok := c.Get(2); ok {
+		t.Fatal("key 2 should be evicted")
+	}
+	if v, ok := c.Get(1); !ok || v != "a" {
+		t.Fatalf("Get(1) = %q, %v, want \"a\", true", v, ok)
+	}
+	c.Remove(1)
+	if _, ok := c.Get(1); ok {
+		t.Fatal("key 1 should be removed")
+	}
+}
+
+func TestCacheExpire(t *testing.T) {
+	c := New[int64, string](10, time.Millisecond)
+	c.Add(1, "a")
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := c.Get(1); ok {
+		t.Fatal("key 1 should be expired")
+	}
+	if c.Len() != 0 {
+		t.Fatalf("Len() = %d, want 0", c.Len())
+	}
+}
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Prometheus指标,注册在默认的registry中,和Go运行时、进程的指标一起通过 /metrics 暴露
+// 指标名统一使用 bluebell_ 前缀
+
+const namespace = "bluebell"
+
+// 业务事件
+var (
+	PostsCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "posts_created_total",
+		Help:      "发布成功的帖子数",
+	})
+	Votes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "votes_total",
+		Help:      "投票成功的次数,direction为up、down或cancel",
+	}, []string{"direction"})
+	CommentsCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "comments_created_total",
+		Help:      "发布成功的评论数",
+	})
+	SensitiveWordRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sensitive_word_rejections_total",
+		Help:      "因为包含敏感词被拒绝的内容数,target为post、comment或username",
+	}, []string{"target"})
+	SensitiveWordActions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sensitive_word_actions_total",
+		Help:      "包含敏感词但没有被拒绝的内容数,policy为mask(屏蔽后发布)或review(交给版主审核)",
+	}, []string{"target", "policy"})
+	OutboxEventsDead = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_events_dead_total",
+		Help:      "重试次数用完、不再自动重试的帖子变更事件数,需要管理员排查后重新加入队列",
+	}, []string{"event_type"})
+	LoginFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_failures_total",
+		Help:      "登录失败的次数,reason为user_not_exist、invalid_password或error",
+	}, []string{"reason"})
+)
+
+// VoteDirection 把投票的方向转换成 Votes 的direction标签
+func VoteDirection(direction int8) string {
+	switch {
+	case direction > 0:
+		return "up"
+	case direction < 0:
+		return "down"
+	}
+	return "cancel"
+}
+
+// Handler 暴露指标的HTTP处理函数
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Register 注册一个采集器,已经注册过同样的采集器时先注销旧的
+// 用于在重新初始化连接时替换采集连接池状态的采集器
+func Register(c prometheus.Collector) {
+	prometheus.Unregister(c)
+	prometheus.MustRegister(c)
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestGinMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinMetrics())
+	r.GET("/post/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, path := range []string{"/post/1", "/post/2", "/nothing/here"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	tests := []struct {
+		route, status string
+		want          float64
+	}{
+		// 按路由模板统计,不同的帖子id记在同一个标签下
+		{route: "/post/:id", status: "200", want: 2},
+		{route: unmatchedRoute, status: "404", want: 1},
+	}
+	for _, tt := range tests {
+		got := testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, tt.route, tt.status))
+		if got != tt.want {
+			t.Errorf("requests{route=%q,status=%q} = %v, want %v", tt.route, tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestVoteDirection(t *testing.T) {
+	for direction, want := range map[int8]string{1: "up", -1: "down", 0: "cancel"} {
+		if got := VoteDirection(direction); got != want {
+			t.Errorf("VoteDirection(%d) = %q, want %q", direction, got, want)
+		}
+	}
+}
+
+func TestRedisPoolCollector(t *testing.T) {
+	c := NewRedisPoolCollector(func() *redis.PoolStats {
+		return &redis.PoolStats{Hits: 5, Misses: 1, TotalConns: 3, IdleConns: 2}
+	})
+	want := `
+# HELP bluebell_redis_pool_conns 连接池中的连接数
+# TYPE bluebell_redis_pool_conns gauge
+bluebell_redis_pool_conns 3
+# HELP bluebell_redis_pool_hits_total 从连接池中取到空闲连接的次数
+# TYPE bluebell_redis_pool_hits_total counter
+bluebell_redis_pool_hits_total 5
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(want),
+		"bluebell_redis_pool_conns", "bluebell_redis_pool_hits_total")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
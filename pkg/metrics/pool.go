@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/go-redis/redis"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// NewDBStatsCollector 采集 sql.DB 连接池的状态(DBStats)
+func NewDBStatsCollector(db *sql.DB, dbName string) prometheus.Collector {
+	return collectors.NewDBStatsCollector(db, dbName)
+}
+
+// redisPoolCollector 在每次采集时读取 go-redis 连接池的状态
+type redisPoolCollector struct {
+	stats func() *redis.PoolStats
+
+	hits       *prometheus.Desc
+	misses     *prometheus.Desc
+	timeouts   *prometheus.Desc
+	totalConns *prometheus.Desc
+	idleConns  *prometheus.Desc
+	staleConns *prometheus.Desc
+}
+
+// NewRedisPoolCollector 采集 go-redis 连接池的状态
+func NewRedisPoolCollector(stats func() *redis.PoolStats) prometheus.Collector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "redis_pool", name), help, nil, nil)
+	}
+	return &redisPoolCollector{
+		stats:      stats,
+		hits:       desc("hits_total", "从连接池中取到空闲连接的次数"),
+		misses:     desc("misses_total", "连接池中没有空闲连接的次数"),
+		timeouts:   desc("timeouts_total", "等待连接超时的次数"),
+		totalConns: desc("conns", "连接池中的连接数"),
+		idleConns:  desc("idle_conns", "连接池中的空闲连接数"),
+		staleConns: desc("stale_conns_total", "因为过期被关闭的连接数"),
+	}
+}
+
+func (c *redisPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.timeouts
+	ch <- c.totalConns
+	ch <- c.idleConns
+	ch <- c.staleConns
+}
+
+func (c *redisPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stats()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(s.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(s.Misses))
+	ch <- prometheus.MustNewConstMetric(c.timeouts, prometheus.CounterValue, float64(s.Timeouts))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(s.TotalConns))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(s.IdleConns))
+	ch <- prometheus.MustNewConstMetric(c.staleConns, prometheus.CounterValue, float64(s.StaleConns))
+}
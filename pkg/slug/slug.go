@@ -0,0 +1,52 @@
+package slug
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Make 把名称转换成URL安全的slug
+// 只保留小写字母和数字,其余连续的字符替换成一个'-',例如 "CS:GO" -> "cs-go"
+// 名称中没有字母和数字时(例如纯中文)返回空字符串,社区使用 ForCommunity 生成不为空的slug
+func Make(name string) string {
+	var b strings.Builder
+	pendingDash := false
+	for _, r := range strings.ToLower(name) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			if pendingDash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			pendingDash = false
+			b.WriteRune(r)
+			continue
+		}
+		pendingDash = true
+	}
+	return b.String()
+}
+
+// ForCommunity 生成社区唯一的slug,taken 判断slug是否已经被其他社区使用
+// Make 的结果为空时使用 c-社区id;被占用时依次尝试加上 -社区id、-社区id-2、-社区id-3 ... 的后缀
+// 和迁移 0010_add_community_slug 给已有社区生成slug的规则一致
+func ForCommunity(name string, communityID int64, taken func(string) (bool, error)) (string, error) {
+	id := strconv.FormatInt(communityID, 10)
+	base := Make(name)
+	if base == "" {
+		base = "c-" + id
+	}
+	candidate := base
+	for n := 1; ; n++ {
+		used, err := taken(candidate)
+		if err != nil {
+			return "", err
+		}
+		if !used {
+			return candidate, nil
+		}
+		candidate = base + "-" + id
+		if n > 1 {
+			candidate += "-" + strconv.Itoa(n)
+		}
+	}
+}
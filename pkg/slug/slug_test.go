@@ -0,0 +1,52 @@
+package slug
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Go", "go"},
+		{"leetcode", "leetcode"},
+		{"CS:GO", "cs-go"},
+		{"  Hello,  World!  ", "hello-world"},
+		{"Go 语言", "go"},
+		{"风铃草", ""},
+	}
+	for _, tt := range tests {
+		if got := Make(tt.name); got != tt.want {
+			t.Errorf("Make(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestForCommunity(t *testing.T) {
+	taken := map[string]bool{"go": true, "c-7": true, "c-7-7": true, "go-9": true}
+	isTaken := func(s string) (bool, error) { return taken[s], nil }
+	tests := []struct {
+		name string
+		id   int64
+		want string
+	}{
+		{"LOL", 5, "lol"},
+		{"风铃草", 6, "c-6"},
+		{"Go", 8, "go-8"},     // 重名加上社区id
+		{"风铃草", 7, "c-7-7-2"}, // c-7 和 c-7-7 都被占用
+		{"Go!", 9, "go-9-2"},
+	}
+	for _, tt := range tests {
+		got, err := ForCommunity(tt.name, tt.id, isTaken)
+		if err != nil || got != tt.want {
+			t.Errorf("ForCommunity(%q, %d) = %q, %v, want %q", tt.name, tt.id, got, err, tt.want)
+		}
+	}
+
+	errTaken := errors.New("db down")
+	if _, err := ForCommunity("Go", 1, func(string) (bool, error) { return false, errTaken }); err != errTaken {
+		t.Fatalf("ForCommunity() error = %v, want %v", err, errTaken)
+	}
+}
@@ -0,0 +1,81 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// setupRecorder 把全局的TracerProvider换成记录span的实现
+func setupRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	if _, err := Init(Options{Exporter: ExporterNone}); err != nil {
+		t.Fatal(err)
+	}
+	sr := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return sr
+}
+
+func TestGinTracing(t *testing.T) {
+	sr := setupRecorder(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinTracing())
+	var handlerSpan trace.SpanContext
+	r.GET("/post/:id", func(c *gin.Context) {
+		handlerSpan = trace.SpanContextFromContext(c.Request.Context())
+		if c.Param("id") == "0" {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus codes.Code
+	}{
+		{name: "ok", path: "/post/1", wantStatus: codes.Unset},
+		{name: "server error", path: "/post/0", wantStatus: codes.Error},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			spans := sr.Ended()
+			if len(spans) != i+1 {
+				t.Fatalf("len(spans) = %d, want %d", len(spans), i+1)
+			}
+			span := spans[i]
+			if span.Name() != "GET /post/:id" {
+				t.Fatalf("Name = %q, want %q", span.Name(), "GET /post/:id")
+			}
+			if got := span.SpanContext().TraceID().String(); got != traceID {
+				t.Fatalf("TraceID = %s, want %s from traceparent", got, traceID)
+			}
+			if !span.Parent().IsRemote() {
+				t.Fatal("parent span should be the remote span from traceparent")
+			}
+			if handlerSpan.SpanID() != span.SpanContext().SpanID() {
+				t.Fatal("request context should carry the server span")
+			}
+			if span.Status().Code != tt.wantStatus {
+				t.Fatalf("Status = %v, want %v", span.Status().Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"bluebell/controller"
 	"bluebell/logger"
 	"bluebell/middlewares"
+	"bluebell/pkg/i18n"
+	"bluebell/pkg/metrics"
+	"bluebell/pkg/tracing"
 	"net/http"
 
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -22,7 +25,7 @@ func SetupRouter(mode string) *gin.Engine {
 	}
 	r := gin.New()
 	//r.Use(logger.GinLogger(), logger.GinRecovery(true), middlewares.RateLimitMiddleware(2*time.Second, 1))
-	r.Use(logger.GinLogger(), logger.GinRecovery(true), middlewares.Cors())
+	r.Use(metrics.GinMetrics(), tracing.GinTracing(), logger.GinRequestID(), i18n.GinLocale(), logger.GinLogger(), logger.GinRecovery(true), controller.ErrorHandler(), middlewares.Cors())
 
 	r.LoadHTMLFiles("./templates/index.html")
 	r.Static("/static", "./static")
@@ -34,6 +37,12 @@ func SetupRouter(mode string) *gin.Engine {
 	r.GET("/ping", func(c *gin.Context) {
 		c.String(http.StatusOK, "pong")
 	})
+	// 存活、就绪检查及版本信息
+	r.GET("/healthz", controller.HealthzHandler)
+	r.GET("/readyz", controller.ReadyzHandler)
+	r.GET("/version", controller.VersionHandler)
+	// Prometheus指标
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
 
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -45,14 +54,17 @@ func SetupRouter(mode string) *gin.Engine {
 		v1.GET("/login", controller.LoginHandler)
 		// 短信验证码登录
 		v1.POST("/loginSMS", controller.LoginSMSHandler)
-		// 根据时间或分数获取帖子列表
-		v1.GET("/posts2", controller.GetPostListHandler2)
-		v1.GET("/posts", controller.GetPostListHandler)
+		// 根据时间或分数获取帖子列表,登录用户可以看到自己的投票
+		v1.GET("/posts2", middlewares.JWTAuthOptionalMiddleware(), controller.GetPostListHandler2)
+		v1.GET("/posts", middlewares.JWTAuthOptionalMiddleware(), controller.GetPostListHandler)
 		v1.GET("/community", controller.CommunityHandler)
 		v1.GET("/community/id/:id", controller.CommunityDetailHandler)
-		v1.GET("/community/name/:name", controller.CommunityByName)
-		v1.GET("/post/:id", controller.GetPostDetailHandler)
+		v1.GET("/community/name/:name", middlewares.JWTAuthOptionalMiddleware(), controller.CommunityByName)
+		v1.GET("/post/:id", middlewares.JWTAuthOptionalMiddleware(), controller.GetPostDetailHandler)
+		// 已废弃,请使用 /search
 		v1.GET("/select", controller.GetPostBySelect)
+		// 全文搜索帖子
+		v1.GET("/search", middlewares.JWTAuthOptionalMiddleware(), controller.SearchHandler)
 	}
 
 	auth := v1.Group("/")
@@ -66,18 +78,64 @@ func SetupRouter(mode string) *gin.Engine {
 		auth.POST("/user/:user_id/avatar", controller.PostAvatar)
 		// 发布帖子
 		auth.POST("/post", controller.CreatePostHandler)
+		// 修改帖子
+		auth.PUT("/post/:id", controller.EditPostHandler)
 		// 投票
 		auth.POST("/vote", controller.PostVoteController)
 		// 个人页面
 		auth.GET("/userPage", controller.GetUserPage)
 		// 删除帖子
 		auth.DELETE("/deleteV1", controller.DeletePost)
+		// 关注社区
+		auth.POST("/community/id/:id/follow", controller.FollowCommunityHandler)
+		auth.DELETE("/community/id/:id/follow", controller.UnfollowCommunityHandler)
+		// 关注的社区内的帖子
+		auth.GET("/feed", controller.GetFeedHandler)
+	}
+
+	// 社区版主,只能管理自己的社区
+	moderation := v1.Group("/moderation", middlewares.JWTAuthMiddleware())
+	{
+		moderation.GET("/communities/:community_id/moderators", controller.GetModeratorsHandler)
+		// 添加/移除版主
+		moderation.POST("/communities/:community_id/moderators", middlewares.CommunityOwner(), controller.AddModeratorHandler)
+		moderation.DELETE("/communities/:community_id/moderators/:user_id", middlewares.CommunityOwner(), controller.RemoveModeratorHandler)
+		// 社区内禁言
+		moderation.POST("/communities/:community_id/bans", middlewares.CommunityModerator(), controller.BanUserHandler)
+		moderation.DELETE("/communities/:community_id/bans/:user_id", middlewares.CommunityModerator(), controller.UnbanUserHandler)
+		// 删除帖子及评论
+		moderation.DELETE("/posts/:post_id", middlewares.CommunityModerator(), controller.ModeratePostHandler)
+		moderation.DELETE("/posts/:post_id/comments/:comment_id", middlewares.CommunityModerator(), controller.RemoveCommentHandler)
+		// 置顶帖子
+		moderation.POST("/posts/:post_id/pin", middlewares.CommunityModerator(), controller.PinPostHandler)
+		moderation.DELETE("/posts/:post_id/pin", middlewares.CommunityModerator(), controller.UnpinPostHandler)
+		// 审核命中敏感词的帖子,审核不通过时用上面的接口删除帖子
+		moderation.GET("/communities/:community_id/reviews", middlewares.CommunityModerator(), controller.GetReviewPostsHandler)
+		moderation.DELETE("/communities/:community_id/reviews/:post_id", middlewares.CommunityModerator(), controller.ApprovePostHandler)
 	}
 
 	manager := r.Group("/manager", middlewares.JWTAuthMiddleware(), middlewares.AuthManager())
 	{
 		// 删除帖子
 		manager.DELETE("/deleteRoot", controller.DeletePost)
+		// 创建社区
+		manager.POST("/communities", controller.CreateCommunityHandler)
+		// 全站封禁用户
+		manager.POST("/suspensions", controller.SuspendUserHandler)
+		manager.DELETE("/suspensions/:user_id", controller.UnsuspendUserHandler)
+		// 缓存命中情况
+		manager.GET("/cache/stats", controller.CacheStatsHandler)
+		// 从MySQL重建redis中的帖子索引
+		manager.POST("/reindex", controller.ReindexHandler)
+		// 不再自动重试的帖子变更事件,排查后重新加入队列
+		manager.GET("/outbox/dead", controller.GetDeadOutboxEventsHandler)
+		manager.POST("/outbox/requeue", controller.RequeueOutboxEventsHandler)
+		// 管理敏感词库,修改后所有实例立即生效
+		manager.GET("/sensitive-words", controller.GetSensitiveWordCategoriesHandler)
+		manager.GET("/sensitive-words/:category", controller.ExportSensitiveWordsHandler)
+		manager.POST("/sensitive-words/:category", controller.AddSensitiveWordsHandler)
+		manager.DELETE("/sensitive-words/:category", controller.RemoveSensitiveWordsHandler)
+		manager.POST("/sensitive-words/:category/import", controller.ImportSensitiveWordsHandler)
 		// 置顶帖子
 		//manager.POST("/postTop", controller.PostTop)
 		// 删除用户头像
@@ -86,9 +144,7 @@ func SetupRouter(mode string) *gin.Engine {
 	pprof.Register(r) // 注册pprof相关路由
 
 	r.NoRoute(func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"msg": "404",
-		})
+		controller.ResponseError(c, controller.CodeNotFound)
 	})
 	return r
 }
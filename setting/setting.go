@@ -2,6 +2,8 @@ package setting
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/viper"
@@ -17,10 +19,27 @@ type AppConfig struct {
 	MachineID int64  `mapstructure:"machine_id"`
 	Port      int    `mapstructure:"port"`
 
-	*LogConfig   `mapstructure:"log"`
-	*MySQLConfig `mapstructure:"mysql"`
-	*RedisConfig `mapstructure:"redis"`
-	*SMSConfig   `mapstructure:"sms"`
+	*ServerConfig     `mapstructure:"server"`
+	*LogConfig        `mapstructure:"log"`
+	*MySQLConfig      `mapstructure:"mysql"`
+	*RedisConfig      `mapstructure:"redis"`
+	*SMSConfig        `mapstructure:"sms"`
+	*SearchConfig     `mapstructure:"search"`
+	*TracingConfig    `mapstructure:"tracing"`
+	*ModerationConfig `mapstructure:"moderation"`
+}
+
+// ServerConfig HTTP服务的超时设置,配置文件中写成"10s"这样的格式
+type ServerConfig struct {
+	ReadTimeout     time.Duration `mapstructure:"read_timeout"`     // 读取整个请求(包括请求体)的超时时间
+	WriteTimeout    time.Duration `mapstructure:"write_timeout"`    // 从读完请求头到写完响应的超时时间
+	IdleTimeout     time.Duration `mapstructure:"idle_timeout"`     // keep-alive连接的空闲时间
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"` // 退出时等待处理中的请求和后台任务结束的最长时间
+	// 退出时先让就绪检查失败,等待shutdown_delay让负载均衡摘除本实例后再停止接收请求
+	ShutdownDelay    time.Duration `mapstructure:"shutdown_delay"`
+	ReadinessTimeout time.Duration `mapstructure:"readiness_timeout"` // 就绪检查中每个依赖的超时时间
+	// 兼容旧的客户端: 所有响应都使用HTTP 200,只通过响应中的code区分错误
+	LegacyStatusOK bool `mapstructure:"legacy_status_ok"`
 }
 
 type MySQLConfig struct {
@@ -31,6 +50,7 @@ type MySQLConfig struct {
 	Port         int    `mapstructure:"port"`
 	MaxOpenConns int    `mapstructure:"max_open_conns"`
 	MaxIdleConns int    `mapstructure:"max_idle_conns"`
+	AutoMigrate  bool   `mapstructure:"auto_migrate"` // 启动web服务时自动执行数据库迁移
 }
 
 type RedisConfig struct {
@@ -58,22 +78,71 @@ type SMSConfig struct {
 	RegionID     string `mapstructure:"region_id"`
 }
 
-func Init() (err error) {
-	// 方式1：直接指定配置文件路径（相对路径或者绝对路径）
+type SearchConfig struct {
+	Engine    string `mapstructure:"engine"`     // mysql 或 bleve,bleve索引保存在本机,只能单实例部署时使用
+	IndexPath string `mapstructure:"index_path"` // bleve索引文件的目录
+}
+
+// ModerationConfig 内容审核的设置
+type ModerationConfig struct {
+	WordsDir         string            `mapstructure:"words_dir"`       // MySQL中没有敏感词时导入的词库目录,每个.txt文件是一个分类,每行一个敏感词
+	ReloadInterval   time.Duration     `mapstructure:"reload_interval"` // 定期从MySQL重新加载词库的间隔,0表示只在收到变化通知时加载
+	DefaultPolicy    string            `mapstructure:"default_policy"`  // 没有配置处理方式的分类使用的处理方式
+	Policies         map[string]string `mapstructure:"policies"`        // 分类到处理方式的映射: reject、mask或review
+	*NormalizeConfig `mapstructure:"normalize"`
+}
+
+// NormalizeConfig 匹配敏感词之前对文本的转换,防止插入空格、标点或使用全角字符、繁体字绕过过滤
+type NormalizeConfig struct {
+	FoldWidth        bool     `mapstructure:"fold_width"`        // 全角字符转换成半角
+	FoldCase         bool     `mapstructure:"fold_case"`         // 转换成小写
+	RemoveSeparators bool     `mapstructure:"remove_separators"` // 去掉空白、标点和符号
+	Stopwords        string   `mapstructure:"stopwords"`         // 额外忽略的字符
+	Simplified       bool     `mapstructure:"simplified"`        // 繁体字转换成简体字
+	HomoglyphFiles   []string `mapstructure:"homoglyph_files"`   // 形近字表,每行一个形近字和对应的标准字符
+}
+
+// TracingConfig 链路追踪的设置
+type TracingConfig struct {
+	Exporter    string  `mapstructure:"exporter"`     // none、stdout或otlp
+	Endpoint    string  `mapstructure:"endpoint"`     // otlp的HTTP地址,如 localhost:4318
+	Insecure    bool    `mapstructure:"insecure"`     // otlp不使用TLS
+	SampleRatio float64 `mapstructure:"sample_ratio"` // 没有上游采样决定时的采样比例,0到1
+}
+
+// envPrefix 环境变量覆盖配置时使用的前缀,如 BLUEBELL_MYSQL_HOST 覆盖 mysql.host
+const envPrefix = "BLUEBELL"
+
+// Init 读取指定路径的配置文件,环境变量中的同名配置优先
+func Init(path string) (err error) {
+	// 直接指定配置文件路径（相对路径或者绝对路径）
 	// 相对路径：相对执行的可执行文件的相对路径
-	viper.SetConfigFile("./conf/dev.yml")
-	// 绝对路径：系统中实际的文件路径
-	//viper.SetConfigFile("/Users/liwenzhou/Desktop/bluebell/conf/config.yaml")
-
-	// 方式2：指定配置文件名和配置文件的位置，viper自行查找可用的配置文件
-	// 配置文件名不需要带后缀
-	// 配置文件位置可配置多个
-	//viper.SetConfigName("config") // 指定配置文件名（不带后缀）
-	//viper.AddConfigPath(".") // 指定查找配置文件的路径（这里使用相对路径）
-	//viper.AddConfigPath("./conf")      // 指定查找配置文件的路径（这里使用相对路径）
-
-	// 基本上是配合远程配置中心使用的，告诉viper当前的数据使用什么格式去解析
-	//viper.SetConfigType("json")
+	viper.SetConfigFile(path)
+	// 环境变量覆盖配置文件: 配置项中的"."换成"_",加上前缀后转成大写
+	viper.SetEnvPrefix(envPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+	// 设置了默认值的配置项在配置文件中没有时也可以用环境变量覆盖
+	viper.SetDefault("server.read_timeout", 10*time.Second)
+	viper.SetDefault("server.write_timeout", 30*time.Second)
+	viper.SetDefault("server.idle_timeout", 60*time.Second)
+	viper.SetDefault("server.shutdown_timeout", 15*time.Second)
+	viper.SetDefault("server.shutdown_delay", 0)
+	viper.SetDefault("server.readiness_timeout", 2*time.Second)
+	viper.SetDefault("server.legacy_status_ok", false)
+	viper.SetDefault("mysql.auto_migrate", false)
+	viper.SetDefault("moderation.words_dir", "./pkg/sensitivewords")
+	viper.SetDefault("moderation.reload_interval", 5*time.Minute)
+	viper.SetDefault("moderation.default_policy", "reject")
+	viper.SetDefault("moderation.normalize.fold_width", true)
+	viper.SetDefault("moderation.normalize.fold_case", true)
+	viper.SetDefault("moderation.normalize.remove_separators", true)
+	viper.SetDefault("moderation.normalize.simplified", true)
+	viper.SetDefault("tracing.exporter", "none")
+	viper.SetDefault("tracing.endpoint", "localhost:4318")
+	viper.SetDefault("tracing.insecure", true)
+	viper.SetDefault("tracing.sample_ratio", 1.0)
+
 	err = viper.ReadInConfig() // 读取配置信息
 	if err != nil {
 		// 读取配置信息失败
@@ -82,8 +151,9 @@ func Init() (err error) {
 	}
 
 	// 把读取到的配置信息反序列化到 Conf 变量中
-	if err := viper.Unmarshal(Conf); err != nil {
+	if err = viper.Unmarshal(Conf); err != nil {
 		fmt.Printf("viper.Unmarshal failed, err:%v\n", err)
+		return
 	}
 
 	viper.WatchConfig()
@@ -95,3 +165,67 @@ func Init() (err error) {
 	})
 	return
 }
+
+// Validate 检查启动服务必需的配置项
+func (c *AppConfig) Validate() error {
+	var missing []string
+	if c.Port <= 0 {
+		missing = append(missing, "port")
+	}
+	if c.StartTime == "" {
+		missing = append(missing, "start_time")
+	}
+	if c.LogConfig == nil {
+		missing = append(missing, "log")
+	}
+	if c.MySQLConfig == nil || c.MySQLConfig.Host == "" || c.MySQLConfig.DB == "" {
+		missing = append(missing, "mysql")
+	}
+	if c.RedisConfig == nil || c.RedisConfig.Host == "" {
+		missing = append(missing, "redis")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing config: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// 名字中包含这些词的配置项在打印时会被隐藏
+var secretWords = []string{"password", "secret", "app_key", "token"}
+
+const redacted = "******"
+
+// Redacted 返回实际生效的配置(包括环境变量覆盖的值),密码等敏感配置被隐藏
+func Redacted() map[string]interface{} {
+	settings := make(map[string]interface{})
+	for _, key := range viper.AllKeys() {
+		val := viper.Get(key)
+		if isSecretKey(key) && val != "" {
+			val = redacted
+		}
+		setNested(settings, strings.Split(key, "."), val)
+	}
+	return settings
+}
+
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, w := range secretWords {
+		if strings.Contains(key, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func setNested(m map[string]interface{}, path []string, val interface{}) {
+	for _, k := range path[:len(path)-1] {
+		sub, ok := m[k].(map[string]interface{})
+		if !ok {
+			sub = make(map[string]interface{})
+			m[k] = sub
+		}
+		m = sub
+	}
+	m[path[len(path)-1]] = val
+}
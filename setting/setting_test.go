@@ -0,0 +1,58 @@
+package setting
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testConfig = `port: 8080
+start_time: "2020-07-01"
+log:
+  level: "info"
+mysql:
+  host: 127.0.0.1
+  password: "123456"
+  dbname: "bluebell"
+redis:
+  host: 127.0.0.1
+  password: ""
+`
+
+func TestInitEnvOverrideAndRedacted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(testConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("BLUEBELL_MYSQL_HOST", "mysql.internal")
+	t.Setenv("BLUEBELL_PORT", "9090")
+	if err := Init(path); err != nil {
+		t.Fatal(err)
+	}
+	if Conf.MySQLConfig.Host != "mysql.internal" || Conf.Port != 9090 {
+		t.Fatalf("env override not applied: host=%q port=%d", Conf.MySQLConfig.Host, Conf.Port)
+	}
+	if err := Conf.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	settings := Redacted()
+	mysql := settings["mysql"].(map[string]interface{})
+	if mysql["password"] != redacted {
+		t.Errorf("mysql.password = %v, want redacted", mysql["password"])
+	}
+	if mysql["host"] != "mysql.internal" {
+		t.Errorf("mysql.host = %v, want mysql.internal", mysql["host"])
+	}
+	// 空的密码不需要隐藏,方便看出没有配置
+	if redis := settings["redis"].(map[string]interface{}); redis["password"] != "" {
+		t.Errorf("redis.password = %v, want empty", redis["password"])
+	}
+}
+
+func TestValidate(t *testing.T) {
+	c := &AppConfig{Port: 8080, StartTime: "2020-07-01", LogConfig: &LogConfig{}}
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for missing mysql and redis")
+	}
+}